@@ -24,6 +24,8 @@ type Variable struct {
 	GlobalSymbol string
 	LocalOffset  int
 	Typ          *Type
+	IsEscaped    bool // captured by a closure and lives on the heap
+	BoxOffset    int  // local slot holding the heap address of an escaped variable
 }
 
 type Object struct {
@@ -75,17 +77,20 @@ type ParenExpr struct {
 }
 
 type SelectorExpr struct {
-	X   Expr
-	Sel *Ident
+	X     Expr
+	Sel   *Ident
+	Thunk *CallThunk // closure of a method value x.M or a method expression T.M
 }
 
 type IndexExpr struct {
-	X     Expr
-	Index Expr
+	X      Expr
+	Lbrack token.Pos // position of "["
+	Index  Expr
 }
 
 type SliceExpr struct {
 	X      Expr
+	Lbrack token.Pos // position of "["
 	Low    Expr
 	High   Expr
 	Max    Expr
@@ -93,8 +98,9 @@ type SliceExpr struct {
 }
 
 type CallExpr struct {
-	Fun      Expr   // function expression
-	Args     []Expr // function arguments; or nil
+	Fun      Expr      // function expression
+	Lparen   token.Pos // position of "("
+	Args     []Expr    // function arguments; or nil
 	Ellipsis token.Pos
 }
 
@@ -103,8 +109,9 @@ type StarExpr struct {
 }
 
 type UnaryExpr struct {
-	X  Expr
-	Op token.Token
+	OpPos token.Pos // position of Op
+	X     Expr
+	Op    token.Token
 }
 
 type BinaryExpr struct {
@@ -129,7 +136,23 @@ type StructType struct {
 }
 
 type InterfaceType struct {
-	Methods []string
+	Methods *FieldList
+}
+
+type MapType struct {
+	Key   Expr
+	Value Expr
+}
+
+// The direction of a channel type
+type ChanDir int
+
+var SEND ChanDir = 1
+var RECV ChanDir = 2
+
+type ChanType struct {
+	Dir   ChanDir
+	Value Expr
 }
 
 type FuncType struct {
@@ -137,6 +160,12 @@ type FuncType struct {
 	Results *FieldList
 }
 
+type FuncLit struct {
+	Type *FuncType
+	Body *BlockStmt
+	Func *Func
+}
+
 type Stmt interface{}
 
 type DeclStmt struct {
@@ -147,34 +176,72 @@ type ExprStmt struct {
 	X Expr
 }
 
+type SendStmt struct {
+	Chan  Expr
+	Arrow token.Pos // position of "<-"
+	Value Expr
+}
+
 type IncDecStmt struct {
-	X   Expr
-	Tok token.Token
+	X      Expr
+	TokPos token.Pos // position of Tok
+	Tok    token.Token
 }
 
 type AssignStmt struct {
 	Lhs     []Expr
+	TokPos  token.Pos // position of Tok
 	Tok     token.Token
 	Rhs     []Expr
 	IsRange bool
 }
 
 type ReturnStmt struct {
+	Return  token.Pos // position of "return" keyword
 	Results []Expr
 	Node    *NodeReturnStmt
 }
 
+type DeferStmt struct {
+	Defer token.Pos // position of "defer" keyword
+	Call  *CallExpr
+	Thunk *CallThunk
+}
+
+type GoStmt struct {
+	Go    token.Pos // position of "go" keyword
+	Call  *CallExpr
+	Thunk *CallThunk
+}
+
+// CallThunk is the call of a defer or go statement, or of a method value, wrapped in a closure
+type CallThunk struct {
+	Tmps     []*Variable // operands evaluated at the statement or the method value
+	TmpExprs []Expr
+	Lit      *FuncLit
+	Depth    int // calls from the caller of Lit to the function called by the statement
+}
+
 type BranchStmt struct {
-	Tok        token.Token
-	Label      string
-	CurrentFor Stmt
+	TokPos token.Pos // position of Tok
+	Tok    token.Token
+	Label  string
+	Target Stmt // statement to break or continue, or the labeled statement to go to
+}
+
+type LabeledStmt struct {
+	Label  *Ident
+	Stmt   Stmt
+	Symbol string
 }
 
 type BlockStmt struct {
-	List []Stmt
+	Lbrace token.Pos // position of "{"
+	List   []Stmt
 }
 
 type IfStmt struct {
+	If   token.Pos // position of "if" keyword
 	Init Stmt
 	Cond Expr
 	Body *BlockStmt
@@ -187,30 +254,53 @@ type CaseClause struct {
 }
 
 type SwitchStmt struct {
-	Init Expr
-	Tag  Expr
-	Body *BlockStmt
-	// lableExit string
+	Switch    token.Pos // position of "switch" keyword
+	Init      Expr
+	Tag       Expr
+	Body      *BlockStmt
+	LabelExit string
+}
+
+type CommClause struct {
+	Comm Stmt // send or receive statement; nil means default case
+	Body []Stmt
+}
+
+type SelectStmt struct {
+	Select    token.Pos // position of "select" keyword
+	Body      *BlockStmt
+	Tmps      []*Variable // operands evaluated before choosing a case
+	TmpExprs  []Expr
+	LabelExit string
 }
 
 type TypeSwitchStmt struct {
-	Assign Stmt
-	Body   *BlockStmt
-	Node   *NodeTypeSwitchStmt
+	Switch    token.Pos // position of "switch" keyword
+	Assign    Stmt
+	Body      *BlockStmt
+	Node      *NodeTypeSwitchStmt
+	LabelExit string
 }
 
 type Func struct {
-	Localvars []*string
-	Localarea int
-	Argsarea  int
-	Vars      []*Variable
-	Params    []*Variable
-	Retvars   []*Variable
-	FuncType  *FuncType
-	RcvType   Expr
-	Name      string
-	Body      *BlockStmt
-	Method    *Method
+	Localvars  []*string
+	Localarea  int
+	Argsarea   int
+	Vars       []*Variable
+	Params     []*Variable
+	Retvars    []*Variable
+	FuncType   *FuncType
+	RcvType    Expr
+	Name       string
+	Body       *BlockStmt
+	Method     *Method
+	Outer      *Func       // enclosing function of a func literal
+	Captures   []*Variable // free variables of a func literal
+	ClosureVar *Variable   // local slot holding the closure context
+	HasDefer   bool
+	Labels     []*LabeledStmt
+	Gotos      []*BranchStmt
+	UsedLabels []string // labels of goto, break and continue statements
 }
 
 type Method struct {
@@ -219,6 +309,7 @@ type Method struct {
 	IsPtrMethod  bool
 	Name         string
 	FuncType     *FuncType
+	Embedded     []*Field // path to the receiver of a promoted method
 }
 
 type NodeReturnStmt struct {
@@ -239,6 +330,7 @@ type TypeSwitchCaseClose struct {
 }
 
 type ForStmt struct {
+	For       token.Pos // position of "for" keyword
 	Init      Stmt
 	Cond      Expr
 	Post      Stmt
@@ -249,6 +341,7 @@ type ForStmt struct {
 }
 
 type RangeStmt struct {
+	For       token.Pos // position of "for" keyword
 	Key       Expr
 	Value     Expr
 	X         Expr
@@ -258,6 +351,7 @@ type RangeStmt struct {
 	LabelExit string
 	Lenvar    *Variable
 	Indexvar  *Variable
+	Itervar   *Variable // map iterator
 	Tok       token.Token
 }
 
@@ -266,9 +360,10 @@ type ImportSpec struct {
 }
 
 type ValueSpec struct {
-	Name  *Ident
-	Type  Expr
+	Names  []*Ident
+	Type   Expr
 	Values []Expr
+	Iota   int // index of the spec in a const declaration
 }
 
 type TypeSpec struct {
@@ -285,7 +380,9 @@ type Decl interface {
 type Spec interface{}
 
 type GenDecl struct {
-	Spec Spec // *ValueSpec | *TypeSpec
+	TokPos token.Pos   // position of Tok
+	Tok    token.Token // token.VAR | token.CONST | token.TYPE
+	Specs  []Spec      // *ValueSpec | *TypeSpec
 }
 
 type FuncDecl struct {
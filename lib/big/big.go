@@ -0,0 +1,606 @@
+// Package big implements the arbitrary-precision integers and rationals
+// which the compiler folds constants in.
+// The values are immutable: operations return new values.
+package big
+
+const wordBits = 30
+const wordBase = 1073741824 // 1 << wordBits
+const wordMask = 1073741823 // wordBase - 1
+
+// Int is an integer of any size.
+type Int struct {
+	neg bool
+	abs []int // words of wordBits bits, least significant first, without leading zero words
+}
+
+// Rat is a fraction whose denominator is positive and coprime to its numerator.
+type Rat struct {
+	num *Int
+	den *Int
+}
+
+func natNorm(x []int) []int {
+	var n int = len(x)
+	for n > 0 && x[n-1] == 0 {
+		n--
+	}
+	return x[0:n]
+}
+
+func natCmp(x []int, y []int) int {
+	if len(x) != len(y) {
+		if len(x) < len(y) {
+			return -1
+		}
+		return 1
+	}
+	var i int
+	for i = len(x) - 1; i >= 0; i-- {
+		if x[i] != y[i] {
+			if x[i] < y[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func natAdd(x []int, y []int) []int {
+	if len(x) < len(y) {
+		return natAdd(y, x)
+	}
+	var z []int = make([]int, len(x)+1, len(x)+1)
+	var carry int
+	var i int
+	for i = 0; i < len(x); i++ {
+		var t int = x[i] + carry
+		if i < len(y) {
+			t = t + y[i]
+		}
+		z[i] = t & wordMask
+		carry = t >> wordBits
+	}
+	z[len(x)] = carry
+	return natNorm(z)
+}
+
+// x - y for x >= y
+func natSub(x []int, y []int) []int {
+	var z []int = make([]int, len(x), len(x))
+	var borrow int
+	var i int
+	for i = 0; i < len(x); i++ {
+		var t int = x[i] - borrow
+		if i < len(y) {
+			t = t - y[i]
+		}
+		borrow = 0
+		if t < 0 {
+			t = t + wordBase
+			borrow = 1
+		}
+		z[i] = t
+	}
+	return natNorm(z)
+}
+
+func natMul(x []int, y []int) []int {
+	if len(x) == 0 || len(y) == 0 {
+		return nil
+	}
+	var z []int = make([]int, len(x)+len(y), len(x)+len(y))
+	var i int
+	var j int
+	for i = 0; i < len(x); i++ {
+		var carry int
+		for j = 0; j < len(y); j++ {
+			var t int = x[i]*y[j] + z[i+j] + carry
+			z[i+j] = t & wordMask
+			carry = t >> wordBits
+		}
+		z[i+len(y)] = carry
+	}
+	return natNorm(z)
+}
+
+func natBitLen(x []int) int {
+	if len(x) == 0 {
+		return 0
+	}
+	var n int = (len(x) - 1) * wordBits
+	var top int = x[len(x)-1]
+	for top != 0 {
+		top = top >> 1
+		n++
+	}
+	return n
+}
+
+func natBit(x []int, i int) int {
+	var w int = i / wordBits
+	if w >= len(x) {
+		return 0
+	}
+	return (x[w] >> (i % wordBits)) & 1
+}
+
+func natShl(x []int, s int) []int {
+	if len(x) == 0 {
+		return nil
+	}
+	var words int = s / wordBits
+	var bits int = s % wordBits
+	var z []int = make([]int, len(x)+words+1, len(x)+words+1)
+	var i int
+	for i = 0; i < len(x); i++ {
+		var t int = x[i] << bits
+		z[i+words] = z[i+words] | (t & wordMask)
+		z[i+words+1] = t >> wordBits
+	}
+	return natNorm(z)
+}
+
+func natShr(x []int, s int) []int {
+	var words int = s / wordBits
+	var bits int = s % wordBits
+	if words >= len(x) {
+		return nil
+	}
+	var n int = len(x) - words
+	var z []int = make([]int, n, n)
+	var i int
+	for i = 0; i < n; i++ {
+		var t int = x[i+words] >> bits
+		if i+words+1 < len(x) {
+			t = t | ((x[i+words+1] << (wordBits - bits)) & wordMask)
+		}
+		z[i] = t
+	}
+	return natNorm(z)
+}
+
+// the quotient and the remainder of x / y for y != 0, by binary long division
+func natDivMod(x []int, y []int) ([]int, []int) {
+	if natCmp(x, y) < 0 {
+		return nil, x
+	}
+	var n int = natBitLen(x)
+	var q []int = make([]int, len(x), len(x))
+	var r []int
+	var i int
+	for i = n - 1; i >= 0; i-- {
+		r = natShl(r, 1)
+		if natBit(x, i) == 1 {
+			r = natAdd(r, []int{1})
+		}
+		if natCmp(r, y) >= 0 {
+			r = natSub(r, y)
+			q[i/wordBits] = q[i/wordBits] | (1 << (i % wordBits))
+		}
+	}
+	return natNorm(q), r
+}
+
+// the quotient and the remainder of x / d for a word d > 0
+func natDivWord(x []int, d int) ([]int, int) {
+	var q []int = make([]int, len(x), len(x))
+	var r int
+	var i int
+	for i = len(x) - 1; i >= 0; i-- {
+		var t int = r<<wordBits | x[i]
+		q[i] = t / d
+		r = t % d
+	}
+	return natNorm(q), r
+}
+
+// the greatest common divisor of x and y, by the binary algorithm
+func natGCD(x []int, y []int) []int {
+	if len(x) == 0 {
+		return y
+	}
+	if len(y) == 0 {
+		return x
+	}
+	var shift int
+	for natBit(x, shift) == 0 && natBit(y, shift) == 0 {
+		shift++
+	}
+	x = natShr(x, shift)
+	y = natShr(y, shift)
+	for len(x) != 0 {
+		for natBit(x, 0) == 0 {
+			x = natShr(x, 1)
+		}
+		for natBit(y, 0) == 0 {
+			y = natShr(y, 1)
+		}
+		if natCmp(x, y) >= 0 {
+			x = natSub(x, y)
+		} else {
+			y = natSub(y, x)
+		}
+	}
+	return natShl(y, shift)
+}
+
+func makeInt(neg bool, abs []int) *Int {
+	abs = natNorm(abs)
+	return &Int{neg: neg && len(abs) != 0, abs: abs}
+}
+
+// NewInt returns the value of x.
+func NewInt(x int) *Int {
+	var abs []int
+	var neg bool = x < 0
+	for x != 0 {
+		var w int = x % wordBase
+		if w < 0 {
+			w = -w // the minimum int cannot be negated
+		}
+		abs = append(abs, w)
+		x = x / wordBase
+	}
+	return makeInt(neg, abs)
+}
+
+// Sign returns -1, 0 or 1 as x is negative, zero or positive.
+func (x *Int) Sign() int {
+	if len(x.abs) == 0 {
+		return 0
+	}
+	if x.neg {
+		return -1
+	}
+	return 1
+}
+
+// Cmp returns -1, 0 or 1 as x is less than, equal to or greater than y.
+func (x *Int) Cmp(y *Int) int {
+	if x.neg != y.neg {
+		if x.neg {
+			return -1
+		}
+		return 1
+	}
+	var c int = natCmp(x.abs, y.abs)
+	if x.neg {
+		return -c
+	}
+	return c
+}
+
+// BitLen returns the length of the absolute value of x in bits.
+func (x *Int) BitLen() int {
+	return natBitLen(x.abs)
+}
+
+func (x *Int) Neg() *Int {
+	return makeInt(!x.neg, x.abs)
+}
+
+func (x *Int) Abs() *Int {
+	return makeInt(false, x.abs)
+}
+
+func (x *Int) Add(y *Int) *Int {
+	if x.neg == y.neg {
+		return makeInt(x.neg, natAdd(x.abs, y.abs))
+	}
+	if natCmp(x.abs, y.abs) >= 0 {
+		return makeInt(x.neg, natSub(x.abs, y.abs))
+	}
+	return makeInt(y.neg, natSub(y.abs, x.abs))
+}
+
+func (x *Int) Sub(y *Int) *Int {
+	return x.Add(y.Neg())
+}
+
+func (x *Int) Mul(y *Int) *Int {
+	return makeInt(x.neg != y.neg, natMul(x.abs, y.abs))
+}
+
+// Quo returns x / y truncated toward zero, for y != 0.
+func (x *Int) Quo(y *Int) *Int {
+	var q []int
+	q, _ = natDivMod(x.abs, y.abs)
+	return makeInt(x.neg != y.neg, q)
+}
+
+// Rem returns the remainder of Quo, which has the sign of x.
+func (x *Int) Rem(y *Int) *Int {
+	var r []int
+	_, r = natDivMod(x.abs, y.abs)
+	return makeInt(x.neg, r)
+}
+
+// Lsh returns x << s.
+func (x *Int) Lsh(s int) *Int {
+	return makeInt(x.neg, natShl(x.abs, s))
+}
+
+// Rsh returns x >> s, which rounds toward negative infinity as for a two's complement integer.
+func (x *Int) Rsh(s int) *Int {
+	if x.neg {
+		// -x >> s == -((x - 1) >> s) - 1
+		var t []int = natShr(natSub(x.abs, []int{1}), s)
+		return makeInt(true, natAdd(t, []int{1}))
+	}
+	return makeInt(false, natShr(x.abs, s))
+}
+
+// Not returns ^x, which is -x - 1.
+func (x *Int) Not() *Int {
+	return x.Neg().Sub(NewInt(1))
+}
+
+// the words of the two's complement representation of x in n words
+func (x *Int) twos(n int) []int {
+	var z []int = make([]int, n, n)
+	var i int
+	for i = 0; i < len(x.abs); i++ {
+		z[i] = x.abs[i]
+	}
+	if !x.neg {
+		return z
+	}
+	// invert and add 1
+	var carry int = 1
+	for i = 0; i < n; i++ {
+		var t int = (z[i] ^ wordMask) + carry
+		z[i] = t & wordMask
+		carry = t >> wordBits
+	}
+	return z
+}
+
+// the value of n words in two's complement
+func fromTwos(z []int) *Int {
+	var n int = len(z)
+	if n == 0 || z[n-1]>>(wordBits-1) == 0 {
+		return makeInt(false, z)
+	}
+	var abs []int = make([]int, n, n)
+	var carry int = 1
+	var i int
+	for i = 0; i < n; i++ {
+		var t int = (z[i] ^ wordMask) + carry
+		abs[i] = t & wordMask
+		carry = t >> wordBits
+	}
+	return makeInt(true, abs)
+}
+
+// bitwise operations on the two's complement representations. op is "&", "|", "^" or "&^".
+func (x *Int) bitwise(op string, y *Int) *Int {
+	var n int = len(x.abs)
+	if len(y.abs) > n {
+		n = len(y.abs)
+	}
+	n++ // room for the sign bit
+	var a []int = x.twos(n)
+	var b []int = y.twos(n)
+	var z []int = make([]int, n, n)
+	var i int
+	for i = 0; i < n; i++ {
+		switch op {
+		case "&":
+			z[i] = a[i] & b[i]
+		case "|":
+			z[i] = a[i] | b[i]
+		case "^":
+			z[i] = a[i] ^ b[i]
+		case "&^":
+			z[i] = a[i] &^ b[i]
+		}
+	}
+	return fromTwos(z)
+}
+
+func (x *Int) And(y *Int) *Int {
+	return x.bitwise("&", y)
+}
+
+func (x *Int) Or(y *Int) *Int {
+	return x.bitwise("|", y)
+}
+
+func (x *Int) Xor(y *Int) *Int {
+	return x.bitwise("^", y)
+}
+
+func (x *Int) AndNot(y *Int) *Int {
+	return x.bitwise("&^", y)
+}
+
+// Int64 returns the low 64 bits of x in two's complement.
+func (x *Int) Int64() int {
+	var v int
+	var i int
+	for i = len(x.abs) - 1; i >= 0; i-- {
+		v = v<<wordBits | x.abs[i]
+	}
+	if x.neg {
+		return -v
+	}
+	return v
+}
+
+// String returns the decimal representation of x.
+func (x *Int) String() string {
+	if len(x.abs) == 0 {
+		return "0"
+	}
+	var digits []uint8
+	var abs []int = x.abs
+	var r int
+	for len(abs) != 0 {
+		abs, r = natDivWord(abs, 10)
+		digits = append(digits, uint8('0'+r))
+	}
+	var s []uint8
+	if x.neg {
+		s = append(s, '-')
+	}
+	var i int
+	for i = len(digits) - 1; i >= 0; i-- {
+		s = append(s, digits[i])
+	}
+	return string(s)
+}
+
+// NewRat returns num / den for den != 0.
+func NewRat(num *Int, den *Int) *Rat {
+	var g []int = natGCD(num.abs, den.abs)
+	var n []int
+	var d []int
+	n, _ = natDivMod(num.abs, g)
+	d, _ = natDivMod(den.abs, g)
+	return &Rat{num: makeInt(num.neg != den.neg, n), den: makeInt(false, d)}
+}
+
+// RatInt returns x as a fraction.
+func RatInt(x *Int) *Rat {
+	return &Rat{num: x, den: NewInt(1)}
+}
+
+func (x *Rat) Num() *Int {
+	return x.num
+}
+
+func (x *Rat) Den() *Int {
+	return x.den
+}
+
+// IsInt reports whether the denominator of x is 1.
+func (x *Rat) IsInt() bool {
+	return len(x.den.abs) == 1 && x.den.abs[0] == 1
+}
+
+func (x *Rat) Sign() int {
+	return x.num.Sign()
+}
+
+func (x *Rat) Cmp(y *Rat) int {
+	return x.num.Mul(y.den).Cmp(y.num.Mul(x.den))
+}
+
+func (x *Rat) Neg() *Rat {
+	return &Rat{num: x.num.Neg(), den: x.den}
+}
+
+func (x *Rat) Add(y *Rat) *Rat {
+	return NewRat(x.num.Mul(y.den).Add(y.num.Mul(x.den)), x.den.Mul(y.den))
+}
+
+func (x *Rat) Sub(y *Rat) *Rat {
+	return x.Add(y.Neg())
+}
+
+func (x *Rat) Mul(y *Rat) *Rat {
+	return NewRat(x.num.Mul(y.num), x.den.Mul(y.den))
+}
+
+// Quo returns x / y for y != 0.
+func (x *Rat) Quo(y *Rat) *Rat {
+	return NewRat(x.num.Mul(y.den), x.den.Mul(y.num))
+}
+
+// Float returns the nearest float with mbits bits of mantissa and an exponent of at most emax,
+// rounding half to even, and whether it is finite. emin is the least exponent of the normal floats.
+func (x *Rat) Float(mbits int, emin int, emax int) (float64, bool) {
+	if x.num.Sign() == 0 {
+		return 0, true
+	}
+	var num []int = x.num.abs
+	var den []int = x.den.abs
+	// 2^e <= num/den < 2^(e+1)
+	var e int = natBitLen(num) - natBitLen(den)
+	if e >= 0 {
+		if natCmp(num, natShl(den, e)) < 0 {
+			e--
+		}
+	} else if natCmp(natShl(num, -e), den) < 0 {
+		e--
+	}
+	if e > emax {
+		return 0, false
+	}
+	// the value in units of the last place, 2^exp
+	var exp int = e
+	if exp < emin {
+		exp = emin
+	}
+	exp = exp - mbits + 1
+	var q []int
+	var r []int
+	if exp >= 0 {
+		q, r = natDivMod(num, natShl(den, exp))
+		den = natShl(den, exp)
+	} else {
+		q, r = natDivMod(natShl(num, -exp), den)
+	}
+	var c int = natCmp(natShl(r, 1), den)
+	if c > 0 || c == 0 && natBit(q, 0) == 1 {
+		q = natAdd(q, []int{1})
+	}
+	if natBitLen(q) > mbits {
+		// rounded up to the next power of 2
+		q = natShr(q, 1)
+		exp++
+		if exp+mbits-1 > emax {
+			return 0, false
+		}
+	}
+	var f float64 = float64(makeInt(false, q).Int64())
+	for exp > 0 {
+		f = f * 2
+		exp--
+	}
+	for exp < 0 {
+		f = f / 2
+		exp++
+	}
+	if x.num.neg {
+		f = -f
+	}
+	return f, true
+}
+
+// Float64 returns the nearest float64 to x, and whether it is finite.
+func (x *Rat) Float64() (float64, bool) {
+	return x.Float(53, -1022, 1023)
+}
+
+// Float32 returns the nearest float32 to x as a float64, and whether it is finite.
+func (x *Rat) Float32() (float64, bool) {
+	return x.Float(24, -126, 127)
+}
+
+// RatFloat64 returns the exact value of a finite f.
+func RatFloat64(f float64) *Rat {
+	var neg bool = f < 0
+	if neg {
+		f = -f
+	}
+	// scale f to an integer, which is exact for a float64
+	var exp int
+	for f != 0 && f < 4503599627370496 { // 1 << 52
+		f = f * 2
+		exp--
+	}
+	for f >= 9007199254740992 { // 1 << 53
+		f = f / 2
+		exp++
+	}
+	var r *Rat
+	var m *Int = makeInt(neg, NewInt(int(f)).abs)
+	if exp >= 0 {
+		r = RatInt(m.Lsh(exp))
+	} else {
+		r = NewRat(m, NewInt(1).Lsh(-exp))
+	}
+	return r
+}
@@ -10,6 +10,7 @@ func Sprintf(format string, a ...interface{}) string {
 	var r []uint8
 	var inPercent bool
 	var argIndex int
+	var prec int = -1 // precision like "%.2f"
 
 	//syscall.Write(1, []uint8("# @@@ Sprintf start. format=" + format + "\n"))
 
@@ -19,6 +20,12 @@ func Sprintf(format string, a ...interface{}) string {
 			//syscall.Write(1, []uint8("@inPercent@"))
 			if c == '%' { // "%%"
 				r = append(r, '%')
+			} else if c == '.' {
+				prec = 0
+				continue
+			} else if '0' <= c && c <= '9' && prec >= 0 {
+				prec = prec*10 + int(c-'0')
+				continue
 			} else {
 				arg := a[argIndex]
 				var sign uint8 = c
@@ -49,6 +56,22 @@ func Sprintf(format string, a ...interface{}) string {
 					for _, _c := range []uint8(str) {
 						r = append(r, _c)
 					}
+				case 'f', 'e', 'g': // %f %e %g
+					var fprec = prec
+					if fprec < 0 && c != 'g' {
+						fprec = 6
+					}
+					switch _arg := arg.(type) {
+					case float64:
+						str = strconv.FormatFloat(_arg, c, fprec, 64)
+					case float32:
+						str = strconv.FormatFloat(float64(_arg), c, fprec, 32)
+					default:
+						str = "%!" + string([]uint8{c}) + "(" + reflect.TypeOf(arg).String() + ")"
+					}
+					for _, _c := range []uint8(str) {
+						r = append(r, _c)
+					}
 				case 'T':
 					t := reflect.TypeOf(arg)
 					str = t.String()
@@ -61,6 +84,7 @@ func Sprintf(format string, a ...interface{}) string {
 				argIndex++
 			}
 			inPercent = false
+			prec = -1
 		} else {
 			if c == '%' {
 				inPercent = true
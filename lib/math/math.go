@@ -0,0 +1,40 @@
+package math
+
+import "unsafe"
+
+func Float64bits(f float64) uint64 {
+	return *(*uint64)(unsafe.Pointer(&f))
+}
+
+func Float64frombits(b uint64) float64 {
+	return *(*float64)(unsafe.Pointer(&b))
+}
+
+func Float32bits(f float32) uint32 {
+	return *(*uint32)(unsafe.Pointer(&f))
+}
+
+func Inf(sign int) float64 {
+	var inf = Float64frombits(9218868437227405312) // 0x7FF0000000000000
+	if sign >= 0 {
+		return inf
+	}
+	return -inf
+}
+
+func NaN() float64 {
+	return Float64frombits(9221120237041090561) // 0x7FF8000000000001
+}
+
+func IsNaN(f float64) bool {
+	return f != f
+}
+
+func IsInf(f float64, sign int) bool {
+	return sign >= 0 && f == Inf(1) || sign <= 0 && f == Inf(-1)
+}
+
+// Signbit reports whether f is negative or negative zero.
+func Signbit(f float64) bool {
+	return Float64bits(f)>>63 != 0
+}
@@ -1,5 +1,7 @@
 package strconv
 
+import "github.com/DQNEO/babygo/lib/math"
+
 func Itoa(ival int) string {
 	if ival == 0 {
 		return "0"
@@ -13,17 +15,19 @@ func Itoa(ival int) string {
 	var ix = 0
 	var minus bool
 	minus = false
+	if ival < 0 {
+		// digits are taken without negation, which would overflow for the minimum int
+		minus = true
+		r[0] = '-'
+	}
 	for ix = 0; ival != 0; ix = ix + 1 {
-		if ival < 0 {
-			ival = -1 * ival
-			minus = true
-			r[0] = '-'
-		} else {
-			next = ival / 10
-			right = ival - next*10
-			ival = next
-			buf[ix] = uint8('0' + right)
+		next = ival / 10
+		right = ival - next*10
+		if right < 0 {
+			right = -right
 		}
+		ival = next
+		buf[ix] = uint8('0' + right)
 	}
 
 	var j int
@@ -37,6 +41,9 @@ func Itoa(ival int) string {
 		}
 	}
 
+	if minus {
+		return string(r[0 : ix+1])
+	}
 	return string(r[0:ix])
 }
 
@@ -65,3 +72,323 @@ func Atoi(gs string) int {
 
 	return n
 }
+
+// ParseFloat converts a decimal literal like "1.5" or "25e-3" into a float64.
+// The result is exact when the digits fit in 53 bits and the exponent is small,
+// which covers most literals written by hand.
+func ParseFloat(s string) float64 {
+	var mantissa int
+	var exp int
+	var isMinus bool
+	var i int
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		isMinus = s[0] == '-'
+		i = 1
+	}
+	var seenDot bool
+	for i < len(s) && (s[i] == '.' || isDigit(s[i])) {
+		var c = s[i]
+		i++
+		if c == '.' {
+			seenDot = true
+			continue
+		}
+		if mantissa < 100000000000000000 {
+			mantissa = mantissa*10 + int(c-'0')
+			if seenDot {
+				exp--
+			}
+		} else if !seenDot {
+			// drop digits which do not fit
+			exp++
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		var expMinus bool
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			expMinus = s[i] == '-'
+			i++
+		}
+		var e int
+		for i < len(s) && isDigit(s[i]) {
+			e = e*10 + int(s[i]-'0')
+			i++
+		}
+		if expMinus {
+			e = -e
+		}
+		exp = exp + e
+	}
+	var f = scaleByPow10(float64(mantissa), exp)
+	if isMinus {
+		return -f
+	}
+	return f
+}
+
+func isDigit(c uint8) bool {
+	return '0' <= c && c <= '9'
+}
+
+// returns x * 10^n
+func scaleByPow10(x float64, n int) float64 {
+	for n > 22 {
+		x = x * 1e22
+		n = n - 22
+	}
+	for n < -22 {
+		x = x / 1e22
+		n = n + 22
+	}
+	if n < 0 {
+		return x / pow10(-n)
+	}
+	return x * pow10(n)
+}
+
+// 10^n for 0 <= n <= 22, which is exact
+func pow10(n int) float64 {
+	var r float64 = 1
+	var i int
+	for i = 0; i < n; i++ {
+		r = r * 10
+	}
+	return r
+}
+
+// decimal digits of f > 0 rounded to n significant digits (n <= 17, further digits are printed as zeros),
+// and the position of the decimal point: f ~ 0.digits * 10^dp
+func floatDigits(f float64, n int) ([]uint8, int) {
+	var dp = decimalPointPos(f)
+	var x = scaleByPow10(f, n-dp)
+	var m = int(x)
+	// round half to even
+	if x-float64(m) > 0.5 || (x-float64(m) == 0.5 && m%2 == 1) {
+		m++
+	}
+	if m >= int(pow10(n)) {
+		m = m / 10
+		dp++
+	}
+	return []uint8(Itoa(m)), dp
+}
+
+// the smallest dp such that f < 10^dp
+func decimalPointPos(f float64) int {
+	var dp int
+	for f >= scaleByPow10(1, dp) {
+		dp++
+	}
+	for f < scaleByPow10(1, dp-1) {
+		dp--
+	}
+	return dp
+}
+
+// the shortest digits which read back as f
+func shortestFloatDigits(f float64, bitSize int) ([]uint8, int) {
+	var n int
+	for n = 1; n < 17; n++ {
+		var digits, dp = floatDigits(f, n)
+		var g = scaleByPow10(float64(Atoi(string(digits))), dp-n)
+		if bitSize == 32 {
+			g = float64(float32(g))
+		}
+		if g == f {
+			return digits, dp
+		}
+	}
+	var digits, dp = floatDigits(f, 17)
+	return digits, dp
+}
+
+// FormatFloat converts f to a string in the format 'e' (-d.dddde±dd), 'f' (-ddd.dddd) or 'g' ('e' for large exponents, 'f' otherwise).
+// A precision of -1 uses the smallest number of digits necessary to represent f as a float of bitSize bits.
+func FormatFloat(f float64, fmt uint8, prec int, bitSize int) string {
+	if f != f {
+		return "NaN"
+	}
+	var neg bool
+	if f < 0 || (f == 0 && 1/f < 0) {
+		neg = true
+		f = -f
+	}
+	if math.IsInf(f, 0) {
+		if neg {
+			return "-Inf"
+		}
+		return "+Inf"
+	}
+
+	var digits []uint8
+	var dp int
+	var shortest = prec < 0
+	if f == 0 {
+		// no digits
+	} else if shortest {
+		digits, dp = shortestFloatDigits(f, bitSize)
+	} else {
+		var n = prec
+		switch fmt {
+		case 'e':
+			n = prec + 1
+		case 'f':
+			n = decimalPointPos(f) + prec
+		case 'g':
+			if prec == 0 {
+				prec = 1
+				n = 1
+			}
+		}
+		if n > 17 {
+			n = 17
+		}
+		if n > 0 {
+			digits, dp = floatDigits(f, n)
+		} else if n == 0 && scaleByPow10(f, prec) >= 0.5 {
+			// rounded up to the last digit
+			digits = []uint8("1")
+			dp = -prec + 1
+		}
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == '0' {
+		digits = digits[0 : len(digits)-1]
+	}
+
+	var r []uint8
+	if neg {
+		r = append(r, '-')
+	}
+	switch fmt {
+	case 'e':
+		if shortest {
+			prec = len(digits) - 1
+		}
+		return string(appendFloatE(r, digits, dp, prec))
+	case 'f':
+		if shortest {
+			prec = len(digits) - dp
+			if prec < 0 {
+				prec = 0
+			}
+		}
+		return string(appendFloatF(r, digits, dp, prec))
+	case 'g':
+		var eprec = prec
+		if eprec > len(digits) && len(digits) >= dp {
+			eprec = len(digits)
+		}
+		if shortest {
+			eprec = 6
+			prec = len(digits)
+		}
+		var exp = dp - 1
+		if exp < -4 || exp >= eprec {
+			if prec > len(digits) {
+				prec = len(digits)
+			}
+			if prec < 1 {
+				prec = 1
+			}
+			return string(appendFloatE(r, digits, dp, prec-1))
+		}
+		if prec > dp {
+			prec = len(digits)
+		}
+		prec = prec - dp
+		if prec < 0 {
+			prec = 0
+		}
+		return string(appendFloatF(r, digits, dp, prec))
+	}
+	return "%" + string([]uint8{fmt})
+}
+
+func digitAt(digits []uint8, i int) uint8 {
+	if 0 <= i && i < len(digits) {
+		return digits[i]
+	}
+	return '0'
+}
+
+// -d.dddde±dd
+func appendFloatE(r []uint8, digits []uint8, dp int, prec int) []uint8 {
+	r = append(r, digitAt(digits, 0))
+	if prec > 0 {
+		r = append(r, '.')
+		var i int
+		for i = 1; i <= prec; i++ {
+			r = append(r, digitAt(digits, i))
+		}
+	}
+	r = append(r, 'e')
+	var exp = dp - 1
+	if len(digits) == 0 {
+		exp = 0
+	}
+	if exp < 0 {
+		r = append(r, '-')
+		exp = -exp
+	} else {
+		r = append(r, '+')
+	}
+	if exp < 10 {
+		r = append(r, '0')
+	}
+	for _, c := range []uint8(Itoa(exp)) {
+		r = append(r, c)
+	}
+	return r
+}
+
+// -ddd.dddd
+func appendFloatF(r []uint8, digits []uint8, dp int, prec int) []uint8 {
+	if dp > 0 {
+		var i int
+		for i = 0; i < dp; i++ {
+			r = append(r, digitAt(digits, i))
+		}
+	} else {
+		r = append(r, '0')
+	}
+	if prec > 0 {
+		r = append(r, '.')
+		var i int
+		for i = 0; i < prec; i++ {
+			r = append(r, digitAt(digits, dp+i))
+		}
+	}
+	return r
+}
+
+// Quote returns a double-quoted Go string literal representing s.
+// Control characters are written as 3-digit octal escapes, so the result is also a valid string for the assembler.
+func Quote(s string) string {
+	var r []uint8
+	r = append(r, '"')
+	for _, c := range []uint8(s) {
+		switch c {
+		case '"', '\\':
+			r = append(r, '\\')
+			r = append(r, c)
+		case '\n':
+			r = append(r, '\\')
+			r = append(r, 'n')
+		case '\t':
+			r = append(r, '\\')
+			r = append(r, 't')
+		default:
+			if c < ' ' || c == 127 {
+				r = append(r, '\\')
+				r = append(r, '0'+c/64)
+				r = append(r, '0'+c/8%8)
+				r = append(r, '0'+c%8)
+			} else {
+				r = append(r, c)
+			}
+		}
+	}
+	r = append(r, '"')
+	return string(r)
+}
@@ -5,6 +5,7 @@ type Pos int
 
 // Kind
 var INT Token = "INT"
+var FLOAT Token = "FLOAT"
 var STRING Token = "STRING"
 
 var NoPos Pos = 0
@@ -12,8 +13,116 @@ var NoPos Pos = 0
 // Token
 var ADD Token = "+"
 var SUB Token = "-"
+var MUL Token = "*"
+var QUO Token = "/"
+var REM Token = "%"
+
+var AND Token = "&"
+var OR Token = "|"
+var XOR Token = "^"
+var SHL Token = "<<"
+var SHR Token = ">>"
+var AND_NOT Token = "&^"
+
+// Keyword
+var CONST Token = "const"
+var TYPE Token = "type"
+var VAR Token = "var"
 
 func (tok Token) String() string {
 	return string(tok)
 }
 
+// A File is a source file added to a FileSet.
+type File struct {
+	name  string
+	base  int
+	size  int
+	lines []int // the offset of the first character of each line
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) Base() int {
+	return f.base
+}
+
+func (f *File) Size() int {
+	return f.size
+}
+
+// AddLine adds the offset of the first character of a line.
+// Offsets not beyond the last line are ignored.
+func (f *File) AddLine(offset int) {
+	if f.lines[len(f.lines)-1] < offset && offset < f.size {
+		f.lines = append(f.lines, offset)
+	}
+}
+
+func (f *File) Pos(offset int) Pos {
+	return Pos(f.base + offset)
+}
+
+func (f *File) Offset(p Pos) int {
+	return int(p) - f.base
+}
+
+// Line returns the line number of p, counting from 1.
+func (f *File) Line(p Pos) int {
+	var offset = f.Offset(p)
+	var lo = 0
+	var hi = len(f.lines)
+	for hi-lo > 1 {
+		var mid = (lo + hi) / 2
+		if f.lines[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo + 1
+}
+
+func (f *File) LineStart(line int) Pos {
+	return Pos(f.base + f.lines[line-1])
+}
+
+// A FileSet gives each file a range of positions, so that a Pos identifies both a file and an offset in it.
+type FileSet struct {
+	base  int
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{
+		base: 1,
+	}
+}
+
+// AddFile adds a file whose positions start at base, or at the next free position if base is negative.
+func (s *FileSet) AddFile(filename string, base int, size int) *File {
+	if base < 0 {
+		base = s.base
+	}
+	var f = &File{
+		name:  filename,
+		base:  base,
+		size:  size,
+		lines: []int{0},
+	}
+	s.base = base + size + 1
+	s.files = append(s.files, f)
+	return f
+}
+
+// File returns the file which contains p, or nil.
+func (s *FileSet) File(p Pos) *File {
+	for _, f := range s.files {
+		if f.base <= int(p) && int(p) <= f.base+f.size {
+			return f
+		}
+	}
+	return nil
+}
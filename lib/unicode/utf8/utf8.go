@@ -0,0 +1,120 @@
+// Package utf8 implements functions and constants to support text encoded in UTF-8.
+package utf8
+
+const RuneError rune = 65533 // "�", the Unicode replacement character
+const RuneSelf int = 128     // characters below RuneSelf are represented as themselves in a single byte
+const MaxRune rune = 1114111 // "\U0010FFFF", the maximum valid Unicode code point
+const UTFMax int = 4         // maximum number of bytes of a UTF-8 encoded Unicode character
+
+const surrogateMin rune = 55296 // 0xD800
+const surrogateMax rune = 57343 // 0xDFFF
+
+// DecodeRuneInString unpacks the first UTF-8 encoding in s and returns the rune and its width in bytes.
+// If s is empty it returns (RuneError, 0). If the encoding is invalid, it returns (RuneError, 1).
+func DecodeRuneInString(s string) (rune, int) {
+	if len(s) == 0 {
+		return RuneError, 0
+	}
+	var c0 = int(s[0])
+	var n int
+	var r int
+	var min int
+	if c0 < RuneSelf {
+		return rune(c0), 1
+	} else if c0 >= 194 && c0 < 224 {
+		n = 2
+		r = c0 & 31
+		min = 128
+	} else if c0 >= 224 && c0 < 240 {
+		n = 3
+		r = c0 & 15
+		min = 2048
+	} else if c0 >= 240 && c0 < 245 {
+		n = 4
+		r = c0 & 7
+		min = 65536
+	} else {
+		return RuneError, 1
+	}
+	if n > len(s) {
+		return RuneError, 1
+	}
+	for i := 1; i < n; i++ {
+		var c = int(s[i])
+		if c&192 != 128 {
+			return RuneError, 1
+		}
+		r = r<<6 | c&63
+	}
+	if r < min || !isValid(rune(r)) {
+		// overlong encoding, out of range or surrogate half
+		return RuneError, 1
+	}
+	return rune(r), n
+}
+
+func isValid(r rune) bool {
+	if r < 0 || r > MaxRune {
+		return false
+	}
+	return r < surrogateMin || r > surrogateMax
+}
+
+// RuneLen returns the number of bytes required to encode the rune.
+// It returns -1 if the rune is not a valid value to encode in UTF-8.
+func RuneLen(r rune) int {
+	if !isValid(r) {
+		return -1
+	}
+	if r < 128 {
+		return 1
+	}
+	if r < 2048 {
+		return 2
+	}
+	if r < 65536 {
+		return 3
+	}
+	return 4
+}
+
+// EncodeRune writes into p (which must be large enough) the UTF-8 encoding of the rune.
+// If the rune is out of range, it writes the encoding of RuneError.
+// It returns the number of bytes written.
+func EncodeRune(p []byte, r rune) int {
+	if !isValid(r) {
+		r = RuneError
+	}
+	var x = int(r)
+	switch RuneLen(r) {
+	case 1:
+		p[0] = byte(x)
+		return 1
+	case 2:
+		p[0] = byte(192 | x>>6)
+		p[1] = byte(128 | x&63)
+		return 2
+	case 3:
+		p[0] = byte(224 | x>>12)
+		p[1] = byte(128 | x>>6&63)
+		p[2] = byte(128 | x&63)
+		return 3
+	}
+	p[0] = byte(240 | x>>18)
+	p[1] = byte(128 | x>>12&63)
+	p[2] = byte(128 | x>>6&63)
+	p[3] = byte(128 | x&63)
+	return 4
+}
+
+// ValidString reports whether s consists entirely of valid UTF-8-encoded runes.
+func ValidString(s string) bool {
+	for len(s) > 0 {
+		var r, size = DecodeRuneInString(s)
+		if r == RuneError && size == 1 {
+			return false
+		}
+		s = s[size:]
+	}
+	return true
+}
@@ -7,10 +7,13 @@ import (
 	"os"
 	"syscall"
 
+	"github.com/DQNEO/babygo/lib/big"
+	"github.com/DQNEO/babygo/lib/math"
 	"github.com/DQNEO/babygo/lib/mylib"
 	"github.com/DQNEO/babygo/lib/path"
 	"github.com/DQNEO/babygo/lib/strconv"
 	"github.com/DQNEO/babygo/lib/strings"
+	"github.com/DQNEO/babygo/lib/unicode/utf8"
 )
 
 var __func__ = "__func__"
@@ -51,14 +54,755 @@ func emitComment(indent int, format string, a ...interface{}) {
 	fmt.Printf(format2, a...)
 }
 
+// constant value evaluated at compile time.
+// Integers are exact and floats are exact fractions, which are rounded to the precision of their type if typed.
+type constValue struct {
+	kind TypeKind // T_INT, T_FLOAT64, T_STRING or T_BOOL
+	typ  *Type    // nil if untyped
+	ival *big.Int
+	fval *big.Rat
+	sval string // value of a string
+	bval bool
+}
+
+// the size of the untyped integer constants
+const maxConstBits = 512
+
+// value of an integer constant which is used as an int
 func evalInt(expr ast.Expr) int {
+	cv := evalConst(expr)
+	if cv.kind != T_INT {
+		panic("integer constant expected")
+	}
+	if cv.typ == nil {
+		cv = convertConst(cv, tInt)
+	}
+	return cv.ival.Int64()
+}
+
+// whether the expression can be evaluated at compile time
+func isConstExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return e.Obj != nil && e.Obj.Kind == ast.Con && e.Obj != gNil
+	case *ast.ParenExpr:
+		return isConstExpr(e.X)
+	case *ast.UnaryExpr:
+		switch e.Op.String() {
+		case "+", "-", "!", "^":
+			return isConstExpr(e.X)
+		}
+		return false
+	case *ast.BinaryExpr:
+		return isConstExpr(e.X) && isConstExpr(e.Y)
+	case *ast.CallExpr:
+		if isMinMax(e.Fun) {
+			// min and max of numeric constants
+			for _, arg := range e.Args {
+				if !isConstExpr(arg) || !isNumericConstKind(getConstKind(getTypeOfExpr(arg))) {
+					return false
+				}
+			}
+			return true
+		}
+		if len(e.Args) != 1 || !isConstExpr(e.Args[0]) {
+			return false
+		}
+		argKind := getConstKind(getTypeOfExpr(e.Args[0]))
+		if isType(e.Fun) {
+			toKind := getConstKind(e2t(e.Fun))
+			return toKind == argKind || (isNumericConstKind(toKind) && isNumericConstKind(argKind))
+		}
+		fn, isIdent := e.Fun.(*ast.Ident)
+		return isIdent && fn.Obj == gLen && argKind == T_STRING
+	}
+	return false
+}
+
+// kind of the constants which can be typed as t
+func isSignedInteger(t *Type) bool {
+	switch kind(t) {
+	case T_INT, T_INT8, T_INT16, T_INT32, T_INT64:
+		return true
+	}
+	return false
+}
+
+func isUnsignedInteger(t *Type) bool {
+	switch kind(t) {
+	case T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64, T_UINTPTR:
+		return true
+	}
+	return false
+}
+
+func isFloat(t *Type) bool {
+	switch kind(t) {
+	case T_FLOAT32, T_FLOAT64:
+		return true
+	}
+	return false
+}
+
+func getConstKind(t *Type) TypeKind {
+	if isSignedInteger(t) || isUnsignedInteger(t) {
+		return T_INT
+	}
+	if isFloat(t) {
+		return T_FLOAT64
+	}
+	return kind(t)
+}
+
+func isNumericConstKind(knd TypeKind) bool {
+	return knd == T_INT || knd == T_FLOAT64
+}
+
+func evalConst(expr ast.Expr) *constValue {
 	switch e := expr.(type) {
 	case *ast.BasicLit:
-		return strconv.Atoi(e.Value)
+		switch e.Kind.String() {
+		case "INT":
+			return &constValue{kind: T_INT, ival: evalIntLit(e)}
+		case "FLOAT":
+			return &constValue{kind: T_FLOAT64, fval: evalFloatLit(e)}
+		case "CHAR":
+			return &constValue{kind: T_INT, ival: big.NewInt(evalCharLit(e))}
+		case "STRING":
+			return &constValue{kind: T_STRING, sval: evalStringLit(e)}
+		}
+	case *ast.Ident:
+		switch e.Obj {
+		case gTrue:
+			return &constValue{kind: T_BOOL, bval: true}
+		case gFalse:
+			return &constValue{kind: T_BOOL, bval: false}
+		case gIota:
+			return &constValue{kind: T_INT, ival: big.NewInt(currentIota)}
+		}
+		if e.Obj.Kind == ast.Con {
+			return evalNamedConst(e)
+		}
+	case *ast.ParenExpr:
+		return evalConst(e.X)
+	case *ast.UnaryExpr:
+		return evalUnaryConst(e.Op.String(), evalConst(e.X))
+	case *ast.BinaryExpr:
+		return evalBinaryConst(e.Op.String(), evalConst(e.X), evalConst(e.Y))
+	case *ast.CallExpr:
+		if isMinMax(e.Fun) {
+			return evalMinMaxConst(e)
+		}
+		arg := evalConst(e.Args[0])
+		if isType(e.Fun) {
+			return convertConst(arg, e2t(e.Fun))
+		}
+		// len of a constant string
+		return &constValue{kind: T_INT, ival: big.NewInt(len(arg.sval))}
+	}
+	throw(expr)
+	return nil
+}
+
+func isMinMax(fun ast.Expr) bool {
+	fn, isIdent := fun.(*ast.Ident)
+	return isIdent && (fn.Obj == gMin || fn.Obj == gMax)
+}
+
+// The arguments take the type of a typed one, or else the kind float if any of them is a float.
+func evalMinMaxConst(e *ast.CallExpr) *constValue {
+	op := "<"
+	if e.Fun.(*ast.Ident).Obj == gMax {
+		op = ">"
+	}
+	var cvs []*constValue
+	var typ *Type
+	var knd TypeKind = T_INT
+	for _, arg := range e.Args {
+		cv := evalConst(arg)
+		if cv.typ != nil && typ == nil {
+			typ = cv.typ
+		}
+		if cv.kind == T_FLOAT64 {
+			knd = T_FLOAT64
+		}
+		cvs = append(cvs, cv)
+	}
+	if typ != nil {
+		knd = getConstKind(typ)
+	}
+	var r *constValue
+	for _, cv := range cvs {
+		if cv.kind != knd {
+			cv = convertConstKind(cv, knd)
+		}
+		if r == nil || compareConst(op, cv, r) {
+			r = cv
+		}
+	}
+	if typ != nil {
+		return convertConst(r, typ)
+	}
+	return r
+}
+
+func evalNamedConst(ident *ast.Ident) *constValue {
+	valSpec := ident.Obj.Decl.(*ast.ValueSpec)
+	outerIota := currentIota
+	currentIota = valSpec.Iota
+	cv := evalConst(getConstValue(ident))
+	currentIota = outerIota
+	if valSpec.Type != nil {
+		return convertConst(cv, e2t(valSpec.Type))
+	}
+	return cv
+}
+
+func evalUnaryConst(op string, x *constValue) *constValue {
+	r := &constValue{kind: x.kind, typ: x.typ}
+	switch op {
+	case "+":
+		r.ival = x.ival
+		r.fval = x.fval
+	case "-":
+		if x.kind == T_INT {
+			r.ival = x.ival.Neg()
+		} else {
+			r.fval = x.fval.Neg()
+		}
+	case "!":
+		r.bval = !x.bval
+	case "^":
+		if x.typ != nil && isUnsignedInteger(x.typ) {
+			// complement within the width of an unsigned type
+			var max *big.Int
+			_, max = intRange(x.typ)
+			r.ival = max.Xor(x.ival)
+		} else {
+			r.ival = x.ival.Not()
+		}
 	default:
-		panic("Unknown type")
+		panic("invalid constant operation " + op)
+	}
+	checkConstOverflow(r)
+	return r
+}
+
+func evalBinaryConst(op string, x *constValue, y *constValue) *constValue {
+	if x.kind != y.kind && isNumericConstKind(x.kind) && isNumericConstKind(y.kind) && op != "<<" && op != ">>" {
+		// an untyped operand takes the kind of the other one. Of two untyped operands the integer becomes a float.
+		if x.typ == nil && (y.typ != nil || x.kind == T_INT) {
+			x = convertConstKind(x, y.kind)
+		} else if y.typ == nil {
+			y = convertConstKind(y, x.kind)
+		}
+	}
+	if x.kind != y.kind {
+		panic("invalid constant operation: mismatched kinds " + string(x.kind) + " " + op + " " + string(y.kind))
+	}
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "&&", "||":
+		return &constValue{kind: T_BOOL, bval: compareConst(op, x, y)}
+	}
+	r := &constValue{kind: x.kind, typ: x.typ}
+	if op == "<<" || op == ">>" {
+		// the type of a shift is the type of the left operand
+		r.ival = shiftConst(op, x.ival, y.ival)
+		checkConstOverflow(r)
+		return r
+	}
+	if x.typ == nil {
+		r.typ = y.typ
+	} else if y.typ != nil && serializeType(x.typ) != serializeType(y.typ) {
+		panic("invalid constant operation: mismatched types " + serializeType(x.typ) + " " + op + " " + serializeType(y.typ))
+	}
+	switch x.kind {
+	case T_STRING:
+		if op != "+" {
+			panic("invalid constant operation " + op)
+		}
+		r.sval = x.sval + y.sval
+	case T_INT:
+		switch op {
+		case "+":
+			r.ival = x.ival.Add(y.ival)
+		case "-":
+			r.ival = x.ival.Sub(y.ival)
+		case "*":
+			r.ival = x.ival.Mul(y.ival)
+		case "&":
+			r.ival = x.ival.And(y.ival)
+		case "|":
+			r.ival = x.ival.Or(y.ival)
+		case "^":
+			r.ival = x.ival.Xor(y.ival)
+		case "&^":
+			r.ival = x.ival.AndNot(y.ival)
+		case "/", "%":
+			if y.ival.Sign() == 0 {
+				panic("invalid constant operation: division by zero")
+			}
+			if op == "/" {
+				r.ival = x.ival.Quo(y.ival)
+			} else {
+				r.ival = x.ival.Rem(y.ival)
+			}
+		default:
+			panic("invalid constant operation " + op)
+		}
+	case T_FLOAT64:
+		switch op {
+		case "+":
+			r.fval = x.fval.Add(y.fval)
+		case "-":
+			r.fval = x.fval.Sub(y.fval)
+		case "*":
+			r.fval = x.fval.Mul(y.fval)
+		case "/":
+			if y.fval.Sign() == 0 {
+				panic("invalid constant operation: division by zero")
+			}
+			r.fval = x.fval.Quo(y.fval)
+		default:
+			panic("invalid constant operation " + op)
+		}
+		r.fval = roundConstFloat(r.fval, r.typ)
+	default:
+		panic("invalid constant operation " + op)
+	}
+	checkConstOverflow(r)
+	return r
+}
+
+func compareConst(op string, x *constValue, y *constValue) bool {
+	switch x.kind {
+	case T_BOOL:
+		switch op {
+		case "&&":
+			return x.bval && y.bval
+		case "||":
+			return x.bval || y.bval
+		case "==":
+			return x.bval == y.bval
+		case "!=":
+			return x.bval != y.bval
+		}
+	case T_STRING:
+		switch op {
+		case "==":
+			return x.sval == y.sval
+		case "!=":
+			return x.sval != y.sval
+		}
+	case T_INT, T_FLOAT64:
+		var c int
+		if x.kind == T_INT {
+			c = x.ival.Cmp(y.ival)
+		} else {
+			c = x.fval.Cmp(y.fval)
+		}
+		switch op {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		}
+	}
+	panic("invalid constant operation " + op)
+	return false
+}
+
+func shiftConst(op string, x *big.Int, count *big.Int) *big.Int {
+	if count.Sign() < 0 {
+		panic("invalid constant operation: negative shift count")
+	}
+	if op == ">>" {
+		if count.BitLen() > 31 {
+			// all the bits are shifted out
+			return x.Rsh(maxConstBits)
+		}
+		return x.Rsh(count.Int64())
+	}
+	if x.Sign() == 0 {
+		return x
+	}
+	if count.Cmp(big.NewInt(maxConstBits)) > 0 {
+		panic("constant shift overflow")
+	}
+	return x.Lsh(count.Int64())
+}
+
+// conversion to a typed constant
+func convertConst(cv *constValue, t *Type) *constValue {
+	if getConstKind(t) != cv.kind && isNumericConstKind(cv.kind) && isNumericConstKind(getConstKind(t)) {
+		cv = convertConstKind(cv, getConstKind(t))
+	}
+	if getConstKind(t) != cv.kind {
+		panic("cannot convert constant to " + serializeType(t))
+	}
+	r := &constValue{kind: cv.kind, typ: t, ival: cv.ival, fval: cv.fval, sval: cv.sval, bval: cv.bval}
+	if r.kind == T_FLOAT64 {
+		r.fval = roundConstFloat(cv.fval, t)
+	}
+	checkConstOverflow(r)
+	return r
+}
+
+// conversion between an integer and a float constant
+func convertConstKind(cv *constValue, knd TypeKind) *constValue {
+	r := &constValue{kind: knd, typ: cv.typ}
+	switch knd {
+	case T_FLOAT64:
+		r.fval = big.RatInt(cv.ival)
+	case T_INT:
+		if !cv.fval.IsInt() {
+			panic("constant " + formatConstFloat(cv.fval) + " truncated to integer")
+		}
+		r.ival = cv.fval.Num()
+	default:
+		unexpectedKind(knd)
+	}
+	return r
+}
+
+// a typed float constant is rounded to the precision of its type
+func roundConstFloat(f *big.Rat, t *Type) *big.Rat {
+	if t == nil {
+		return f
+	}
+	var r float64
+	var ok bool
+	if kind(t) == T_FLOAT32 {
+		r, ok = f.Float32()
+	} else {
+		r, ok = f.Float64()
+	}
+	if !ok {
+		panic("constant " + formatConstFloat(f) + " overflows " + serializeType(t))
+	}
+	return big.RatFloat64(r)
+}
+
+// the float64 nearest to a float constant
+func constFloat64(cv *constValue) float64 {
+	var f float64
+	var ok bool
+	f, ok = cv.fval.Float64()
+	if !ok {
+		panic("constant " + formatConstFloat(cv.fval) + " overflows float64")
+	}
+	return f
+}
+
+// a float constant in the format of %g, or as "1e+309" if it is too large for a float64
+func formatConstFloat(f *big.Rat) string {
+	var r float64
+	var ok bool
+	r, ok = f.Float64()
+	if ok {
+		return strconv.FormatFloat(r, 'g', -1, 64)
+	}
+	var digits = f.Num().Quo(f.Den()).Abs().String()
+	var s = digits[0:1]
+	var i int
+	var last int
+	for i = 1; i < len(digits) && i < 6; i++ {
+		if digits[i] != '0' {
+			last = i
+		}
+	}
+	if last > 0 {
+		s = s + "." + digits[1:last+1]
+	}
+	if f.Sign() < 0 {
+		s = "-" + s
+	}
+	return s + "e+" + strconv.Itoa(len(digits)-1)
+}
+
+// the least and the greatest values of an integer type
+func intRange(t *Type) (*big.Int, *big.Int) {
+	var bits int
+	switch kind(t) {
+	case T_INT8, T_UINT8:
+		bits = 8
+	case T_INT16, T_UINT16:
+		bits = 16
+	case T_INT32, T_UINT32:
+		bits = 32
+	default:
+		bits = 64
+	}
+	var one = big.NewInt(1)
+	if isSignedInteger(t) {
+		return one.Lsh(bits - 1).Neg(), one.Lsh(bits - 1).Sub(one)
+	}
+	return big.NewInt(0), one.Lsh(bits).Sub(one)
+}
+
+func checkConstOverflow(cv *constValue) {
+	if cv.kind != T_INT {
+		return
+	}
+	if cv.typ == nil {
+		if cv.ival.BitLen() > maxConstBits {
+			panic("constant overflow")
+		}
+		return
+	}
+	var min *big.Int
+	var max *big.Int
+	min, max = intRange(cv.typ)
+	if cv.ival.Cmp(min) < 0 || cv.ival.Cmp(max) > 0 {
+		panic("constant " + cv.ival.String() + " overflows " + serializeType(cv.typ))
+	}
+}
+
+func removeUnderscores(lit string) string {
+	var r []uint8
+	for _, c := range []uint8(lit) {
+		if c != '_' {
+			r = append(r, c)
+		}
+	}
+	return string(r)
+}
+
+func digitVal(c uint8) int {
+	if '0' <= c && c <= '9' {
+		return int(c - '0')
+	}
+	if 'a' <= c && c <= 'f' {
+		return int(c-'a') + 10
+	}
+	if 'A' <= c && c <= 'F' {
+		return int(c-'A') + 10
+	}
+	return 16
+}
+
+// decimal, hexadecimal, octal or binary integer literal with optional underscores
+func evalIntLit(e *ast.BasicLit) *big.Int {
+	var val = removeUnderscores(e.Value)
+	var base = 10
+	var i = 0
+	if len(val) > 1 && val[0] == '0' {
+		switch val[1] {
+		case 'x', 'X':
+			base = 16
+			i = 2
+		case 'b', 'B':
+			base = 2
+			i = 2
+		case 'o', 'O':
+			base = 8
+			i = 2
+		default:
+			base = 8
+			i = 1
+		}
+	}
+	var n = big.NewInt(0)
+	for i < len(val) {
+		d := digitVal(val[i])
+		if d >= base {
+			panic("invalid digit in literal " + e.Value)
+		}
+		n = n.Mul(big.NewInt(base)).Add(big.NewInt(d))
+		i++
+	}
+	return n
+}
+
+// decimal float literal like "1.5", ".5" or "25e-3" with optional underscores
+func evalFloatLit(e *ast.BasicLit) *big.Rat {
+	var val = removeUnderscores(e.Value)
+	var mantissa = big.NewInt(0)
+	var exp int
+	var i int
+	var seenDot bool
+	for i < len(val) && (val[i] == '.' || digitVal(val[i]) < 10) {
+		if val[i] == '.' {
+			seenDot = true
+		} else {
+			mantissa = mantissa.Mul(big.NewInt(10)).Add(big.NewInt(digitVal(val[i])))
+			if seenDot {
+				exp--
+			}
+		}
+		i++
+	}
+	if i < len(val) && (val[i] == 'e' || val[i] == 'E') {
+		i++
+		var expMinus bool
+		if i < len(val) && (val[i] == '-' || val[i] == '+') {
+			expMinus = val[i] == '-'
+			i++
+		}
+		var n int
+		for i < len(val) {
+			n = n*10 + digitVal(val[i])
+			if n > 10000 {
+				panic("invalid constant: exponent too large in " + e.Value)
+			}
+			i++
+		}
+		if expMinus {
+			n = -n
+		}
+		exp = exp + n
+	}
+	if i != len(val) {
+		panic("invalid float literal " + e.Value)
+	}
+	var pow = big.NewInt(1)
+	var ten = big.NewInt(10)
+	var j int
+	for j = 0; j < exp || j < -exp; j++ {
+		pow = pow.Mul(ten)
+	}
+	if exp >= 0 {
+		return big.RatInt(mantissa.Mul(pow))
+	}
+	return big.NewRat(mantissa, pow)
+}
+
+// decode the character or the escape sequence at lit[i], and return its value and the index after it.
+// https://golang.org/ref/spec#Rune_literals
+func evalEscape(lit string, i int) (int, int) {
+	if lit[i] != '\\' {
+		return int(lit[i]), i + 1
+	}
+	var c = lit[i+1]
+	switch c {
+	case 'a':
+		return 7, i + 2
+	case 'b':
+		return 8, i + 2
+	case 'f':
+		return 12, i + 2
+	case 'n':
+		return 10, i + 2
+	case 'r':
+		return 13, i + 2
+	case 't':
+		return 9, i + 2
+	case 'v':
+		return 11, i + 2
+	case '\\', '\'', '"':
+		return int(c), i + 2
+	}
+	var base int
+	var ndigits int
+	switch c {
+	case 'x':
+		base = 16
+		ndigits = 2
+		i = i + 2
+	case 'u':
+		base = 16
+		ndigits = 4
+		i = i + 2
+	case 'U':
+		base = 16
+		ndigits = 8
+		i = i + 2
+	default:
+		base = 8
+		ndigits = 3
+		i = i + 1
+	}
+	var v int
+	for j := 0; j < ndigits; j++ {
+		d := digitVal(lit[i+j])
+		if d >= base {
+			panic("invalid escape sequence in " + lit)
+		}
+		v = v*base + d
+	}
+	return v, i + ndigits
+}
+
+// https://golang.org/ref/spec#Rune_literals
+func evalCharLit(e *ast.BasicLit) int {
+	if e.Value[1] >= uint8(utf8.RuneSelf) {
+		var r, _ = utf8.DecodeRuneInString(e.Value[1:])
+		return int(r)
+	}
+	var v, _ = evalEscape(e.Value, 1)
+	return v
+}
+
+// value of an interpreted or raw string literal
+// https://golang.org/ref/spec#String_literals
+func evalStringLit(e *ast.BasicLit) string {
+	var lit = e.Value
+	var buf []uint8
+	if lit[0] == '`' {
+		// carriage returns are discarded from raw strings
+		for _, c := range []uint8(lit[1 : len(lit)-1]) {
+			if c != '\r' {
+				buf = append(buf, c)
+			}
+		}
+		return string(buf)
+	}
+	var encoded = make([]uint8, utf8.UTFMax, utf8.UTFMax)
+	var i = 1
+	for i < len(lit)-1 {
+		var isUnicode = lit[i] == '\\' && (lit[i+1] == 'u' || lit[i+1] == 'U')
+		var v, next = evalEscape(lit, i)
+		if isUnicode {
+			n := utf8.EncodeRune(encoded, rune(v))
+			for j := 0; j < n; j++ {
+				buf = append(buf, encoded[j])
+			}
+		} else {
+			// \x and octal escapes denote a single byte
+			buf = append(buf, uint8(v))
+		}
+		i = next
+	}
+	return string(buf)
+}
+
+// push a folded integer, float or boolean constant
+func emitConstValue(cv *constValue) {
+	switch cv.kind {
+	case T_BOOL:
+		if cv.bval {
+			emitTrue()
+		} else {
+			emitFalse()
+		}
+	case T_INT:
+		emitPushInt(cv.ival.Int64(), "constant")
+	case T_FLOAT64:
+		// floats are pushed as their bit pattern
+		emitPushInt(int(math.Float64bits(constFloat64(cv))), "float constant")
+	default:
+		unexpectedKind(cv.kind)
+	}
+}
+
+// pushq takes only a 32-bit immediate
+func emitPushInt(ival int, comment string) {
+	if -2147483648 <= ival && ival <= 2147483647 {
+		fmt.Printf("  pushq $%d # %s\n", ival, comment)
+	} else {
+		fmt.Printf("  movq $%d, %%rax # %s\n", ival, comment)
+		fmt.Printf("  pushq %%rax\n")
 	}
-	return 0
 }
 
 func emitPopPrimitive(comment string) {
@@ -96,7 +840,8 @@ func emitPushStackTop(condType *Type, offset int, comment string) {
 		fmt.Printf("  movq %d+0(%%rsp), %%rax # copy str.ptr from stack top (%s)\n", offset, comment)
 		fmt.Printf("  pushq %%rcx # str.len\n")
 		fmt.Printf("  pushq %%rax # str.ptr\n")
-	case T_POINTER, T_UINTPTR, T_BOOL, T_INT, T_UINT8, T_UINT16:
+	case T_POINTER, T_UINTPTR, T_BOOL, T_INT, T_INT8, T_INT16, T_INT32, T_INT64,
+		T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64, T_FLOAT32, T_FLOAT64, T_MAP, T_CHAN, T_FUNC:
 		fmt.Printf("  movq %d(%%rsp), %%rax # copy stack top value (%s) \n", offset, comment)
 		fmt.Printf("  pushq %%rax\n")
 	default:
@@ -147,13 +892,10 @@ func emitLoadAndPush(t *Type) {
 		fmt.Printf("  movq %d(%%rax), %%rax # dtype\n", 0)
 		fmt.Printf("  pushq %%rdx # data\n")
 		fmt.Printf("  pushq %%rax # dtype\n")
-	case T_UINT8:
-		fmt.Printf("  movzbq %d(%%rax), %%rax # load uint8\n", 0)
-		fmt.Printf("  pushq %%rax\n")
-	case T_UINT16:
-		fmt.Printf("  movzwq %d(%%rax), %%rax # load uint16\n", 0)
+	case T_INT8, T_INT16, T_INT32, T_UINT8, T_UINT16, T_UINT32, T_FLOAT32:
+		emitLoadNarrowValue(kind(t), "0(%rax)")
 		fmt.Printf("  pushq %%rax\n")
-	case T_INT, T_BOOL, T_UINTPTR, T_POINTER:
+	case T_INT, T_INT64, T_UINT, T_UINT64, T_FLOAT64, T_BOOL, T_UINTPTR, T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		fmt.Printf("  movq %d(%%rax), %%rax # load int\n", 0)
 		fmt.Printf("  pushq %%rax\n")
 	case T_ARRAY, T_STRUCT:
@@ -164,11 +906,68 @@ func emitLoadAndPush(t *Type) {
 	}
 }
 
-func emitVariableAddr(variable *Variable) {
-	emitComment(2, "emit Addr of variable \"%s\" \n", variable.Name)
+// load a value narrower than 8 bytes into %rax.
+// An integer is extended by its signedness and a float32 is widened to float64.
+func emitLoadNarrowValue(knd TypeKind, src string) {
+	switch knd {
+	case T_INT8:
+		fmt.Printf("  movsbq %s, %%rax # load int8\n", src)
+	case T_INT16:
+		fmt.Printf("  movswq %s, %%rax # load int16\n", src)
+	case T_INT32:
+		fmt.Printf("  movslq %s, %%rax # load int32\n", src)
+	case T_UINT8:
+		fmt.Printf("  movzbq %s, %%rax # load uint8\n", src)
+	case T_UINT16:
+		fmt.Printf("  movzwq %s, %%rax # load uint16\n", src)
+	case T_UINT32:
+		fmt.Printf("  movl %s, %%eax # load uint32\n", src)
+	case T_FLOAT32:
+		fmt.Printf("  movss %s, %%xmm0 # load float32\n", src)
+		fmt.Printf("  cvtss2sd %%xmm0, %%xmm0\n")
+		fmt.Printf("  movq %%xmm0, %%rax\n")
+	default:
+		unexpectedKind(knd)
+	}
+}
 
+// Integers narrower than 8 bytes are kept sign or zero extended in registers.
+// Arithmetic on them drops the carried out bits so that the result wraps around.
+// Likewise a float32 is kept as a float64 and its results are rounded to float32.
+func emitWrapAround(t *Type) {
+	switch kind(t) {
+	case T_INT8:
+		fmt.Printf("  movsbq %%al, %%rax # wrap around int8\n")
+	case T_INT16:
+		fmt.Printf("  movswq %%ax, %%rax # wrap around int16\n")
+	case T_INT32:
+		fmt.Printf("  movslq %%eax, %%rax # wrap around int32\n")
+	case T_UINT8:
+		fmt.Printf("  movzbq %%al, %%rax # wrap around uint8\n")
+	case T_UINT16:
+		fmt.Printf("  movzwq %%ax, %%rax # wrap around uint16\n")
+	case T_UINT32:
+		fmt.Printf("  movl %%eax, %%eax # wrap around uint32\n")
+	case T_FLOAT32:
+		fmt.Printf("  movq %%rax, %%xmm0\n")
+		fmt.Printf("  cvtsd2ss %%xmm0, %%xmm0 # round to float32\n")
+		fmt.Printf("  cvtss2sd %%xmm0, %%xmm0\n")
+		fmt.Printf("  movq %%xmm0, %%rax\n")
+	}
+}
+
+func emitVariableAddr(variable *Variable) {
+	emitComment(2, "emit Addr of variable \"%s\" \n", variable.Name)
+
+	captureIndex := getCaptureIndex(currentFunc, variable)
 	if variable.IsGlobal {
 		fmt.Printf("  leaq %s(%%rip), %%rax # global variable \"%s\"\n", variable.GlobalSymbol, variable.Name)
+	} else if captureIndex >= 0 {
+		// free variable of a func literal
+		fmt.Printf("  movq %d(%%rbp), %%rax # closure\n", currentFunc.ClosureVar.LocalOffset)
+		fmt.Printf("  movq %d(%%rax), %%rax # captured variable \"%s\"\n", SizeOfPtr*(captureIndex+1), variable.Name)
+	} else if variable.IsEscaped {
+		fmt.Printf("  movq %d(%%rbp), %%rax # escaped variable \"%s\"\n", variable.BoxOffset, variable.Name)
 	} else {
 		fmt.Printf("  leaq %d(%%rbp), %%rax # local variable \"%s\"\n", variable.LocalOffset, variable.Name)
 	}
@@ -176,19 +975,45 @@ func emitVariableAddr(variable *Variable) {
 	fmt.Printf("  pushq %%rax # variable address\n")
 }
 
-func emitListHeadAddr(list ast.Expr) {
+func getCaptureIndex(fnc *ast.Func, vr *Variable) int {
+	if fnc == nil {
+		return -1
+	}
+	for i, captured := range fnc.Captures {
+		if captured == vr {
+			return i
+		}
+	}
+	return -1
+}
+
+// allocate a heap cell for a variable captured by a closure
+func emitNewBox(vr *Variable) {
+	if vr == nil || !vr.IsEscaped {
+		return
+	}
+	emitCallMalloc(getSizeOfType(vr.Typ), !hasPointers(vr.Typ))
+	fmt.Printf("  popq %%rax # box\n")
+	fmt.Printf("  movq %%rax, %d(%%rbp) # box of \"%s\"\n", vr.BoxOffset, vr.Name)
+}
+
+// push the address of the first element, and then the length
+func emitListHeadAddrAndLen(list ast.Expr) {
 	t := getTypeOfExpr(list)
 	switch kind(t) {
 	case T_ARRAY:
 		emitAddr(list) // array head
+		emitLen(list)
 	case T_SLICE:
 		emitExpr(list, nil)
 		emitPopSlice()
 		fmt.Printf("  pushq %%rax # slice.ptr\n")
+		fmt.Printf("  pushq %%rcx # slice.len\n")
 	case T_STRING:
 		emitExpr(list, nil)
 		emitPopString()
 		fmt.Printf("  pushq %%rax # string.ptr\n")
+		fmt.Printf("  pushq %%rcx # string.len\n")
 	default:
 		unexpectedKind(kind(t))
 	}
@@ -205,12 +1030,22 @@ func emitAddr(expr ast.Expr) {
 		vr := obj2var(e.Obj)
 		emitVariableAddr(vr)
 	case *ast.IndexExpr:
+		if kind(getTypeOfExpr(e.X)) == T_MAP {
+			// read only. m[k] = v is handled by emitAddrForMapSet
+			emitMapGet(e)
+			fmt.Printf("  popq %%rax # addr of value\n")
+			fmt.Printf("  popq %%rcx # ok (unused)\n")
+			fmt.Printf("  pushq %%rax # addr of value\n")
+			return
+		}
 		emitExpr(e.Index, nil) // index number
 		list := e.X
 		elmType := getTypeOfExpr(e)
-		emitListElementAddr(list, elmType)
+		emitListElementAddr(list, elmType, e.Lbrack)
 	case *ast.StarExpr:
 		emitExpr(e.X, nil)
+	case *ast.ParenExpr:
+		emitAddr(e.X)
 	case *ast.SelectorExpr:
 		if isQI(e) { // pkg.SomeType
 			ident := lookupForeignIdent(selector2QI(e))
@@ -231,6 +1066,10 @@ func emitAddr(expr ast.Expr) {
 
 			field := lookupStructField(structTypeLiteral, e.Sel.Name)
 			offset := getStructFieldOffset(field)
+			if offset >= minLegalPointer {
+				fmt.Printf("  movq 0(%%rsp), %%rax # struct head address\n")
+				emitNilCheck()
+			}
 			emitAddConst(offset, "struct head address + struct.field offset")
 		}
 	case *ast.CompositeLit:
@@ -267,33 +1106,131 @@ func isType(expr ast.Expr) bool {
 		return isType(e.X)
 	case *ast.InterfaceType:
 		return true
+	case *ast.MapType:
+		return true
+	case *ast.ChanType:
+		return true
+	case *ast.FuncType:
+		return true
 	}
 	return false
 }
 
 // explicit conversion T(e)
+// a conversion to a narrower integer type keeps the low bits
+func emitIntegerConversion(toType *Type) {
+	if getSizeOfType(toType) == 8 {
+		return
+	}
+	fmt.Printf("  popq %%rax\n")
+	emitWrapAround(toType)
+	fmt.Printf("  pushq %%rax\n")
+}
+
+// conversion between integer and float types
+func emitNumericConversion(fromType *Type, toType *Type) {
+	if !isFloat(fromType) && !isFloat(toType) {
+		emitIntegerConversion(toType)
+		return
+	}
+	fmt.Printf("  popq %%rax\n")
+	if isFloat(fromType) && !isFloat(toType) {
+		emitFloatToInteger(isUnsignedInteger(toType) && getSizeOfType(toType) == 8)
+	} else if !isFloat(fromType) && isFloat(toType) {
+		emitIntegerToFloat(isUnsignedInteger(fromType) && getSizeOfType(fromType) == 8)
+	}
+	emitWrapAround(toType)
+	fmt.Printf("  pushq %%rax\n")
+}
+
+// convert the float in %rax to an integer, truncating toward zero
+func emitFloatToInteger(toUint64 bool) {
+	fmt.Printf("  movq %%rax, %%xmm0\n")
+	if !toUint64 {
+		fmt.Printf("  cvttsd2siq %%xmm0, %%rax\n")
+		return
+	}
+	// values from 2^63 do not fit in a signed integer. Take 2^63 off and put it back as the top bit
+	labelid++
+	labelBig := fmt.Sprintf(".L.%d.big", labelid)
+	labelExit := fmt.Sprintf(".L.%d.exit", labelid)
+	fmt.Printf("  movq $%d, %%rcx # 2^63 in float64\n", 4890909195324358656) // 0x43E0000000000000
+	fmt.Printf("  movq %%rcx, %%xmm1\n")
+	fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+	fmt.Printf("  jae %s\n", labelBig)
+	fmt.Printf("  cvttsd2siq %%xmm0, %%rax\n")
+	fmt.Printf("  jmp %s\n", labelExit)
+	fmt.Printf("  %s:\n", labelBig)
+	fmt.Printf("  subsd %%xmm1, %%xmm0\n")
+	fmt.Printf("  cvttsd2siq %%xmm0, %%rax\n")
+	fmt.Printf("  btcq $63, %%rax\n")
+	fmt.Printf("  %s:\n", labelExit)
+}
+
+// convert the integer in %rax to a float64
+func emitIntegerToFloat(fromUint64 bool) {
+	if !fromUint64 {
+		fmt.Printf("  cvtsi2sdq %%rax, %%xmm0\n")
+		fmt.Printf("  movq %%xmm0, %%rax\n")
+		return
+	}
+	// halve a value with the top bit set, keeping the lowest bit for rounding, and double it back
+	labelid++
+	labelBig := fmt.Sprintf(".L.%d.big", labelid)
+	labelExit := fmt.Sprintf(".L.%d.exit", labelid)
+	fmt.Printf("  testq %%rax, %%rax\n")
+	fmt.Printf("  js %s\n", labelBig)
+	fmt.Printf("  cvtsi2sdq %%rax, %%xmm0\n")
+	fmt.Printf("  jmp %s\n", labelExit)
+	fmt.Printf("  %s:\n", labelBig)
+	fmt.Printf("  movq %%rax, %%rcx\n")
+	fmt.Printf("  shrq $1, %%rcx\n")
+	fmt.Printf("  andq $1, %%rax\n")
+	fmt.Printf("  orq %%rax, %%rcx\n")
+	fmt.Printf("  cvtsi2sdq %%rcx, %%xmm0\n")
+	fmt.Printf("  addsd %%xmm0, %%xmm0\n")
+	fmt.Printf("  %s:\n", labelExit)
+	fmt.Printf("  movq %%xmm0, %%rax\n")
+}
+
 func emitConversion(toType *Type, arg0 ast.Expr) {
 	emitComment(2, "[emitConversion]\n")
 	switch to := toType.E.(type) {
 	case *ast.Ident:
 		switch to.Obj {
 		case gString: // string(e)
-			switch kind(getTypeOfExpr(arg0)) {
-			case T_SLICE: // string(slice)
-				emitExpr(arg0, nil) // slice
-				emitPopSlice()
-				fmt.Printf("  pushq %%rcx # str len\n")
-				fmt.Printf("  pushq %%rax # str ptr\n")
+			fromType := getTypeOfExpr(arg0)
+			switch kind(fromType) {
+			case T_SLICE:
+				if kind(getElementTypeOfListType(fromType)) == T_INT32 { // string(runes)
+					emitCallRuntime("slicerunetostring", arg0)
+				} else { // string(bytes)
+					emitExpr(arg0, nil) // slice
+					emitPopSlice()
+					fmt.Printf("  pushq %%rcx # str len\n")
+					fmt.Printf("  pushq %%rax # str ptr\n")
+				}
 			case T_STRING: // string(string)
 				emitExpr(arg0, nil)
 			default:
-				unexpectedKind(kind(getTypeOfExpr(arg0)))
+				if isSignedInteger(fromType) || isUnsignedInteger(fromType) { // string(rune)
+					emitCallRuntime("intstring", arg0)
+				} else {
+					unexpectedKind(kind(fromType))
+				}
 			}
-		case gInt, gUint8, gUint16, gUintptr: // int(e)
+		case gInt, gInt8, gInt16, gInt32, gInt64, gUint, gUint8, gUint16, gUint32, gUint64, gUintptr,
+			gFloat32, gFloat64: // int(e), float64(e)
 			emitExpr(arg0, nil)
+			emitNumericConversion(getTypeOfExpr(arg0), toType)
 		default:
-			if to.Obj.Kind == ast.Typ {
+			if isInterface(toType) { // I(e)
+				emitExprIfc(arg0, &evalContext{_type: toType})
+			} else if to.Obj.Kind == ast.Typ {
 				emitExpr(arg0, nil)
+				if isSignedInteger(toType) || isUnsignedInteger(toType) || isFloat(toType) {
+					emitNumericConversion(getTypeOfExpr(arg0), toType)
+				}
 			} else {
 				throw(to.Obj)
 			}
@@ -314,6 +1251,10 @@ func emitConversion(toType *Type, arg0 ast.Expr) {
 			throw(to)
 		}
 		assert(kind(getTypeOfExpr(arg0)) == T_STRING, "source type should be slice", __func__)
+		if kind(e2t(arrayType.Elt)) == T_INT32 {
+			emitCallRuntime("stringtoslicerune", arg0)
+			return
+		}
 		emitComment(2, "Conversion of string => slice \n")
 		emitExpr(arg0, nil)
 		emitPopString()
@@ -325,18 +1266,32 @@ func emitConversion(toType *Type, arg0 ast.Expr) {
 	case *ast.StarExpr: // (*T)(arg0)
 		emitExpr(arg0, nil)
 	case *ast.InterfaceType:
-		emitExpr(arg0, nil)
-		if isInterface(getTypeOfExpr(arg0)) {
-			// do nothing
-		} else {
-			// Convert dynamic value to interface
-			emitConvertToInterface(getTypeOfExpr(arg0))
-		}
+		// Convert dynamic value to interface
+		emitExprIfc(arg0, &evalContext{_type: toType})
 	default:
 		throw(to)
 	}
 }
 
+// A string to append or copy to a []byte is converted to a slice which shares its bytes.
+func bytesOfString(e ast.Expr, elmType *Type) ast.Expr {
+	if kind(getTypeOfExpr(e)) != T_STRING {
+		return e
+	}
+	return &ast.CallExpr{
+		Fun:  &ast.ArrayType{Elt: elmType.E},
+		Args: []ast.Expr{e},
+	}
+}
+
+// call a runtime function which takes a single argument
+func emitCallRuntime(name string, arg ast.Expr) {
+	ff := lookupForeignFunc(newQI("runtime", name))
+	emitAllocReturnVarsAreaFF(ff)
+	emitExpr(arg, nil) // an integer argument is pushed as int
+	emitCallFF(ff)
+}
+
 func emitZeroValue(t *Type) {
 	switch kind(t) {
 	case T_SLICE:
@@ -349,12 +1304,13 @@ func emitZeroValue(t *Type) {
 	case T_INTERFACE:
 		fmt.Printf("  pushq $0 # interface data\n")
 		fmt.Printf("  pushq $0 # interface dtype\n")
-	case T_INT, T_UINTPTR, T_UINT8, T_POINTER, T_BOOL:
+	case T_INT, T_INT8, T_INT16, T_INT32, T_INT64, T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64,
+		T_FLOAT32, T_FLOAT64, T_UINTPTR, T_POINTER, T_BOOL, T_MAP, T_CHAN, T_FUNC:
 		fmt.Printf("  pushq $0 # %s zero value\n", string(kind(t)))
-	case T_STRUCT:
+	case T_STRUCT, T_ARRAY:
 		structSize := getSizeOfType(t)
-		emitComment(2, "zero value of a struct. size=%d (allocating on heap)\n", structSize)
-		emitCallMalloc(structSize)
+		emitComment(2, "zero value of a struct or an array. size=%d (allocating on heap)\n", structSize)
+		emitCallMalloc(structSize, !hasPointers(t))
 	default:
 		unexpectedKind(kind(t))
 	}
@@ -373,6 +1329,16 @@ func emitLen(arg ast.Expr) {
 		emitExpr(arg, nil)
 		emitPopString()
 		fmt.Printf("  pushq %%rcx # len\n")
+	case T_MAP:
+		ff := lookupForeignFunc(newQI("runtime", "mapLen"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitExpr(arg, nil)
+		emitCallFF(ff)
+	case T_CHAN:
+		ff := lookupForeignFunc(newQI("runtime", "chanlen"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitExpr(arg, nil)
+		emitCallFF(ff)
 	default:
 		unexpectedKind(kind(getTypeOfExpr(arg)))
 	}
@@ -389,15 +1355,26 @@ func emitCap(arg ast.Expr) {
 		fmt.Printf("  pushq %%rdx # cap\n")
 	case T_STRING:
 		panic("cap() cannot accept string type")
+	case T_CHAN:
+		ff := lookupForeignFunc(newQI("runtime", "chancap"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitExpr(arg, nil)
+		emitCallFF(ff)
 	default:
 		unexpectedKind(kind(getTypeOfExpr(arg)))
 	}
 }
 
-func emitCallMalloc(size int) {
+// An object without pointers is not scanned by the garbage collector.
+func emitCallMalloc(size int, noscan bool) {
 	// call malloc and return pointer
-	ff := lookupForeignFunc(newQI("runtime", "malloc"))
+	ff := lookupForeignFunc(newQI("runtime", "mallocgc"))
 	emitAllocReturnVarsAreaFF(ff)
+	if noscan {
+		fmt.Printf("  pushq $1 # noscan\n")
+	} else {
+		fmt.Printf("  pushq $0 # noscan\n")
+	}
 	fmt.Printf("  pushq $%d\n", size)
 	emitCallFF(ff)
 }
@@ -430,7 +1407,7 @@ func emitArrayLiteral(arrayType *ast.ArrayType, arrayLen int, elts []ast.Expr) {
 	elmType := e2t(arrayType.Elt)
 	elmSize := getSizeOfType(elmType)
 	memSize := elmSize * arrayLen
-	emitCallMalloc(memSize) // push
+	emitCallMalloc(memSize, !hasPointers(elmType)) // push
 	for i, elm := range elts {
 		// push lhs address
 		emitPushStackTop(tUintptr, 0, "malloced address")
@@ -445,6 +1422,156 @@ func emitArrayLiteral(arrayType *ast.ArrayType, arrayLen int, elts []ast.Expr) {
 	}
 }
 
+func emitMapLiteral(mapType *Type, elts []ast.Expr) {
+	emitComment(2, "emitMapLiteral\n")
+	keyType := getKeyTypeOfMapType(mapType)
+	valueType := getElementTypeOfListType(mapType)
+	emitMakeMap(mapType, newNumberLiteral(len(elts))) // push
+	ff := lookupForeignFunc(newQI("runtime", "mapAssign"))
+	for _, elm := range elts {
+		kvExpr := elm.(*ast.KeyValueExpr)
+		// push lhs address
+		emitAllocReturnVarsAreaFF(ff)
+		emitMapKey(kvExpr.Key, keyType)
+		emitPushStackTop(tUintptr, 24, "map")
+		emitCallFF(ff)
+		// push rhs value
+		ctx := &evalContext{
+			_type: valueType,
+		}
+		emitExprIfc(kvExpr.Value, ctx)
+		// assign
+		emitStore(valueType, true, false)
+	}
+}
+
+// make(map[K]V, size)
+// Constant sizes are checked at compile time. Others are checked by the runtime.
+func checkMakeArgs(t *Type, sizes []ast.Expr) {
+	maxArgs := 1
+	names := []string{"size"}
+	if kind(t) == T_SLICE {
+		maxArgs = 2
+		names = []string{"len", "cap"}
+		if len(sizes) == 0 {
+			panic("invalid operation: make(" + serializeType(t) + ") expects 2 or 3 arguments")
+		}
+	}
+	if len(sizes) > maxArgs {
+		panic("invalid operation: too many arguments to make(" + serializeType(t) + ")")
+	}
+	for i, size := range sizes {
+		if isConstExpr(size) && evalInt(size) < 0 {
+			panic("invalid argument: negative " + names[i] + " argument in make(" + serializeType(t) + ")")
+		}
+	}
+	if len(sizes) == 2 && isConstExpr(sizes[0]) && isConstExpr(sizes[1]) && evalInt(sizes[0]) > evalInt(sizes[1]) {
+		panic("invalid argument: len larger than cap in make(" + serializeType(t) + ")")
+	}
+}
+
+func emitMakeMap(mapType *Type, size ast.Expr) {
+	valueSize := getSizeOfType(getElementTypeOfListType(mapType))
+	ff := lookupForeignFunc(newQI("runtime", "makeMap"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq $%d # value size\n", valueSize)
+	emitExpr(size, nil)
+	emitCallFF(ff)
+}
+
+// Map keys are passed to the runtime as interface values
+func emitMapKey(key ast.Expr, keyType *Type) {
+	ctx := &evalContext{
+		_type: keyType,
+	}
+	emitExprIfc(key, ctx)
+	if !isInterface(keyType) {
+		emitConvertToInterface(keyType)
+	}
+}
+
+// push the address of m[k] and the ok value
+// The address points to a zero value if the key is not found.
+func emitMapGet(e *ast.IndexExpr) {
+	mapType := getTypeOfExpr(e.X)
+	valueSize := getSizeOfType(getElementTypeOfListType(mapType))
+	ff := lookupForeignFunc(newQI("runtime", "mapAccess2"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq $%d # value size\n", valueSize)
+	emitMapKey(e.Index, getKeyTypeOfMapType(mapType))
+	emitExpr(e.X, nil) // map
+	emitCallFF(ff)
+}
+
+// push the address of m[k] to store a value. The entry is created if it does not exist.
+func emitAddrForMapSet(e *ast.IndexExpr) {
+	mapType := getTypeOfExpr(e.X)
+	ff := lookupForeignFunc(newQI("runtime", "mapAssign"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitMapKey(e.Index, getKeyTypeOfMapType(mapType))
+	emitExpr(e.X, nil) // map
+	emitCallFF(ff)
+}
+
+// make(chan T, size)
+func emitMakeChan(chanType *Type, size ast.Expr) {
+	elemSize := getSizeOfType(getElementTypeOfListType(chanType))
+	ff := lookupForeignFunc(newQI("runtime", "makechan"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitExpr(size, nil)
+	fmt.Printf("  pushq $%d # elem size\n", elemSize)
+	emitCallFF(ff)
+}
+
+// ch <- v
+func emitChanSend(ch ast.Expr, value ast.Expr) {
+	elemType := getElementTypeOfListType(getTypeOfExpr(ch))
+	ff := lookupForeignFunc(newQI("runtime", "chansend"))
+	emitAllocReturnVarsAreaFF(ff)
+	// the value is copied to the heap and passed by address
+	ctx := &evalContext{
+		_type: elemType,
+	}
+	emitExprIfc(value, ctx)
+	emitCallMalloc(getSizeOfType(elemType), !hasPointers(elemType))
+	emitStore(elemType, false, true) // heap addr pushed
+	emitExpr(ch, nil)
+	emitCallFF(ff)
+}
+
+// push the address of the received value and the ok value
+func emitChanRecv(ch ast.Expr) {
+	ff := lookupForeignFunc(newQI("runtime", "chanrecv"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitExpr(ch, nil)
+	emitCallFF(ff)
+}
+
+func isChanRecvExpr(expr ast.Expr) bool {
+	unaryExpr, ok := expr.(*ast.UnaryExpr)
+	if !ok {
+		return false
+	}
+	return unaryExpr.Op.String() == "<-"
+}
+
+func isMapIndexExpr(expr ast.Expr) bool {
+	indexExpr, ok := expr.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	return kind(getTypeOfExpr(indexExpr.X)) == T_MAP
+}
+
+// lhs address when rhs values are already on the stack
+func emitAddrForStore(lhs ast.Expr) {
+	if isMapIndexExpr(lhs) {
+		emitAddrForMapSet(lhs.(*ast.IndexExpr))
+	} else {
+		emitAddr(lhs)
+	}
+}
+
 func emitInvertBoolValue() {
 	emitPopBool("")
 	fmt.Printf("  xor $1, %%rax\n")
@@ -538,6 +1665,41 @@ func prepareArgs(funcType *ast.FuncType, receiver ast.Expr, eArgs []ast.Expr, ex
 
 // see "ABI of stack layout" in the emitFuncall comment
 func emitCall(symbol string, args []*Arg, resultList *ast.FieldList) {
+	emitAllocReturnVarsArea(getTotalFieldsSize(resultList))
+	totalParamSize := emitArgs(args)
+	emitCallQ(symbol, totalParamSize, resultList)
+}
+
+// call a func value. The closure is passed in %rdx
+func emitCallFuncValue(fun ast.Expr, args []*Arg, resultList *ast.FieldList) {
+	emitAllocReturnVarsArea(getTotalFieldsSize(resultList))
+	totalParamSize := emitArgs(args)
+	emitExpr(fun, nil)
+	fmt.Printf("  popq %%rdx # closure\n")
+	fmt.Printf("  callq *(%%rdx)\n")
+	emitFreeParametersArea(totalParamSize)
+	emitFreeAndPushReturnedValue(resultList)
+}
+
+// The receiver is passed as an interface value to the wrapper found in the method table of its dynamic type.
+func emitInterfaceMethodCall(method *ast.Field, args []*Arg) {
+	funcType := method.Type.(*ast.FuncType)
+	emitAllocReturnVarsArea(getTotalFieldsSize(funcType.Results))
+	totalParamSize := emitArgs(args)
+
+	ff := lookupForeignFunc(newQI("runtime", "findMethod"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq $%d # method id of %s\n", getMethodId(method.Name.Name, funcType), method.Name.Name)
+	fmt.Printf("  pushq 16(%%rsp) # ifc.dtype\n")
+	emitCallFF(ff)
+	fmt.Printf("  popq %%rax # method wrapper\n")
+	fmt.Printf("  callq *%%rax\n")
+	emitFreeParametersArea(totalParamSize)
+	emitFreeAndPushReturnedValue(funcType.Results)
+}
+
+// push args and return the size of the parameters area
+func emitArgs(args []*Arg) int {
 	emitComment(2, "emitArgs len=%d\n", len(args))
 
 	var totalParamSize int
@@ -546,7 +1708,6 @@ func emitCall(symbol string, args []*Arg, resultList *ast.FieldList) {
 		totalParamSize += getSizeOfType(arg.paramType)
 	}
 
-	emitAllocReturnVarsArea(getTotalFieldsSize(resultList))
 	fmt.Printf("  subq $%d, %%rsp # alloc parameters area\n", totalParamSize)
 	for _, arg := range args {
 		paramType := arg.paramType
@@ -560,7 +1721,7 @@ func emitCall(symbol string, args []*Arg, resultList *ast.FieldList) {
 		emitRegiToMem(paramType)
 	}
 
-	emitCallQ(symbol, totalParamSize, resultList)
+	return totalParamSize
 }
 
 func emitAllocReturnVarsAreaFF(ff *ForeignFunc) {
@@ -594,19 +1755,42 @@ func emitCallQ(symbol string, totalParamSize int, resultList *ast.FieldList) {
 func emitReturnStmt(s *ast.ReturnStmt) {
 	node := s.Node
 	fnc := node.Fnc
-	if len(fnc.Retvars) != len(s.Results) {
-		panic("length of return and func type do not match")
+	if len(s.Results) == 1 && len(fnc.Retvars) > 1 {
+		// return f() where f returns as many values
+		emitExpr(s.Results[0], nil)
+		for _, vr := range fnc.Retvars {
+			switch kind(vr.Typ) {
+			case T_INT8, T_INT16, T_INT32, T_UINT8, T_UINT16, T_UINT32, T_FLOAT32:
+				emitRepushNarrowValue(vr.Typ)
+			}
+			emitVariableAddr(vr)
+			emitStore(vr.Typ, false, false)
+		}
+	} else {
+		if len(fnc.Retvars) != len(s.Results) {
+			panic("length of return and func type do not match")
+		}
+		var i int
+		_len := len(s.Results)
+		for i = 0; i < _len; i++ {
+			emitAssignToVar(fnc.Retvars[i], s.Results[i])
+		}
 	}
-
-	var i int
-	_len := len(s.Results)
-	for i = 0; i < _len; i++ {
-		emitAssignToVar(fnc.Retvars[i], s.Results[i])
+	if fnc.HasDefer {
+		emitDeferReturn()
 	}
 	fmt.Printf("  leave\n")
 	fmt.Printf("  ret\n")
 }
 
+// run the deferred calls of the current frame
+func emitDeferReturn() {
+	ff := lookupForeignFunc(newQI("runtime", "deferreturn"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq %%rbp # frame\n")
+	emitCallFF(ff)
+}
+
 // caller
 func emitFreeAndPushReturnedValue(resultList *ast.FieldList) {
 	if resultList == nil {
@@ -620,11 +1804,9 @@ func emitFreeAndPushReturnedValue(resultList *ast.FieldList) {
 		knd := kind(e2t(retval0.Type))
 		switch knd {
 		case T_STRING, T_INTERFACE:
-		case T_UINT8:
-			fmt.Printf("  movzbq (%%rsp), %%rax # load uint8\n")
-			fmt.Printf("  addq $%d, %%rsp # free returnvars area\n", 1)
-			fmt.Printf("  pushq %%rax\n")
-		case T_BOOL, T_INT, T_UINTPTR, T_POINTER:
+		case T_INT8, T_INT16, T_INT32, T_UINT8, T_UINT16, T_UINT32, T_FLOAT32:
+			emitRepushNarrowValue(e2t(retval0.Type))
+		case T_BOOL, T_INT, T_INT64, T_UINT, T_UINT64, T_FLOAT64, T_UINTPTR, T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		case T_SLICE:
 		default:
 			unexpectedKind(knd)
@@ -634,6 +1816,13 @@ func emitFreeAndPushReturnedValue(resultList *ast.FieldList) {
 	}
 }
 
+// A returned value narrower than 8 bytes occupies only its size on the stack.
+func emitRepushNarrowValue(t *Type) {
+	emitLoadNarrowValue(kind(t), "(%rsp)")
+	fmt.Printf("  addq $%d, %%rsp # free returnvars area\n", getSizeOfType(t))
+	fmt.Printf("  pushq %%rax\n")
+}
+
 // ABI of stack layout in function call
 //
 // string:
@@ -667,6 +1856,13 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 	var funcType *ast.FuncType
 	var symbol string
 	var receiver ast.Expr
+	if isFuncValue(fun) {
+		// f(), s.f(), fs[i](), func(){}()
+		funcType = getUnderlyingType(getTypeOfExpr(fun)).E.(*ast.FuncType)
+		args := prepareArgs(funcType, nil, eArgs, hasEllissis)
+		emitCallFuncValue(fun, args, funcType.Results)
+		return
+	}
 	switch fn := fun.(type) {
 	case *ast.Ident:
 		// check if it's a builtin func
@@ -681,15 +1877,28 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 			typeArg := e2t(eArgs[0])
 			// size to malloc
 			size := getSizeOfType(typeArg)
-			emitCallMalloc(size)
+			emitCallMalloc(size, !hasPointers(typeArg))
 			return
 		case gMake:
 			typeArg := e2t(eArgs[0])
+			checkMakeArgs(typeArg, eArgs[1:])
 			switch kind(typeArg) {
 			case T_SLICE:
 				// make([]T, ...)
 				arrayType := getUnderlyingType(typeArg).E.(*ast.ArrayType)
 				elmSize := getSizeOfType(e2t(arrayType.Elt))
+				noscan := newBoolLiteral(!hasPointers(e2t(arrayType.Elt)))
+				if len(eArgs) == 2 {
+					// make([]T, len) has the capacity len
+					ff := lookupForeignFunc(newQI("runtime", "makeSlice"))
+					emitAllocReturnVarsAreaFF(ff)
+					emitExpr(noscan, nil)
+					emitExpr(eArgs[1], nil) // cap
+					fmt.Printf("  pushq (%%rsp) # len\n")
+					fmt.Printf("  pushq $%d # elmSize\n", elmSize)
+					emitCallFF(ff)
+					return
+				}
 				numlit := newNumberLiteral(elmSize)
 				args := []*Arg{
 					// elmSize
@@ -707,6 +1916,11 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 						e:         eArgs[2],
 						paramType: tInt,
 					},
+					// noscan
+					&Arg{
+						e:         noscan,
+						paramType: tBool,
+					},
 				}
 
 				resultList := &ast.FieldList{
@@ -718,48 +1932,96 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 				}
 				emitCall("runtime.makeSlice", args, resultList)
 				return
+			case T_MAP:
+				// make(map[K]V) or make(map[K]V, size)
+				var size ast.Expr = eZeroInt
+				if len(eArgs) > 1 {
+					size = eArgs[1]
+				}
+				emitMakeMap(typeArg, size)
+				return
+			case T_CHAN:
+				// make(chan T) or make(chan T, size)
+				var size ast.Expr = eZeroInt
+				if len(eArgs) > 1 {
+					size = eArgs[1]
+				}
+				emitMakeChan(typeArg, size)
+				return
 			default:
 				throw(typeArg)
 			}
 		case gAppend:
+			// append(s, a, b) is append(s, []T{a, b}...)
 			sliceArg := eArgs[0]
-			elemArg := eArgs[1]
 			elmType := getElementTypeOfListType(getTypeOfExpr(sliceArg))
-			elmSize := getSizeOfType(elmType)
+			var elms ast.Expr
+			if hasEllissis {
+				elms = eArgs[1]
+			} else {
+				elms = &ast.CompositeLit{
+					Type: &ast.ArrayType{Elt: elmType.E},
+					Elts: eArgs[1:],
+				}
+			}
 			args := []*Arg{
 				// slice
 				&Arg{
 					e:         sliceArg,
 					paramType: e2t(generalSlice),
 				},
-				// elm
+				// elements to append
+				&Arg{
+					e:         bytesOfString(elms, elmType),
+					paramType: e2t(generalSlice),
+				},
+				// elmSize
 				&Arg{
-					e:         elemArg,
-					paramType: elmType,
+					e:         newNumberLiteral(getSizeOfType(elmType)),
+					paramType: tInt,
+				},
+				// noscan
+				&Arg{
+					e:         newBoolLiteral(!hasPointers(elmType)),
+					paramType: tBool,
 				},
 			}
-
-			var symbol string
-			switch elmSize {
-			case 1:
-				symbol = "runtime.append1"
-			case 8:
-				symbol = "runtime.append8"
-			case 16:
-				symbol = "runtime.append16"
-			case 24:
-				symbol = "runtime.append24"
-			default:
-				throw(elmSize)
+			resultList := &ast.FieldList{
+				List: []*ast.Field{
+					&ast.Field{
+						Type: generalSlice,
+					},
+				},
+			}
+			emitCall("runtime.appendslice", args, resultList)
+			return
+		case gCopy:
+			elmType := getElementTypeOfListType(getTypeOfExpr(eArgs[0]))
+			args := []*Arg{
+				// dst
+				&Arg{
+					e:         eArgs[0],
+					paramType: e2t(generalSlice),
+				},
+				// src
+				&Arg{
+					e:         bytesOfString(eArgs[1], elmType),
+					paramType: e2t(generalSlice),
+				},
+				// elmSize
+				&Arg{
+					e:         newNumberLiteral(getSizeOfType(elmType)),
+					paramType: tInt,
+				},
 			}
 			resultList := &ast.FieldList{
 				List: []*ast.Field{
 					&ast.Field{
-						Type:  generalSlice,
+						Type: tInt.E,
 					},
 				},
 			}
-			emitCall(symbol, args, resultList)
+			emitCall("runtime.slicecopy", args, resultList)
 			return
 		case gPanic:
 			symbol = "runtime.panic"
@@ -769,11 +2031,51 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 			}}
 			emitCall(symbol, _args, nil)
 			return
+		case gRecover:
+			resultList := &ast.FieldList{
+				List: []*ast.Field{
+					&ast.Field{
+						Type: tEface.E,
+					},
+				},
+			}
+			emitCall("runtime.recover", nil, resultList)
+			return
+		case gDelete:
+			mapType := getTypeOfExpr(eArgs[0])
+			ff := lookupForeignFunc(newQI("runtime", "mapDelete"))
+			emitAllocReturnVarsAreaFF(ff)
+			emitMapKey(eArgs[1], getKeyTypeOfMapType(mapType))
+			emitExpr(eArgs[0], nil) // map
+			emitCallFF(ff)
+			return
+		case gClose:
+			ff := lookupForeignFunc(newQI("runtime", "closechan"))
+			emitAllocReturnVarsAreaFF(ff)
+			emitExpr(eArgs[0], nil)
+			emitCallFF(ff)
+			return
+		case gMin, gMax:
+			emitMinMax(fn.Obj == gMin, eArgs)
+			return
+		case gClear:
+			t := getTypeOfExpr(eArgs[0])
+			switch kind(t) {
+			case T_MAP:
+				emitCallRuntime("mapClear", eArgs[0])
+			case T_SLICE:
+				// zero the elements up to the length
+				ff := lookupForeignFunc(newQI("runtime", "clearslice"))
+				emitAllocReturnVarsAreaFF(ff)
+				fmt.Printf("  pushq $%d # elmSize\n", getSizeOfType(getElementTypeOfListType(t)))
+				emitExpr(eArgs[0], nil)
+				emitCallFF(ff)
+			default:
+				panic("invalid argument for clear: " + serializeType(t))
+			}
+			return
 		}
 
-		if fn.Name == "makeSlice1" || fn.Name == "makeSlice8" || fn.Name == "makeSlice16" || fn.Name == "makeSlice24" {
-			fn.Name = "makeSlice"
-		}
 		// general function call
 		symbol = getPackageSymbol(currentPkg.name, fn.Name)
 		if currentPkg.name == "os" && fn.Name == "runtime_args" {
@@ -791,16 +2093,33 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 			symbol = string(qi)
 			ff := lookupForeignFunc(qi)
 			funcType = ff.decl.Type
+		} else if isInterface(getTypeOfExpr(fn.X)) {
+			// ifc.method()
+			method := lookupInterfaceMethod(getTypeOfExpr(fn.X), fn.Sel)
+			funcType = method.Type.(*ast.FuncType)
+			args := prepareArgs(funcType, fn.X, eArgs, hasEllissis)
+			emitInterfaceMethodCall(method, args)
+			return
 		} else {
 			// method call
 			receiver = fn.X
 			receiverType := getTypeOfExpr(receiver)
 			method := lookupMethod(receiverType, fn.Sel)
+			if method.IsPtrMethod && kind(receiverType) != T_POINTER {
+				// x.m() is shorthand for (&x).m()
+				receiver = &ast.UnaryExpr{
+					Op: token.Token("&"),
+					X:  receiver,
+				}
+			} else if !method.IsPtrMethod && kind(receiverType) == T_POINTER {
+				// x.m() is shorthand for (*x).m()
+				receiver = &ast.StarExpr{
+					X: receiver,
+				}
+			}
 			funcType = method.FuncType
 			symbol = getMethodSymbol(method)
 		}
-	case *ast.ParenExpr:
-		panic("[astParenExpr] TBI ")
 	default:
 		throw(fun)
 	}
@@ -809,22 +2128,107 @@ func emitFuncall(fun ast.Expr, eArgs []ast.Expr, hasEllissis bool) {
 	emitCall(symbol, args, funcType.Results)
 }
 
-func emitNil(targetType *Type) {
-	if targetType == nil {
-		panic("Type is required to emit nil")
+// the callee is a value of func type rather than a declared func or method
+func isFuncValue(fun ast.Expr) bool {
+	switch fn := fun.(type) {
+	case *ast.Ident:
+		return fn.Obj.Kind == ast.Var
+	case *ast.SelectorExpr:
+		if isQI(fn) {
+			return false
+		}
+		if isType(fn.X) {
+			// T.M(x)
+			return true
+		}
+		// struct field of func type
+		ut := getUnderlyingType(getTypeOfExpr(fn.X))
+		var structTypeLiteral *ast.StructType
+		switch typ := ut.E.(type) {
+		case *ast.StructType: // strct.field
+			structTypeLiteral = typ
+		case *ast.StarExpr: // ptr.field
+			structTypeLiteral, _ = getUnderlyingType(e2t(typ.X)).E.(*ast.StructType)
+		}
+		if structTypeLiteral == nil {
+			return false
+		}
+		for _, field := range structTypeLiteral.Fields.List {
+			if getFieldName(field) == fn.Sel.Name {
+				return true
+			}
+		}
+		return false
+	default:
+		return !isType(fun)
+	}
+}
+
+// report whether the call calls an interface method, which goes through the method wrapper
+func isInterfaceMethodCall(call *ast.CallExpr) bool {
+	sel, isSel := call.Fun.(*ast.SelectorExpr)
+	if !isSel || isQI(sel) || isType(sel.X) {
+		return false
+	}
+	return isInterface(getTypeOfExpr(sel.X))
+}
+
+func emitNil(targetType *Type) {
+	if targetType == nil {
+		panic("Type is required to emit nil")
 	}
 	switch kind(targetType) {
-	case T_SLICE, T_POINTER, T_INTERFACE:
+	case T_SLICE, T_POINTER, T_INTERFACE, T_MAP, T_CHAN, T_FUNC:
 		emitZeroValue(targetType)
 	default:
 		unexpectedKind(kind(targetType))
 	}
 }
 
+// iota of the constant being evaluated
+var currentIota int
+
 func emitNamedConst(ident *ast.Ident, ctx *evalContext) {
+	cv := evalConst(ident)
+	if cv.kind == T_STRING {
+		emitExpr(getConstStringLiteral(ident), nil)
+		return
+	}
+	emitConstValue(cv)
+}
+
+type constStringLiteral struct {
+	obj *ast.Object
+	lit *ast.BasicLit
+}
+
+// literals of the folded string constants
+var constStringLiterals []*constStringLiteral
+
+func getConstStringLiteral(ident *ast.Ident) *ast.BasicLit {
+	lit, isLit := getConstValue(ident).(*ast.BasicLit)
+	if isLit {
+		return lit
+	}
+	for _, entry := range constStringLiterals {
+		if entry.obj == ident.Obj {
+			return entry.lit
+		}
+	}
+	panic("no literal for constant " + ident.Name)
+	return nil
+}
+
+// the expression which a named constant is declared with
+func getConstValue(ident *ast.Ident) ast.Expr {
 	valSpec := ident.Obj.Decl.(*ast.ValueSpec)
-	lit := valSpec.Values[0].(*ast.BasicLit)
-	emitExprIfc(lit, ctx)
+	for i, name := range valSpec.Names {
+		if name.Obj == ident.Obj {
+			return valSpec.Values[i]
+		}
+	}
+	panic("no value for constant " + ident.Name)
+	return nil
 }
 
 type okContext struct {
@@ -844,6 +2248,8 @@ func emitIdent(e *ast.Ident, ctx *evalContext) bool {
 		emitTrue()
 	case gFalse: // false constant
 		emitFalse()
+	case gIota:
+		fmt.Printf("  pushq $%d # iota\n", currentIota)
 	case gNil:
 		assert(ctx._type != nil, "context of nil is not passed", __func__)
 		emitNil(ctx._type)
@@ -856,6 +2262,8 @@ func emitIdent(e *ast.Ident, ctx *evalContext) bool {
 			emitLoadAndPush(getTypeOfExpr(e))
 		case ast.Con:
 			emitNamedConst(e, ctx)
+		case ast.Fun:
+			emitFuncValue(getPackageSymbol(currentPkg.name, e.Name))
 		default:
 			panic("Unexpected ident kind:")
 		}
@@ -864,6 +2272,21 @@ func emitIdent(e *ast.Ident, ctx *evalContext) bool {
 }
 // 1 or 2 values
 func emitIndexExpr(e *ast.IndexExpr, ctx *evalContext) {
+	if ctx != nil && ctx.okContext != nil {
+		// v, ok := m[k]
+		assert(kind(getTypeOfExpr(e.X)) == T_MAP, "should be a map", __func__)
+		emitMapGet(e)
+		fmt.Printf("  popq %%rax # addr of value\n")
+		fmt.Printf("  popq %%r8 # ok\n")
+		if ctx.okContext.needMain {
+			fmt.Printf("  pushq %%rax # addr of value\n")
+			emitLoadAndPush(getTypeOfExpr(e))
+		}
+		if ctx.okContext.needOk {
+			fmt.Printf("  pushq %%r8 # ok\n")
+		}
+		return
+	}
 	emitAddr(e)
 	emitLoadAndPush(getTypeOfExpr(e))
 }
@@ -876,8 +2299,16 @@ func emitStarExpr(e *ast.StarExpr, ctx *evalContext) {
 func emitSelectorExpr(e *ast.SelectorExpr, ctx *evalContext) {
 	// pkg.Ident or strct.field
 	if isQI(e) {
-		ident := lookupForeignIdent(selector2QI(e))
-		emitExpr(ident, ctx)
+		qi := selector2QI(e)
+		ident := lookupForeignIdent(qi)
+		if ident.Obj.Kind == ast.Fun {
+			emitFuncValue(string(qi))
+		} else {
+			emitExpr(ident, ctx)
+		}
+	} else if e.Thunk != nil {
+		// x.M or T.M
+		emitCallThunk(e.Thunk)
 	} else {
 		// strct.field
 		emitAddr(e)
@@ -902,26 +2333,11 @@ func emitParenExpr(e *ast.ParenExpr, ctx *evalContext) {
 func emitBasicLit(e *ast.BasicLit, ctx *evalContext) {
 	switch e.Kind.String() {
 	case "CHAR":
-		var val = e.Value
-		var char = val[1]
-		if val[1] == '\\' {
-			switch val[2] {
-			case '\'':
-				char = '\''
-			case 'n':
-				char = '\n'
-			case '\\':
-				char = '\\'
-			case 't':
-				char = '\t'
-			case 'r':
-				char = '\r'
-			}
-		}
-		fmt.Printf("  pushq $%d # convert char literal to int\n", int(char))
+		fmt.Printf("  pushq $%d # convert char literal to int\n", evalCharLit(e))
 	case "INT":
-		ival := strconv.Atoi(e.Value)
-		fmt.Printf("  pushq $%d # number literal\n", ival)
+		emitPushInt(evalInt(e), "number literal")
+	case "FLOAT":
+		emitConstValue(evalConst(e))
 	case "STRING":
 		sl := getStringLiteral(e)
 		if sl.strlen == 0 {
@@ -944,19 +2360,57 @@ func emitUnaryExpr(e *ast.UnaryExpr, ctx *evalContext) {
 	case "-":
 		emitExpr(e.X, nil)
 		fmt.Printf("  popq %%rax # e.X\n")
-		fmt.Printf("  imulq $-1, %%rax\n")
+		if isFloat(getTypeOfExpr(e.X)) {
+			fmt.Printf("  btcq $63, %%rax # flip the sign bit\n")
+		} else {
+			fmt.Printf("  imulq $-1, %%rax\n")
+			emitWrapAround(getTypeOfExpr(e.X))
+		}
 		fmt.Printf("  pushq %%rax\n")
 	case "&":
 		emitAddr(e.X)
 	case "!":
 		emitExpr(e.X, nil)
 		emitInvertBoolValue()
+	case "^":
+		emitExpr(e.X, nil)
+		fmt.Printf("  popq %%rax # e.X\n")
+		fmt.Printf("  notq %%rax\n")
+		emitWrapAround(getTypeOfExpr(e.X))
+		fmt.Printf("  pushq %%rax\n")
+	case "<-":
+		emitRecvExpr(e, ctx)
 	default:
 		throw(e.Op)
 	}
 }
+
+// 1 or 2 values
+func emitRecvExpr(e *ast.UnaryExpr, ctx *evalContext) {
+	elemType := getTypeOfExpr(e)
+	emitChanRecv(e.X)
+	fmt.Printf("  popq %%rax # addr of value\n")
+	fmt.Printf("  popq %%r8 # ok\n")
+	if ctx != nil && ctx.okContext != nil {
+		// v, ok := <-ch
+		if ctx.okContext.needMain {
+			fmt.Printf("  pushq %%rax # addr of value\n")
+			emitLoadAndPush(elemType)
+		}
+		if ctx.okContext.needOk {
+			fmt.Printf("  pushq %%r8 # ok\n")
+		}
+		return
+	}
+	fmt.Printf("  pushq %%rax # addr of value\n")
+	emitLoadAndPush(elemType)
+}
 // 1 value
 func emitBinaryExpr(e *ast.BinaryExpr, ctx *evalContext) {
+	if isFloat(getOperandType(e)) && !isInterface(getTypeOfExpr(e.Y)) {
+		emitFloatBinaryExpr(e)
+		return
+	}
 	switch e.Op.String() {
 	case "&&":
 		labelid++
@@ -1001,6 +2455,7 @@ func emitBinaryExpr(e *ast.BinaryExpr, ctx *evalContext) {
 			fmt.Printf("  popq %%rcx # right\n")
 			fmt.Printf("  popq %%rax # left\n")
 			fmt.Printf("  addq %%rcx, %%rax\n")
+			emitWrapAround(getTypeOfExpr(e))
 			fmt.Printf("  pushq %%rax\n")
 		}
 	case "-":
@@ -1009,6 +2464,7 @@ func emitBinaryExpr(e *ast.BinaryExpr, ctx *evalContext) {
 		fmt.Printf("  popq %%rcx # right\n")
 		fmt.Printf("  popq %%rax # left\n")
 		fmt.Printf("  subq %%rcx, %%rax\n")
+		emitWrapAround(getTypeOfExpr(e))
 		fmt.Printf("  pushq %%rax\n")
 	case "*":
 		emitExpr(e.X, nil) // left
@@ -1016,24 +2472,41 @@ func emitBinaryExpr(e *ast.BinaryExpr, ctx *evalContext) {
 		fmt.Printf("  popq %%rcx # right\n")
 		fmt.Printf("  popq %%rax # left\n")
 		fmt.Printf("  imulq %%rcx, %%rax\n")
+		emitWrapAround(getTypeOfExpr(e))
 		fmt.Printf("  pushq %%rax\n")
-	case "%":
+	case "/", "%":
+		emitDivExpr(e)
+	case "&":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
 		fmt.Printf("  popq %%rcx # right\n")
 		fmt.Printf("  popq %%rax # left\n")
-		fmt.Printf("  movq $0, %%rdx # init %%rdx\n")
-		fmt.Printf("  divq %%rcx\n")
-		fmt.Printf("  movq %%rdx, %%rax\n")
+		fmt.Printf("  andq %%rcx, %%rax\n")
 		fmt.Printf("  pushq %%rax\n")
-	case "/":
+	case "|":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
 		fmt.Printf("  popq %%rcx # right\n")
 		fmt.Printf("  popq %%rax # left\n")
-		fmt.Printf("  movq $0, %%rdx # init %%rdx\n")
-		fmt.Printf("  divq %%rcx\n")
+		fmt.Printf("  orq %%rcx, %%rax\n")
+		fmt.Printf("  pushq %%rax\n")
+	case "^":
+		emitExpr(e.X, nil) // left
+		emitExpr(e.Y, nil) // right
+		fmt.Printf("  popq %%rcx # right\n")
+		fmt.Printf("  popq %%rax # left\n")
+		fmt.Printf("  xorq %%rcx, %%rax\n")
+		fmt.Printf("  pushq %%rax\n")
+	case "&^":
+		emitExpr(e.X, nil) // left
+		emitExpr(e.Y, nil) // right
+		fmt.Printf("  popq %%rcx # right\n")
+		fmt.Printf("  popq %%rax # left\n")
+		fmt.Printf("  notq %%rcx\n")
+		fmt.Printf("  andq %%rcx, %%rax\n")
 		fmt.Printf("  pushq %%rax\n")
+	case "<<", ">>":
+		emitShiftExpr(e)
 	case "==":
 		emitBinaryExprComparison(e.X, e.Y)
 	case "!=":
@@ -1042,23 +2515,94 @@ func emitBinaryExpr(e *ast.BinaryExpr, ctx *evalContext) {
 	case "<":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
-		emitCompExpr("setl")
+		if isUnsignedInteger(getOperandType(e)) {
+			emitCompExpr("setb")
+		} else {
+			emitCompExpr("setl")
+		}
 	case "<=":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
-		emitCompExpr("setle")
+		if isUnsignedInteger(getOperandType(e)) {
+			emitCompExpr("setbe")
+		} else {
+			emitCompExpr("setle")
+		}
 	case ">":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
-		emitCompExpr("setg")
+		if isUnsignedInteger(getOperandType(e)) {
+			emitCompExpr("seta")
+		} else {
+			emitCompExpr("setg")
+		}
 	case ">=":
 		emitExpr(e.X, nil) // left
 		emitExpr(e.Y, nil) // right
-		emitCompExpr("setge")
+		if isUnsignedInteger(getOperandType(e)) {
+			emitCompExpr("setae")
+		} else {
+			emitCompExpr("setge")
+		}
 	default:
 		panic(e.Op.String())
 	}
 }
+// Floats are pushed as float64 bit patterns and computed in SSE registers
+func emitFloatBinaryExpr(e *ast.BinaryExpr) {
+	t := getOperandType(e)
+	ctx := &evalContext{_type: t}
+	emitExpr(e.X, ctx) // left
+	emitExpr(e.Y, ctx) // right
+	fmt.Printf("  popq %%rcx # right\n")
+	fmt.Printf("  popq %%rax # left\n")
+	fmt.Printf("  movq %%rax, %%xmm0\n")
+	fmt.Printf("  movq %%rcx, %%xmm1\n")
+	switch e.Op.String() {
+	case "+":
+		fmt.Printf("  addsd %%xmm1, %%xmm0\n")
+	case "-":
+		fmt.Printf("  subsd %%xmm1, %%xmm0\n")
+	case "*":
+		fmt.Printf("  mulsd %%xmm1, %%xmm0\n")
+	case "/":
+		fmt.Printf("  divsd %%xmm1, %%xmm0\n")
+	case "==":
+		// NaN is unordered and equal to nothing
+		fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+		fmt.Printf("  sete %%al\n")
+		fmt.Printf("  setnp %%cl\n")
+		fmt.Printf("  andb %%cl, %%al\n")
+	case "!=":
+		fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+		fmt.Printf("  setne %%al\n")
+		fmt.Printf("  setp %%cl\n")
+		fmt.Printf("  orb %%cl, %%al\n")
+	case "<": // right > left
+		fmt.Printf("  ucomisd %%xmm0, %%xmm1\n")
+		fmt.Printf("  seta %%al\n")
+	case "<=":
+		fmt.Printf("  ucomisd %%xmm0, %%xmm1\n")
+		fmt.Printf("  setae %%al\n")
+	case ">":
+		fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+		fmt.Printf("  seta %%al\n")
+	case ">=":
+		fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+		fmt.Printf("  setae %%al\n")
+	default:
+		panic("invalid float operation " + e.Op.String())
+	}
+	switch e.Op.String() {
+	case "+", "-", "*", "/":
+		fmt.Printf("  movq %%xmm0, %%rax\n")
+		emitWrapAround(t)
+	default:
+		fmt.Printf("  movzbq %%al, %%rax\n") // true:1, false:0
+	}
+	fmt.Printf("  pushq %%rax\n")
+}
+
 // 1 value
 func emitCompositeLit(e *ast.CompositeLit, ctx *evalContext) {
 	// slice , array, map or struct
@@ -1078,100 +2622,134 @@ func emitCompositeLit(e *ast.CompositeLit, ctx *evalContext) {
 		fmt.Printf("  pushq $%d # slice.cap\n", length)
 		fmt.Printf("  pushq $%d # slice.len\n", length)
 		fmt.Printf("  pushq %%rax # slice.ptr\n")
+	case T_MAP:
+		emitMapLiteral(ut, e.Elts)
 	default:
 		unexpectedKind(kind(e2t(e.Type)))
 	}
 }
 // 1 value list[low:high]
+// closure layout:
+//   0: code address
+//   8: address of the 1st captured variable
+//   16: address of the 2nd captured variable
+//   ...
+func emitFuncLit(e *ast.FuncLit, ctx *evalContext) {
+	fnc := e.Func
+	emitCallMalloc(SizeOfPtr*(1+len(fnc.Captures)), false)
+	fmt.Printf("  popq %%rax # closure\n")
+	fmt.Printf("  leaq %s(%%rip), %%rcx # code\n", getPackageSymbol(currentPkg.name, fnc.Name))
+	fmt.Printf("  movq %%rcx, 0(%%rax)\n")
+	fmt.Printf("  pushq %%rax # closure\n")
+	for i, vr := range fnc.Captures {
+		emitVariableAddr(vr)
+		fmt.Printf("  popq %%rcx # address of captured variable\n")
+		fmt.Printf("  movq (%%rsp), %%rax # closure\n")
+		fmt.Printf("  movq %%rcx, %d(%%rax) # capture \"%s\"\n", SizeOfPtr*(i+1), vr.Name)
+	}
+}
+
+// a declared func as a value is a closure which captures nothing
+func emitFuncValue(symbol string) {
+	emitCallMalloc(SizeOfPtr, true) // only the code address
+	fmt.Printf("  popq %%rax # closure\n")
+	fmt.Printf("  leaq %s(%%rip), %%rcx # code\n", symbol)
+	fmt.Printf("  movq %%rcx, 0(%%rax)\n")
+	fmt.Printf("  pushq %%rax # closure\n")
+}
+
 func emitSliceExpr(e *ast.SliceExpr, ctx *evalContext) {
 	list := e.X
 	listType := getTypeOfExpr(list)
+	checked := isBoundsChecked(e.Lbrack)
+
+	// The operand and then the indices are evaluated once each.
+	// The operand is pushed as the head address, the bound of the high index and the length.
+	switch kind(listType) {
+	case T_SLICE:
+		emitExpr(list, nil)
+		emitPopSlice()
+		fmt.Printf("  pushq %%rax # slice.ptr\n")
+		fmt.Printf("  pushq %%rdx # slice.cap\n")
+		fmt.Printf("  pushq %%rcx # slice.len\n")
+	case T_ARRAY:
+		emitAddr(list) // array head
+		emitLen(list)
+		emitLen(list)
+	case T_STRING:
+		emitExpr(list, nil)
+		emitPopString()
+		fmt.Printf("  pushq %%rax # string.ptr\n")
+		fmt.Printf("  pushq %%rcx # string.len\n")
+		fmt.Printf("  pushq %%rcx # string.len\n")
+	default:
+		unexpectedKind(kind(listType))
+	}
 
 	// For convenience, any of the indices may be omitted.
-	// A missing low index defaults to zero;
-	var low ast.Expr
+	// A missing low index defaults to zero; a missing high index defaults to the length.
 	if e.Low != nil {
-		low = e.Low
+		emitExpr(e.Low, nil)
 	} else {
-		low = eZeroInt
+		fmt.Printf("  pushq $0 # low\n")
+	}
+	if e.High != nil {
+		emitExpr(e.High, nil)
+	}
+	if e.Max != nil {
+		emitExpr(e.Max, nil)
+		fmt.Printf("  popq %%rdx # max\n")
+	}
+	if e.High != nil {
+		fmt.Printf("  popq %%rcx # high\n")
+	}
+	fmt.Printf("  popq %%rax # low\n")
+	fmt.Printf("  popq %%rsi # len\n")
+	fmt.Printf("  popq %%rdi # cap\n")
+	if e.High == nil {
+		fmt.Printf("  movq %%rsi, %%rcx # high = len\n")
+	}
+	if e.Max == nil {
+		fmt.Printf("  movq %%rdi, %%rdx # max = cap\n")
 	}
 
-	switch kind(listType) {
-	case T_SLICE, T_ARRAY:
-		if e.Max == nil {
-			// new cap = cap(operand) - low
-			emitCap(e.X)
-			emitExpr(low, nil)
-			fmt.Printf("  popq %%rcx # low\n")
-			fmt.Printf("  popq %%rax # orig_cap\n")
-			fmt.Printf("  subq %%rcx, %%rax # orig_cap - low\n")
-			fmt.Printf("  pushq %%rax # new cap\n")
-
-			// new len = high - low
-			if e.High != nil {
-				emitExpr(e.High, nil)
-			} else {
-				// high = len(orig)
-				emitLen(e.X)
-			}
-			emitExpr(low, nil)
-			fmt.Printf("  popq %%rcx # low\n")
-			fmt.Printf("  popq %%rax # high\n")
-			fmt.Printf("  subq %%rcx, %%rax # high - low\n")
-			fmt.Printf("  pushq %%rax # new len\n")
+	// check the bounds from the right
+	if e.Max != nil {
+		if kind(listType) == T_SLICE {
+			emitBoundsCheck(boundsSlice3Acap, "%rdx", "%rdi", true, checked)
 		} else {
-			// new cap = max - low
-			emitExpr(e.Max, nil)
-			emitExpr(low, nil)
-			fmt.Printf("  popq %%rcx # low\n")
-			fmt.Printf("  popq %%rax # max\n")
-			fmt.Printf("  subq %%rcx, %%rax # new cap = max - low\n")
-			fmt.Printf("  pushq %%rax # new cap\n")
-			// new len = high - low
-			emitExpr(e.High, nil)
-			emitExpr(low, nil)
-			fmt.Printf("  popq %%rcx # low\n")
-			fmt.Printf("  popq %%rax # high\n")
-			fmt.Printf("  subq %%rcx, %%rax # new len = high - low\n")
-			fmt.Printf("  pushq %%rax # new len\n")
+			emitBoundsCheck(boundsSlice3Alen, "%rdx", "%rdi", true, checked)
 		}
-	case T_STRING:
-		// new len = high - low
+		emitBoundsCheck(boundsSlice3B, "%rcx", "%rdx", true, checked)
+		emitBoundsCheck(boundsSlice3C, "%rax", "%rcx", true, checked)
+	} else {
 		if e.High != nil {
-			emitExpr(e.High, nil)
-		} else {
-			// high = len(orig)
-			emitLen(e.X)
-		}
-		emitExpr(low, nil)
-		fmt.Printf("  popq %%rcx # low\n")
-		fmt.Printf("  popq %%rax # high\n")
-		fmt.Printf("  subq %%rcx, %%rax # high - low\n")
-		fmt.Printf("  pushq %%rax # len\n")
-		// no cap
-	default:
-		unexpectedKind(kind(listType))
+			if kind(listType) == T_SLICE {
+				emitBoundsCheck(boundsSliceAcap, "%rcx", "%rdi", true, checked)
+			} else {
+				emitBoundsCheck(boundsSliceAlen, "%rcx", "%rdi", true, checked)
+			}
+		}
+		emitBoundsCheck(boundsSliceB, "%rax", "%rcx", true, checked)
 	}
 
-	emitExpr(low, nil) // index number
+	fmt.Printf("  popq %%rsi # list head\n")
+	fmt.Printf("  subq %%rax, %%rdx # new cap = max - low\n")
+	fmt.Printf("  subq %%rax, %%rcx # new len = high - low\n")
 	elmType := getElementTypeOfListType(listType)
-	emitListElementAddr(list, elmType)
+	fmt.Printf("  imulq $%d, %%rax # low * elm size\n", getSizeOfType(elmType))
+	fmt.Printf("  addq %%rsi, %%rax # new ptr\n")
+	if kind(listType) != T_STRING {
+		fmt.Printf("  pushq %%rdx # new cap\n")
+	}
+	fmt.Printf("  pushq %%rcx # new len\n")
+	fmt.Printf("  pushq %%rax # new ptr\n")
 }
 // 1 or 2 values
 func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
-	emitExpr(e.X, nil)
-	fmt.Printf("  popq  %%rax # ifc.dtype\n")
-	fmt.Printf("  popq  %%rcx # ifc.data\n")
-	fmt.Printf("  pushq %%rax # ifc.data\n")
 	typ := e2t(e.Type)
-	sType := serializeType(typ)
-	tid := getTypeId(sType)
-	typeSymbol := typeIdToSymbol(tid)
-	// check if type matches
-	fmt.Printf("  leaq %s(%%rip), %%rax # ifc.dtype\n", typeSymbol)
-	fmt.Printf("  pushq %%rax           # ifc.dtype\n")
-
-	emitCompExpr("sete") // this pushes 1 or 0 in the end
+	emitExpr(e.X, nil) // evaluated once. The interface value stays on the stack
+	emitDynamicTypeCheck(typ)
 	emitPopBool("type assertion ok value")
 	fmt.Printf("  cmpq $1, %%rax\n")
 
@@ -1185,9 +2763,9 @@ func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
 		// ok context
 		emitComment(2, " double value context\n")
 		if ctx.okContext.needMain {
-			emitExpr(e.X, nil)
-			fmt.Printf("  popq %%rax # garbage\n")
-			emitLoadAndPush(e2t(e.Type)) // load dynamic data
+			emitTypeAssertedValue(typ)
+		} else {
+			emitRevertStackTop(getTypeOfExpr(e.X))
 		}
 		if ctx.okContext.needOk {
 			fmt.Printf("  pushq $1 # ok = true\n")
@@ -1195,9 +2773,7 @@ func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
 	} else {
 		// default context is single value context
 		emitComment(2, " single value context\n")
-		emitExpr(e.X, nil)
-		fmt.Printf("  popq %%rax # garbage\n")
-		emitLoadAndPush(e2t(e.Type)) // load dynamic data
+		emitTypeAssertedValue(typ)
 	}
 
 	// exit
@@ -1208,6 +2784,7 @@ func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
 	if ctx != nil && ctx.okContext != nil {
 		// ok context
 		emitComment(2, " double value context\n")
+		emitRevertStackTop(getTypeOfExpr(e.X))
 		if ctx.okContext.needMain {
 			emitZeroValue(typ)
 		}
@@ -1217,12 +2794,60 @@ func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
 	} else {
 		// default context is single value context
 		emitComment(2, " single value context\n")
-		emitZeroValue(typ)
+		emitPanicDotType(getTypeOfExpr(e.X), typ)
 	}
 
 	fmt.Printf("  %s:\n", labelTypeAssertionEnd)
 }
 
+// runtime.panicdottype(ifc.dtype, typ, srcType) with the interface value on the stack.
+// The static type of the operand is reported only if typ is a concrete type, as Go does.
+func emitPanicDotType(srcType *Type, typ *Type) {
+	fmt.Printf("  movq 0(%%rsp), %%rcx # ifc.dtype\n")
+	ff := lookupForeignFunc(newQI("runtime", "panicdottype"))
+	emitAllocReturnVarsAreaFF(ff)
+	if isInterface(typ) {
+		fmt.Printf("  pushq $0 # iface\n")
+	} else {
+		emitDtypeSymbol(srcType)
+	}
+	emitDtypeSymbol(typ)
+	fmt.Printf("  pushq %%rcx # ifc.dtype\n")
+	emitCallFF(ff)
+}
+
+// push true if the dynamic type of the interface value on the stack is typ,
+// or implements typ if it is an interface type. The interface value is left on the stack.
+func emitDynamicTypeCheck(typ *Type) {
+	fmt.Printf("  movq 0(%%rsp), %%rcx # ifc.dtype\n")
+	if isInterface(typ) {
+		ff := lookupForeignFunc(newQI("runtime", "implements"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitDtypeSymbol(typ)
+		fmt.Printf("  pushq %%rcx # ifc.dtype\n")
+		emitCallFF(ff)
+		return
+	}
+	fmt.Printf("  pushq %%rcx # ifc.dtype\n")
+	tid := getTypeId(typ)
+	typeSymbol := typeIdToSymbol(tid)
+	// check if type matches
+	fmt.Printf("  leaq %s(%%rip), %%rax # ifc.dtype\n", typeSymbol)
+	fmt.Printf("  pushq %%rax           # ifc.dtype\n")
+
+	emitCompExpr("sete") // this pushes 1 or 0 in the end
+}
+
+// replace the interface value on the stack with its value as typ after the dynamic type check
+func emitTypeAssertedValue(typ *Type) {
+	if isInterface(typ) {
+		// the interface value as it is
+		return
+	}
+	fmt.Printf("  popq %%rax # garbage\n")
+	emitLoadAndPush(typ) // load dynamic data
+}
+
 // targetType is the type of someone who receives the expr value.
 // There are various forms:
 //   Assignment:       x = expr
@@ -1234,6 +2859,25 @@ func emitTypeAssertExpr(e *ast.TypeAssertExpr, ctx *evalContext) {
 //   - the target type is interface and expr is not.
 func emitExpr(expr ast.Expr, ctx *evalContext) bool {
 	emitComment(2, "[emitExpr] dtype=%T\n", expr)
+	_, isLit := expr.(*ast.BasicLit)
+	if isConstExpr(expr) {
+		cv := evalConst(expr)
+		if ctx != nil && ctx._type != nil && cv.typ == nil && isNumericConstKind(cv.kind) {
+			if isFloat(ctx._type) || isSignedInteger(ctx._type) || isUnsignedInteger(ctx._type) {
+				// an untyped constant takes the type of the context
+				emitConstValue(convertConst(cv, ctx._type))
+				return false
+			}
+			if isInterface(ctx._type) {
+				// or its default type
+				convertConst(cv, getTypeOfExpr(expr))
+			}
+		}
+		if !isLit && cv.kind != T_STRING {
+			emitConstValue(cv)
+			return false
+		}
+	}
 	switch e := expr.(type) {
 	case *ast.Ident:
 		return emitIdent(e, ctx) // 1 value
@@ -1259,6 +2903,8 @@ func emitExpr(expr ast.Expr, ctx *evalContext) bool {
 		emitSliceExpr(e, ctx) // 1 value list[low:high]
 	case *ast.TypeAssertExpr:
 		emitTypeAssertExpr(e, ctx) // 1 or 2 values
+	case *ast.FuncLit:
+		emitFuncLit(e, ctx) // 1 value
 	default:
 		throw(expr)
 	}
@@ -1270,7 +2916,7 @@ func emitConvertToInterface(fromType *Type) {
 	emitComment(2, "ConversionToInterface\n")
 	memSize := getSizeOfType(fromType)
 	// copy data to heap
-	emitCallMalloc(memSize)
+	emitCallMalloc(memSize, !hasPointers(fromType))
 	emitStore(fromType, false, true) // heap addr pushed
 	// push type id
 	emitDtypeSymbol(fromType)
@@ -1278,8 +2924,11 @@ func emitConvertToInterface(fromType *Type) {
 
 func emitExprIfc(expr ast.Expr, ctx *evalContext) {
 	isNilObj := emitExpr(expr, ctx)
-	if !isNilObj && ctx != nil && ctx._type != nil && isInterface(ctx._type) && !isInterface(getTypeOfExpr(expr)) {
-		emitConvertToInterface(getTypeOfExpr(expr))
+	if !isNilObj && ctx != nil && ctx._type != nil && isInterface(ctx._type) {
+		checkImplements(getTypeOfExpr(expr), ctx._type)
+		if !isInterface(getTypeOfExpr(expr)) {
+			emitConvertToInterface(getTypeOfExpr(expr))
+		}
 	}
 }
 
@@ -1288,6 +2937,7 @@ var typeMap []*typeEntry
 type typeEntry struct {
 	serialized string
 	id         int
+	typ        *Type
 }
 
 var typeId int = 1
@@ -1296,7 +2946,8 @@ func typeIdToSymbol(id int) string {
 	return "dtype." + strconv.Itoa(id)
 }
 
-func getTypeId(serialized string) int {
+func getTypeId(t *Type) int {
+	serialized := serializeType(t)
 	for _, te := range typeMap {
 		if te.serialized == serialized {
 			return te.id
@@ -1306,6 +2957,7 @@ func getTypeId(serialized string) int {
 	te := &typeEntry{
 		serialized: serialized,
 		id:         typeId,
+		typ:        t,
 	}
 	typeMap = append(typeMap, te)
 	typeId++
@@ -1314,7 +2966,7 @@ func getTypeId(serialized string) int {
 
 func emitDtypeSymbol(t *Type) {
 	str := serializeType(t)
-	typeId := getTypeId(str)
+	typeId := getTypeId(t)
 	typeSymbol := typeIdToSymbol(typeId)
 	fmt.Printf("  leaq %s(%%rip), %%rax # type symbol \"%s\"\n", typeSymbol, str)
 	fmt.Printf("  pushq %%rax           # type symbol\n")
@@ -1328,16 +2980,86 @@ func newNumberLiteral(x int) *ast.BasicLit {
 	return e
 }
 
-func emitListElementAddr(list ast.Expr, elmType *Type) {
-	emitListHeadAddr(list)
+func newBoolLiteral(x bool) *ast.Ident {
+	if x {
+		return &ast.Ident{
+			Name: "true",
+			Obj:  gTrue,
+		}
+	}
+	return &ast.Ident{
+		Name: "false",
+		Obj:  gFalse,
+	}
+}
+
+// The index is on the stack top. It is not checked if lbrack is token.NoPos.
+func emitListElementAddr(list ast.Expr, elmType *Type, lbrack token.Pos) {
+	emitListHeadAddrAndLen(list)
+	fmt.Printf("  popq %%rdx # len\n")
 	emitPopAddress("list head")
+	listType := getTypeOfExpr(list)
+	if kind(listType) == T_ARRAY && getSizeOfType(listType) >= minLegalPointer {
+		emitNilCheck()
+	}
 	fmt.Printf("  popq %%rcx # index id\n")
+	emitBoundsCheck(boundsIndex, "%rcx", "%rdx", false, isBoundsChecked(lbrack))
 	fmt.Printf("  movq $%d, %%rdx # elm size\n", getSizeOfType(elmType))
 	fmt.Printf("  imulq %%rdx, %%rcx\n")
 	fmt.Printf("  addq %%rcx, %%rax\n")
 	fmt.Printf("  pushq %%rax # addr of element\n")
 }
 
+// Addresses below minLegalPointer fault, and the runtime reports the faults as nil pointer dereferences.
+// An offset of minLegalPointer or more from a nil pointer could reach mapped memory or fault elsewhere.
+const minLegalPointer int = 4096
+
+// load from the address in %rax, which faults as a nil dereference if it is below minLegalPointer,
+// before an offset which may be larger is added to it
+func emitNilCheck() {
+	fmt.Printf("  testb %%al, (%%rax) # nil check\n")
+}
+
+var disableBoundsCheck bool
+
+// the kinds of bounds checks. (runtime.panicBounds depends on the values)
+const boundsIndex int = 0      // s[x]: 0 <= x < len(s)
+const boundsSliceAlen int = 1  // s[:x]: 0 <= x <= len(s)
+const boundsSliceAcap int = 2  // s[:x]: 0 <= x <= cap(s)
+const boundsSliceB int = 3     // s[x:y]: 0 <= x <= y
+const boundsSlice3Alen int = 4 // s[::x]: 0 <= x <= len(s)
+const boundsSlice3Acap int = 5 // s[::x]: 0 <= x <= cap(s)
+const boundsSlice3B int = 6    // s[:x:y]: 0 <= x <= y
+const boundsSlice3C int = 7    // s[x:y:]: 0 <= x <= y
+
+// whether an index or slice expression at lbrack is checked
+func isBoundsChecked(lbrack token.Pos) bool {
+	return !disableBoundsCheck && lbrack != token.NoPos
+}
+
+// Panic unless x < y, or x <= y if orEqual, as unsigned integers.
+// A negative x looks like a large unsigned integer.
+func emitBoundsCheck(code int, x string, y string, orEqual bool, checked bool) {
+	if !checked {
+		return
+	}
+	labelid++
+	labelInBounds := fmt.Sprintf(".L.%d.inbounds", labelid)
+	fmt.Printf("  cmpq %s, %s\n", y, x)
+	if orEqual {
+		fmt.Printf("  jbe %s\n", labelInBounds)
+	} else {
+		fmt.Printf("  jb %s\n", labelInBounds)
+	}
+	ff := lookupForeignFunc(newQI("runtime", "panicBounds"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq %s # y\n", y)
+	fmt.Printf("  pushq %s # x\n", x)
+	fmt.Printf("  pushq $%d # code\n", code)
+	emitCallFF(ff)
+	fmt.Printf("  %s:\n", labelInBounds)
+}
+
 func emitCatStrings(left ast.Expr, right ast.Expr) {
 	args := []*Arg{
 		&Arg{
@@ -1382,49 +3104,275 @@ func emitCompStrings(left ast.Expr, right ast.Expr) {
 	emitCall("runtime.cmpstrings", args, resultList)
 }
 
-func emitBinaryExprComparison(left ast.Expr, right ast.Expr) {
-	if kind(getTypeOfExpr(left)) == T_STRING {
-		emitCompStrings(left, right)
-	} else if kind(getTypeOfExpr(left)) == T_INTERFACE {
-		var t = getTypeOfExpr(left)
-		ff := lookupForeignFunc(newQI("runtime", "cmpinterface"))
-		emitAllocReturnVarsAreaFF(ff)
-		emitExpr(left, nil) // left
-		ctx := &evalContext{_type: t}
-		emitExprIfc(right, ctx) // right
-		emitCallFF(ff)
+// min(x, y, ...) and max(x, y, ...) evaluate the arguments from left to right
+// and keep the current result on the stack.
+func emitMinMax(isMin bool, args []ast.Expr) {
+	t := getMinMaxType(args)
+	if kind(t) == T_STRING {
+		name := "maxstring"
+		if isMin {
+			name = "minstring"
+		}
+		emitMinMaxStrings(lookupForeignFunc(newQI("runtime", name)), args)
+		return
+	}
+	ctx := &evalContext{_type: t}
+	emitExpr(args[0], ctx)
+	for _, arg := range args[1:] {
+		emitExpr(arg, ctx)
+		fmt.Printf("  popq %%rcx # right\n")
+		fmt.Printf("  popq %%rax # left\n")
+		if isFloat(t) {
+			emitMinMaxFloat(isMin)
+		} else {
+			// take the right one if it is smaller (or larger)
+			fmt.Printf("  cmpq %%rcx, %%rax\n")
+			var cmov string
+			if isUnsignedInteger(t) {
+				cmov = "cmovbq"
+				if isMin {
+					cmov = "cmovaq"
+				}
+			} else {
+				cmov = "cmovlq"
+				if isMin {
+					cmov = "cmovgq"
+				}
+			}
+			fmt.Printf("  %s %%rcx, %%rax\n", cmov)
+		}
+		fmt.Printf("  pushq %%rax\n")
+	}
+}
+
+// A NaN makes the result NaN, and -0 is smaller than +0.
+func emitMinMaxFloat(isMin bool) {
+	labelid++
+	labelRight := fmt.Sprintf(".L.%d.right", labelid)
+	labelEqual := fmt.Sprintf(".L.%d.equal", labelid)
+	labelNaN := fmt.Sprintf(".L.%d.nan", labelid)
+	labelExit := fmt.Sprintf(".L.%d.exit", labelid)
+	fmt.Printf("  movq %%rax, %%xmm0\n")
+	fmt.Printf("  movq %%rcx, %%xmm1\n")
+	fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+	fmt.Printf("  jp %s\n", labelNaN)
+	fmt.Printf("  je %s\n", labelEqual)
+	if isMin {
+		fmt.Printf("  ja %s\n", labelRight)
 	} else {
-		var t = getTypeOfExpr(left)
-		emitExpr(left, nil) // left
-		ctx := &evalContext{_type: t}
-		emitExprIfc(right, ctx) // right
-		emitCompExpr("sete")
+		fmt.Printf("  jb %s\n", labelRight)
+	}
+	fmt.Printf("  jmp %s\n", labelExit)
+	fmt.Printf("  %s:\n", labelRight)
+	fmt.Printf("  movq %%rcx, %%rax\n")
+	fmt.Printf("  jmp %s\n", labelExit)
+	fmt.Printf("  %s:\n", labelEqual)
+	// only the sign bits of zeros can differ
+	if isMin {
+		fmt.Printf("  orq %%rcx, %%rax\n")
+	} else {
+		fmt.Printf("  andq %%rcx, %%rax\n")
 	}
+	fmt.Printf("  jmp %s\n", labelExit)
+	fmt.Printf("  %s:\n", labelNaN)
+	fmt.Printf("  addsd %%xmm1, %%xmm0\n")
+	fmt.Printf("  movq %%xmm0, %%rax\n")
+	fmt.Printf("  %s:\n", labelExit)
 }
 
-//@TODO handle larger types than int
-func emitCompExpr(inst string) {
-	fmt.Printf("  popq %%rcx # right\n")
-	fmt.Printf("  popq %%rax # left\n")
-	fmt.Printf("  cmpq %%rcx, %%rax\n")
-	fmt.Printf("  %s %%al\n", inst)
-	fmt.Printf("  movzbq %%al, %%rax\n") // true:1, false:0
-	fmt.Printf("  pushq %%rax\n")
+// min(a, b, c) is minstring(c, minstring(b, a))
+func emitMinMaxStrings(ff *ForeignFunc, args []ast.Expr) {
+	if len(args) == 1 {
+		emitExpr(args[0], nil)
+		return
+	}
+	emitAllocReturnVarsAreaFF(ff)
+	emitMinMaxStrings(ff, args[:len(args)-1])
+	emitExpr(args[len(args)-1], nil)
+	emitCallFF(ff)
 }
 
-func emitPop(knd TypeKind) {
-	switch knd {
-	case T_SLICE:
-		emitPopSlice()
-	case T_STRING:
-		emitPopString()
-	case T_INTERFACE:
-		emitPopInterFace()
-	case T_INT, T_BOOL, T_UINTPTR, T_POINTER:
-		emitPopPrimitive(string(knd))
-	case T_UINT16:
+// Go truncates the quotient toward zero, which is what idivq does.
+// Dividing the most negative integer by -1 traps on x86, so it is done by negation.
+func emitDivExpr(e *ast.BinaryExpr) {
+	labelid++
+	labelDiv := fmt.Sprintf(".L.%d.div", labelid)
+	labelExit := fmt.Sprintf(".L.%d.exit", labelid)
+	isRem := e.Op.String() == "%"
+	emitExpr(e.X, nil) // left
+	emitExpr(e.Y, nil) // right
+	fmt.Printf("  popq %%rcx # right\n")
+	fmt.Printf("  popq %%rax # left\n")
+	fmt.Printf("  cmpq $0, %%rcx\n")
+	fmt.Printf("  jne %s\n", labelDiv)
+	ff := lookupForeignFunc(newQI("runtime", "panicdivide"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitCallFF(ff)
+	fmt.Printf("  %s:\n", labelDiv)
+	if isSignedInteger(getTypeOfExpr(e)) {
+		labelIdiv := fmt.Sprintf(".L.%d.idiv", labelid)
+		fmt.Printf("  cmpq $-1, %%rcx\n")
+		fmt.Printf("  jne %s\n", labelIdiv)
+		if isRem {
+			fmt.Printf("  movq $0, %%rax # x %% -1 is 0\n")
+		} else {
+			fmt.Printf("  negq %%rax # x / -1 is -x\n")
+		}
+		fmt.Printf("  jmp %s\n", labelExit)
+		fmt.Printf("  %s:\n", labelIdiv)
+		fmt.Printf("  cqto # sign extend %%rax into %%rdx\n")
+		fmt.Printf("  idivq %%rcx\n")
+	} else {
+		fmt.Printf("  movq $0, %%rdx # init %%rdx\n")
+		fmt.Printf("  divq %%rcx\n")
+	}
+	if isRem {
+		fmt.Printf("  movq %%rdx, %%rax\n")
+	}
+	fmt.Printf("  %s:\n", labelExit)
+	emitWrapAround(getTypeOfExpr(e))
+	fmt.Printf("  pushq %%rax\n")
+}
+
+// x86 uses only the low 6 bits of a shift count, while in Go
+// shifting by the width or more leaves nothing but the sign bits.
+func emitShiftExpr(e *ast.BinaryExpr) {
+	labelid++
+	labelShift := fmt.Sprintf(".L.%d.shift", labelid)
+	labelExit := fmt.Sprintf(".L.%d.exit", labelid)
+	isSigned := isSignedInteger(getTypeOfExpr(e.X))
+	emitExpr(e.X, nil) // left
+	emitExpr(e.Y, nil) // right
+	if isSignedInteger(getTypeOfExpr(e.Y)) {
+		labelCountOk := fmt.Sprintf(".L.%d.count", labelid)
+		fmt.Printf("  cmpq $0, (%%rsp) # shift count\n")
+		fmt.Printf("  jge %s\n", labelCountOk)
+		ff := lookupForeignFunc(newQI("runtime", "panicshift"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitCallFF(ff)
+		fmt.Printf("  %s:\n", labelCountOk)
+	}
+	fmt.Printf("  popq %%rcx # shift count\n")
+	fmt.Printf("  popq %%rax # left\n")
+	fmt.Printf("  cmpq $64, %%rcx\n")
+	fmt.Printf("  jb %s\n", labelShift)
+	if e.Op.String() == ">>" && isSigned {
+		fmt.Printf("  movq $63, %%rcx # fill with the sign bit\n")
+	} else {
+		fmt.Printf("  movq $0, %%rax # all bits are shifted out\n")
+		fmt.Printf("  jmp %s\n", labelExit)
+	}
+	fmt.Printf("  %s:\n", labelShift)
+	if e.Op.String() == "<<" {
+		fmt.Printf("  shlq %%cl, %%rax\n")
+		emitWrapAround(getTypeOfExpr(e.X))
+	} else if isSigned {
+		fmt.Printf("  sarq %%cl, %%rax\n")
+	} else {
+		fmt.Printf("  shrq %%cl, %%rax\n")
+	}
+	fmt.Printf("  %s:\n", labelExit)
+	fmt.Printf("  pushq %%rax\n")
+}
+
+func emitBinaryExprComparison(left ast.Expr, right ast.Expr) {
+	if isNilIdent(left) {
+		// nil == x
+		var x = right
+		right = left
+		left = x
+	}
+	var t = getTypeOfExpr(left)
+	if isNilIdent(right) {
+		emitCompNil(left, t)
+		return
+	}
+	if !isInterface(t) && isInterface(getTypeOfExpr(right)) {
+		// x == ifc
+		if !isComparable(t) {
+			panic("invalid operation: " + serializeType(t) + " cannot be compared")
+		}
+		t = getTypeOfExpr(right)
+	}
+	switch kind(t) {
+	case T_STRING:
+		emitCompStrings(left, right)
+	case T_INTERFACE:
+		if !isInterface(getTypeOfExpr(right)) && !isComparable(getTypeOfExpr(right)) {
+			panic("invalid operation: " + serializeType(getTypeOfExpr(right)) + " cannot be compared")
+		}
+		ff := lookupForeignFunc(newQI("runtime", "cmpinterface"))
+		emitAllocReturnVarsAreaFF(ff)
+		ctx := &evalContext{_type: t}
+		emitExprIfc(left, ctx)  // left
+		emitExprIfc(right, ctx) // right
+		emitCallFF(ff)
+	case T_STRUCT, T_ARRAY:
+		if !isComparable(t) {
+			panic("invalid operation: " + serializeType(t) + " cannot be compared")
+		}
+		emitAllocReturnVarsArea(SizeOfInt) // bool
+		emitExpr(left, nil)                // left
+		emitExpr(right, nil)               // right
+		fmt.Printf("  callq %s\n", getEqualFuncSymbol(t))
+		emitFreeParametersArea(SizeOfPtr * 2)
+	case T_SLICE, T_MAP, T_FUNC:
+		panic("invalid operation: " + serializeType(t) + " can only be compared to nil")
+	default:
+		emitExpr(left, nil) // left
+		ctx := &evalContext{_type: t}
+		emitExprIfc(right, ctx) // right
+		emitCompExpr("sete")
+	}
+}
+
+func isNilIdent(e ast.Expr) bool {
+	ident, isIdent := e.(*ast.Ident)
+	return isIdent && ident.Obj == gNil
+}
+
+// x == nil compares only the first word of x,
+// which is the pointer of a slice or the dynamic type of an interface.
+func emitCompNil(x ast.Expr, t *Type) {
+	emitExpr(x, nil)
+	switch kind(t) {
+	case T_SLICE:
+		emitPopSlice()
+		fmt.Printf("  pushq %%rax # slice.ptr\n")
+	case T_INTERFACE:
+		emitPopInterFace()
+		fmt.Printf("  pushq %%rax # ifc.dtype\n")
+	case T_POINTER, T_UINTPTR, T_MAP, T_CHAN, T_FUNC:
+	default:
+		panic("invalid operation: mismatched types " + serializeType(t) + " and untyped nil")
+	}
+	fmt.Printf("  pushq $0 # nil\n")
+	emitCompExpr("sete")
+}
+
+//@TODO handle larger types than int
+func emitCompExpr(inst string) {
+	fmt.Printf("  popq %%rcx # right\n")
+	fmt.Printf("  popq %%rax # left\n")
+	fmt.Printf("  cmpq %%rcx, %%rax\n")
+	fmt.Printf("  %s %%al\n", inst)
+	fmt.Printf("  movzbq %%al, %%rax\n") // true:1, false:0
+	fmt.Printf("  pushq %%rax\n")
+}
+
+func emitPop(knd TypeKind) {
+	switch knd {
+	case T_SLICE:
+		emitPopSlice()
+	case T_STRING:
+		emitPopString()
+	case T_INTERFACE:
+		emitPopInterFace()
+	case T_INT, T_BOOL, T_UINTPTR, T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		emitPopPrimitive(string(knd))
-	case T_UINT8:
+	case T_INT8, T_INT16, T_INT32, T_INT64, T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64:
+		emitPopPrimitive(string(knd))
+	case T_FLOAT32, T_FLOAT64:
 		emitPopPrimitive(string(knd))
 	case T_STRUCT, T_ARRAY:
 		emitPopPrimitive(string(knd))
@@ -1465,11 +3413,17 @@ func emitRegiToMem(t *Type) {
 	case T_INTERFACE:
 		fmt.Printf("  movq %%rax, %d(%%rsi) # store dtype\n", 0)
 		fmt.Printf("  movq %%rcx, %d(%%rsi) # store data\n", 8)
-	case T_INT, T_BOOL, T_UINTPTR, T_POINTER:
+	case T_INT, T_INT64, T_UINT, T_UINT64, T_FLOAT64, T_BOOL, T_UINTPTR, T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		fmt.Printf("  movq %%rax, %d(%%rsi) # assign\n", 0)
-	case T_UINT16:
+	case T_FLOAT32:
+		fmt.Printf("  movq %%rax, %%xmm0\n")
+		fmt.Printf("  cvtsd2ss %%xmm0, %%xmm0\n")
+		fmt.Printf("  movss %%xmm0, %d(%%rsi) # assign float32\n", 0)
+	case T_INT32, T_UINT32:
+		fmt.Printf("  movl %%eax, %d(%%rsi) # assign long\n", 0)
+	case T_INT16, T_UINT16:
 		fmt.Printf("  movw %%ax, %d(%%rsi) # assign word\n", 0)
-	case T_UINT8:
+	case T_INT8, T_UINT8:
 		fmt.Printf("  movb %%al, %d(%%rsi) # assign byte\n", 0)
 	case T_STRUCT, T_ARRAY:
 		fmt.Printf("  pushq $%d # size\n", getSizeOfType(t))
@@ -1507,12 +3461,12 @@ func emitAssignWithOK(lhss []ast.Expr, rhs ast.Expr) {
 	emitExprIfc(rhs, ctx) // {push data}, {push bool}
 	if needOK {
 		emitComment(2, "Assignment: ok variable\n")
-		emitAddr(lhsOK)
+		emitAddrForStore(lhsOK)
 		emitStore(getTypeOfExpr(lhsOK), false, false)
 	}
 
 	if needMain {
-		emitAddr(lhsMain)
+		emitAddrForStore(lhsMain)
 		emitComment(2, "Assignment: emitStore(getTypeOfExpr(lhs))\n")
 		emitStore(getTypeOfExpr(lhsMain), false, false)
 	}
@@ -1531,6 +3485,18 @@ func emitAssignToVar(vr *Variable, rhs ast.Expr) {
 }
 
 func emitAssign(lhs ast.Expr, rhs ast.Expr) {
+	if isMapIndexExpr(lhs) {
+		// m[k] = v
+		// rhs is evaluated before the entry is created
+		emitComment(2, "Assignment: emitExpr(rhs)\n")
+		ctx := &evalContext{
+			_type: getTypeOfExpr(lhs),
+		}
+		emitExprIfc(rhs, ctx)
+		emitAddrForMapSet(lhs.(*ast.IndexExpr))
+		emitStore(getTypeOfExpr(lhs), false, false)
+		return
+	}
 	emitComment(2, "Assignment: emitAddr(lhs)\n")
 	emitAddr(lhs)
 	emitComment(2, "Assignment: emitExpr(rhs)\n")
@@ -1547,40 +3513,96 @@ func emitBlockStmt(s *ast.BlockStmt) {
 	}
 }
 func emitExprStmt(s *ast.ExprStmt) {
+	if isChanRecvExpr(s.X) {
+		// <-ch discards the received value
+		ctx := &evalContext{
+			okContext: &okContext{},
+		}
+		emitExpr(s.X, ctx)
+		return
+	}
 	emitExpr(s.X, nil)
 }
+
+func emitSendStmt(s *ast.SendStmt) {
+	emitChanSend(s.Chan, s.Value)
+}
 func emitDeclStmt(s *ast.DeclStmt) {
 	genDecl := s.Decl.(*ast.GenDecl)
-	declSpec := genDecl.Spec
-	switch spec := declSpec.(type) {
-	case *ast.ValueSpec:
-		valSpec := spec
-		t := e2t(valSpec.Type)
-		lhs := valSpec.Name
+	if genDecl.Tok != token.VAR {
+		// constants are evaluated where they are used
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		valSpec := spec.(*ast.ValueSpec)
+		for _, lhs := range valSpec.Names {
+			emitNewBox(lhs.Obj.Variable)
+		}
 		if len(valSpec.Values) == 0 {
-			emitComment(2, "lhs addresss\n")
-			emitAddr(lhs)
-			emitComment(2, "emitZeroValue\n")
-			emitZeroValue(t)
-			emitComment(2, "Assignment: zero value\n")
-			emitStore(t, true, false)
-		} else if len(valSpec.Values) == 1 {
+			for _, lhs := range valSpec.Names {
+				t := getTypeOfExpr(lhs)
+				emitComment(2, "lhs addresss\n")
+				emitAddr(lhs)
+				emitComment(2, "emitZeroValue\n")
+				emitZeroValue(t)
+				emitComment(2, "Assignment: zero value\n")
+				emitStore(t, true, false)
+			}
+		} else if len(valSpec.Values) == len(valSpec.Names) {
 			// assignment
-			rhs := valSpec.Values[0]
-			emitAssign(lhs, rhs)
+			for i, lhs := range valSpec.Names {
+				emitAssign(lhs, valSpec.Values[i])
+			}
+		} else {
+			// var a, b = f()
+			var lhss []ast.Expr
+			for _, lhs := range valSpec.Names {
+				lhss = append(lhss, lhs)
+			}
+			emitAssignMultiValues(lhss, valSpec.Values[0])
+		}
+	}
+}
+// a, b, c = f()
+func emitAssignMultiValues(lhss []ast.Expr, rhs0 ast.Expr) {
+	emitExpr(rhs0, nil) // @TODO interface conversion
+	callExpr := rhs0.(*ast.CallExpr)
+	returnTypes := getCallResultTypes(callExpr)
+	fmt.Printf("# len lhs=%d\n", len(lhss))
+	fmt.Printf("# returnTypes=%d\n", len(returnTypes))
+	assert(len(returnTypes) == len(lhss), fmt.Sprintf("length unmatches %d <=> %d", len(lhss), len(returnTypes)), __func__)
+	length := len(returnTypes)
+	for i := 0; i < length; i++ {
+		lhs := lhss[i]
+		rhsType := returnTypes[i]
+		if isBlankIdentifier(lhs) {
+			emitPop(kind(rhsType))
 		} else {
-			panic("TBI")
+			switch kind(rhsType) {
+			case T_INT8, T_INT16, T_INT32, T_UINT8, T_UINT16, T_UINT32, T_FLOAT32:
+				// repush stack top
+				emitRepushNarrowValue(rhsType)
+			}
+			emitAddrForStore(lhs)
+			emitStore(getTypeOfExpr(lhs), false, false)
 		}
-	default:
-		throw(declSpec)
 	}
 }
+
 func emitAssignStmt(s *ast.AssignStmt) {
 	switch s.Tok.String() {
 	case "=", ":=":
+		if s.Tok.String() == ":=" {
+			// every execution of a short var decl makes new variables
+			for _, lhs := range s.Lhs {
+				if !isBlankIdentifier(lhs) {
+					emitNewBox(lhs.(*ast.Ident).Obj.Variable)
+				}
+			}
+		}
 		rhs0 := s.Rhs[0]
 		_, isTypeAssertion := rhs0.(*ast.TypeAssertExpr)
-		if len(s.Lhs) == 2 && isTypeAssertion {
+		if len(s.Lhs) == 2 && (isTypeAssertion || isMapIndexExpr(rhs0) || isChanRecvExpr(rhs0)) {
 			emitAssignWithOK(s.Lhs, rhs0)
 		} else {
 			if len(s.Lhs) == 1 && len(s.Rhs) == 1 {
@@ -1595,44 +3617,13 @@ func emitAssignStmt(s *ast.AssignStmt) {
 			} else if len(s.Lhs) >= 1 && len(s.Rhs) == 1 {
 				// multi-values expr
 				// a, b, c = f()
-				emitExpr(rhs0, nil) // @TODO interface conversion
-				callExpr := rhs0.(*ast.CallExpr)
-				returnTypes := getCallResultTypes(callExpr)
-				fmt.Printf("# len lhs=%d\n", len(s.Lhs))
-				fmt.Printf("# returnTypes=%d\n", len(returnTypes))
-				assert(len(returnTypes) == len(s.Lhs), fmt.Sprintf("length unmatches %d <=> %d", len(s.Lhs), len(returnTypes)), __func__)
-				length := len(returnTypes)
-				for i := 0; i < length; i++ {
-					lhs := s.Lhs[i]
-					rhsType := returnTypes[i]
-					if isBlankIdentifier(lhs) {
-						emitPop(kind(rhsType))
-					} else {
-						switch kind(rhsType) {
-						case T_UINT8:
-							// repush stack top
-							fmt.Printf("  movzbq (%%rsp), %%rax # load uint8\n")
-							fmt.Printf("  addq $%d, %%rsp # free returnvars area\n", 1)
-							fmt.Printf("  pushq %%rax\n")
-						}
-						emitAddr(lhs)
-						emitStore(getTypeOfExpr(lhs), false, false)
-					}
-				}
-
+				emitAssignMultiValues(s.Lhs, rhs0)
 			}
 		}
-	case "+=":
-		binaryExpr := &ast.BinaryExpr{
-			X:     s.Lhs[0],
-			Op:    token.ADD,
-			Y:     s.Rhs[0],
-		}
-		emitAssign(s.Lhs[0], binaryExpr)
-	case "-=":
+	case "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<=", ">>=", "&^=":
 		binaryExpr := &ast.BinaryExpr{
 			X:     s.Lhs[0],
-			Op:    token.SUB,
+			Op:    getCompoundAssignOp(s.Tok),
 			Y:     s.Rhs[0],
 		}
 		emitAssign(s.Lhs[0], binaryExpr)
@@ -1640,6 +3631,37 @@ func emitAssignStmt(s *ast.AssignStmt) {
 		panic("TBI: assignment of " + s.Tok.String())
 	}
 }
+
+// x op= y is x = x op y
+func getCompoundAssignOp(tok token.Token) token.Token {
+	switch tok.String() {
+	case "+=":
+		return token.ADD
+	case "-=":
+		return token.SUB
+	case "*=":
+		return token.MUL
+	case "/=":
+		return token.QUO
+	case "%=":
+		return token.REM
+	case "&=":
+		return token.AND
+	case "|=":
+		return token.OR
+	case "^=":
+		return token.XOR
+	case "<<=":
+		return token.SHL
+	case ">>=":
+		return token.SHR
+	case "&^=":
+		return token.AND_NOT
+	}
+	panic("unexpected assignment token " + tok.String())
+	return tok
+}
+
 func emitIfStmt(s *ast.IfStmt) {
 	emitComment(2, "if\n")
 
@@ -1694,7 +3716,7 @@ func emitForStmt(s *ast.ForStmt) {
 	fmt.Printf("  %s:\n", labelExit)
 }
 
-// only for array and slice for now
+// array, slice, string and map
 func emitRangeStmt(s *ast.RangeStmt) {
 	meta := s
 	labelid++
@@ -1704,6 +3726,18 @@ func emitRangeStmt(s *ast.RangeStmt) {
 
 	meta.LabelPost = labelPost
 	meta.LabelExit = labelExit
+	if kind(getTypeOfExpr(s.X)) == T_MAP {
+		emitRangeMap(s, labelCond, labelPost, labelExit)
+		return
+	}
+	if kind(getTypeOfExpr(s.X)) == T_CHAN {
+		emitRangeChan(s, labelCond, labelPost, labelExit)
+		return
+	}
+	if kind(getTypeOfExpr(s.X)) == T_STRING {
+		emitRangeString(s, labelCond, labelPost, labelExit)
+		return
+	}
 	// initialization: store len(rangeexpr)
 	emitComment(2, "ForRange Initialization\n")
 	rangeMeta := s
@@ -1746,16 +3780,18 @@ func emitRangeStmt(s *ast.RangeStmt) {
 	fmt.Printf("  cmpq $1, %%rax\n")
 	fmt.Printf("  jne %s # jmp if false\n", labelExit)
 
-	emitComment(2, "assign list[indexvar] value variables\n")
-	elemType := getTypeOfExpr(s.Value)
-	emitAddr(s.Value) // lhs
+	if s.Value != nil {
+		emitComment(2, "assign list[indexvar] value variables\n")
+		elemType := getTypeOfExpr(s.Value)
+		emitAddr(s.Value) // lhs
 
-	emitVariableAddr(s.Indexvar)
-	emitLoadAndPush(tInt) // index value
-	emitListElementAddr(s.X, elemType)
+		emitVariableAddr(s.Indexvar)
+		emitLoadAndPush(tInt) // index value
+		emitListElementAddr(s.X, elemType, token.NoPos)
 
-	emitLoadAndPush(elemType)
-	emitStore(elemType, true, false)
+		emitLoadAndPush(elemType)
+		emitStore(elemType, true, false)
+	}
 
 	// Body
 	emitComment(2, "ForRange Body\n")
@@ -1785,6 +3821,174 @@ func emitRangeStmt(s *ast.RangeStmt) {
 
 	fmt.Printf("  %s:\n", labelExit)
 }
+
+// for i, r := range s
+// i is the index of the first byte of each UTF-8 encoded code point r.
+func emitRangeString(s *ast.RangeStmt, labelCond string, labelPost string, labelExit string) {
+	// initialization: lenvar = len(s.X), indexvar = 0
+	emitComment(2, "ForRange Initialization\n")
+	emitVariableAddr(s.Lenvar)
+	emitLen(s.X)
+	emitStore(tInt, true, false)
+	emitVariableAddr(s.Indexvar)
+	emitZeroValue(tInt)
+	emitStore(tInt, true, false)
+
+	// Condition
+	emitComment(2, "ForRange Condition\n")
+	fmt.Printf("  %s:\n", labelCond)
+	emitVariableAddr(s.Indexvar)
+	emitLoadAndPush(tInt)
+	emitVariableAddr(s.Lenvar)
+	emitLoadAndPush(tInt)
+	emitCompExpr("setl")
+	emitPopBool(" indexvar < lenvar")
+	fmt.Printf("  cmpq $1, %%rax\n")
+	fmt.Printf("  jne %s # jmp if false\n", labelExit)
+
+	if s.Key != nil && !isBlankIdentifier(s.Key) {
+		emitAddr(s.Key) // lhs
+		emitVariableAddr(s.Indexvar)
+		emitLoadAndPush(tInt)
+		emitStore(tInt, true, false)
+	}
+
+	// value, indexvar = runtime.decoderune(s.X, indexvar)
+	ff := lookupForeignFunc(newQI("runtime", "decoderune"))
+	emitAllocReturnVarsAreaFF(ff)
+	// the first parameter is on the top of the stack
+	emitVariableAddr(s.Indexvar)
+	emitLoadAndPush(tInt)
+	emitExpr(s.X, nil)
+	emitCallFF(ff)
+	if s.Value != nil && !isBlankIdentifier(s.Value) {
+		emitAddr(s.Value) // lhs
+		emitStore(getTypeOfExpr(s.Value), false, false)
+	} else {
+		fmt.Printf("  popq %%rax # discard the rune\n")
+	}
+	emitVariableAddr(s.Indexvar) // lhs
+	emitStore(tInt, false, false)
+
+	// Body
+	emitComment(2, "ForRange Body\n")
+	emitStmt(s.Body)
+
+	fmt.Printf("  %s:\n", labelPost) // used for "continue"
+	fmt.Printf("  jmp %s\n", labelCond)
+
+	fmt.Printf("  %s:\n", labelExit)
+}
+
+// for k, v := range m
+func emitRangeChan(s *ast.RangeStmt, labelCond string, labelPost string, labelExit string) {
+	elemType := getElementTypeOfListType(getTypeOfExpr(s.X))
+
+	// initialization: itervar = ch
+	emitComment(2, "ForRange Initialization\n")
+	emitVariableAddr(s.Itervar)
+	emitExpr(s.X, nil)
+	emitStore(tUintptr, true, false)
+
+	// Condition
+	// if a value is received from itervar then
+	//   execute body
+	// else
+	//   exit
+	emitComment(2, "ForRange Condition\n")
+	fmt.Printf("  %s:\n", labelCond)
+	ff := lookupForeignFunc(newQI("runtime", "chanrecv"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitVariableAddr(s.Itervar)
+	emitLoadAndPush(tUintptr)
+	emitCallFF(ff)
+	fmt.Printf("  popq %%rax # addr of value\n")
+	fmt.Printf("  popq %%rcx # ok\n")
+	fmt.Printf("  cmpq $1, %%rcx\n")
+	fmt.Printf("  jne %s # exit if the channel is closed\n", labelExit)
+
+	if s.Key != nil && !isBlankIdentifier(s.Key) {
+		emitComment(2, "assign received value to key variable\n")
+		fmt.Printf("  pushq %%rax # addr of value\n")
+		emitLoadAndPush(elemType)
+		emitAddr(s.Key) // lhs
+		emitStore(elemType, false, false)
+	}
+
+	// Body
+	emitComment(2, "ForRange Body\n")
+	emitStmt(s.Body)
+
+	fmt.Printf("  %s:\n", labelPost) // used for "continue"
+	fmt.Printf("  jmp %s\n", labelCond)
+
+	fmt.Printf("  %s:\n", labelExit)
+}
+
+func emitRangeMap(s *ast.RangeStmt, labelCond string, labelPost string, labelExit string) {
+	mapType := getTypeOfExpr(s.X)
+	keyType := getKeyTypeOfMapType(mapType)
+	valueType := getElementTypeOfListType(mapType)
+
+	// initialization: itervar = mapIterInit(m)
+	emitComment(2, "ForRange Initialization\n")
+	emitVariableAddr(s.Itervar)
+	ff := lookupForeignFunc(newQI("runtime", "mapIterInit"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitExpr(s.X, nil)
+	emitCallFF(ff)
+	emitStore(tUintptr, true, false)
+
+	// Condition
+	// if mapIterNext(itervar) then
+	//   execute body
+	// else
+	//   exit
+	emitComment(2, "ForRange Condition\n")
+	fmt.Printf("  %s:\n", labelCond)
+	emitCallMapIter("mapIterNext", s.Itervar)
+	emitPopBool("mapIterNext")
+	fmt.Printf("  cmpq $1, %%rax\n")
+	fmt.Printf("  jne %s # jmp if false\n", labelExit)
+
+	if s.Key != nil && !isBlankIdentifier(s.Key) {
+		emitComment(2, "assign map key to key variable\n")
+		emitAddr(s.Key) // lhs
+		emitCallMapIter("mapIterKey", s.Itervar)
+		emitLoadAndPush(tEface) // boxed key
+		if !isInterface(keyType) {
+			fmt.Printf("  popq %%rax # key dtype\n")
+			emitLoadAndPush(keyType) // unbox
+		}
+		emitStore(keyType, true, false)
+	}
+	if s.Value != nil && !isBlankIdentifier(s.Value) {
+		emitComment(2, "assign map value to value variable\n")
+		emitAddr(s.Value) // lhs
+		emitCallMapIter("mapIterValue", s.Itervar)
+		emitLoadAndPush(valueType)
+		emitStore(valueType, true, false)
+	}
+
+	// Body
+	emitComment(2, "ForRange Body\n")
+	emitStmt(s.Body)
+
+	fmt.Printf("  %s:\n", labelPost) // used for "continue"
+	fmt.Printf("  jmp %s\n", labelCond)
+
+	fmt.Printf("  %s:\n", labelExit)
+}
+
+// call runtime.mapIterXXX(itervar)
+func emitCallMapIter(name string, itervar *Variable) {
+	ff := lookupForeignFunc(newQI("runtime", name))
+	emitAllocReturnVarsAreaFF(ff)
+	emitVariableAddr(itervar)
+	emitLoadAndPush(tUintptr)
+	emitCallFF(ff)
+}
+
 func emitIncDecStmt(s *ast.IncDecStmt) {
 	var addValue int
 	switch s.Tok.String() {
@@ -1795,6 +3999,14 @@ func emitIncDecStmt(s *ast.IncDecStmt) {
 	default:
 		panic("Unexpected Tok=" + s.Tok.String())
 	}
+	if isMapIndexExpr(s.X) {
+		// m[k]++
+		emitExpr(s.X, nil)
+		emitAddConst(addValue, "rhs ++ or --")
+		emitAddrForMapSet(s.X.(*ast.IndexExpr))
+		emitStore(getTypeOfExpr(s.X), false, false)
+		return
+	}
 	emitAddr(s.X)
 	emitExpr(s.X, nil)
 	emitAddConst(addValue, "rhs ++ or --")
@@ -1803,6 +4015,7 @@ func emitIncDecStmt(s *ast.IncDecStmt) {
 func emitSwitchStmt(s *ast.SwitchStmt) {
 	labelid++
 	labelEnd := fmt.Sprintf(".L.switch.%d.exit", labelid)
+	s.LabelExit = labelEnd
 	if s.Init != nil {
 		panic("TBI")
 	}
@@ -1843,7 +4056,8 @@ func emitSwitchStmt(s *ast.SwitchStmt) {
 				emitPushStackTop(condType, SizeOfInt, "switch expr")
 				emitExpr(e, nil)
 				emitCallFF(ff)
-			case T_INT, T_UINT8, T_UINT16, T_UINTPTR, T_POINTER:
+			case T_INT, T_INT8, T_INT16, T_INT32, T_INT64, T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64,
+				T_UINTPTR, T_POINTER:
 				emitPushStackTop(condType, 0, "switch expr")
 				emitExpr(e, nil)
 				emitCompExpr("sete")
@@ -1874,7 +4088,11 @@ func emitSwitchStmt(s *ast.SwitchStmt) {
 		for _, _s := range cc.Body {
 			emitStmt(_s)
 		}
-		fmt.Printf("  jmp %s\n", labelEnd)
+		if hasFallthrough(cc) {
+			fmt.Printf("  jmp %s # fallthrough\n", labels[i+1])
+		} else {
+			fmt.Printf("  jmp %s\n", labelEnd)
+		}
 	}
 	fmt.Printf("%s:\n", labelEnd)
 }
@@ -1883,6 +4101,7 @@ func emitTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 	//		assert(ok, "should exist")
 	labelid++
 	labelEnd := fmt.Sprintf(".L.typeswitch.%d.exit", labelid)
+	s.LabelExit = labelEnd
 
 	// subjectVariable = subject
 	emitVariableAddr(typeSwitch.SubjectVariable)
@@ -1904,13 +4123,24 @@ func emitTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 			continue
 		}
 		for _, e := range cc.List {
-			emitVariableAddr(typeSwitch.SubjectVariable)
-			emitPopAddress("type switch subject")
-			fmt.Printf("  movq (%%rax), %%rax # dtype\n")
-			fmt.Printf("  pushq %%rax # dtype\n")
+			if isInterface(e2t(e)) {
+				ff := lookupForeignFunc(newQI("runtime", "implements"))
+				emitAllocReturnVarsAreaFF(ff)
+				emitDtypeSymbol(e2t(e))
+				emitVariableAddr(typeSwitch.SubjectVariable)
+				emitPopAddress("type switch subject")
+				fmt.Printf("  movq (%%rax), %%rax # dtype\n")
+				fmt.Printf("  pushq %%rax # dtype\n")
+				emitCallFF(ff)
+			} else {
+				emitVariableAddr(typeSwitch.SubjectVariable)
+				emitPopAddress("type switch subject")
+				fmt.Printf("  movq (%%rax), %%rax # dtype\n")
+				fmt.Printf("  pushq %%rax # dtype\n")
 
-			emitDtypeSymbol(e2t(e))
-			emitCompExpr("sete") // this pushes 1 or 0 in the end
+				emitDtypeSymbol(e2t(e))
+				emitCompExpr("sete") // this pushes 1 or 0 in the end
+			}
 			emitPopBool(" of switch-case comparison")
 
 			fmt.Printf("  cmpq $1, %%rax\n")
@@ -1941,10 +4171,12 @@ func emitTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 				emitAddr(expr)
 				emitVariableAddr(typeSwitch.SubjectVariable)
 				emitLoadAndPush(tEface)
-				fmt.Printf("  popq %%rax # ifc.dtype\n")
-				fmt.Printf("  popq %%rcx # ifc.data\n")
-				fmt.Printf("  push %%rcx # ifc.data\n")
-				emitLoadAndPush(typeSwitchCaseClose.VariableType)
+				if !isInterface(typeSwitchCaseClose.VariableType) {
+					fmt.Printf("  popq %%rax # ifc.dtype\n")
+					fmt.Printf("  popq %%rcx # ifc.data\n")
+					fmt.Printf("  push %%rcx # ifc.data\n")
+					emitLoadAndPush(typeSwitchCaseClose.VariableType)
+				}
 
 				emitStore(typeSwitchCaseClose.VariableType, true, false)
 			}
@@ -1955,37 +4187,162 @@ func emitTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 	}
 	fmt.Printf("%s:\n", labelEnd)
 }
-func emitBranchStmt(s *ast.BranchStmt) {
-	var containerFor = s.CurrentFor
-	var labelToGo string
-	switch s.Tok.String() {
-	case "continue":
-		switch s := containerFor.(type) {
-		case *ast.ForStmt:
-			labelToGo = s.LabelPost
-		case *ast.RangeStmt:
-			labelToGo = s.LabelPost
-		default:
-			panic("unexpected container dtype=" + dtypeOf(containerFor))
+// select is compiled as
+//   sel := runtime.newselect(); runtime.selectcase(sel, ch, isSend) ...
+//   switch runtime.selectgo(sel, block) { case i: <operation of case i>; body }
+func emitSelectStmt(s *ast.SelectStmt) {
+	labelid++
+	labelEnd := fmt.Sprintf(".L.select.end.%d", labelid)
+	s.LabelExit = labelEnd
+	for i, vr := range s.Tmps {
+		emitNewBox(vr)
+		emitAssignToVar(vr, s.TmpExprs[i])
+	}
+
+	ff := lookupForeignFunc(newQI("runtime", "newselect"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitCallFF(ff) // push select state
+
+	ffCase := lookupForeignFunc(newQI("runtime", "selectcase"))
+	var clauseLabels []string
+	var labels []string // labels of the registered cases
+	var labelDefault string
+	var ncases int
+	for i, stmt := range s.Body.List {
+		cc := stmt.(*ast.CommClause)
+		label := fmt.Sprintf(".L.select.case.%d.%d", labelid, i)
+		clauseLabels = append(clauseLabels, label)
+		if cc.Comm == nil {
+			labelDefault = label
+			continue
+		}
+		labels = append(labels, label)
+		ncases++
+		emitAllocReturnVarsAreaFF(ffCase)
+		switch comm := cc.Comm.(type) {
+		case *ast.SendStmt:
+			fmt.Printf("  pushq $1 # isSend\n")
+			emitExpr(comm.Chan, nil)
+		case *ast.ExprStmt:
+			fmt.Printf("  pushq $0 # isSend\n")
+			emitExpr(comm.X.(*ast.UnaryExpr).X, nil)
+		case *ast.AssignStmt:
+			fmt.Printf("  pushq $0 # isSend\n")
+			emitExpr(comm.Rhs[0].(*ast.UnaryExpr).X, nil)
+		default:
+			throw(cc.Comm)
+		}
+		emitPushStackTop(tUintptr, 16, "select state")
+		emitCallFF(ffCase)
+	}
+
+	ffGo := lookupForeignFunc(newQI("runtime", "selectgo"))
+	emitAllocReturnVarsAreaFF(ffGo)
+	if labelDefault == "" {
+		fmt.Printf("  pushq $1 # block\n")
+	} else {
+		fmt.Printf("  pushq $0 # block\n")
+	}
+	emitPushStackTop(tUintptr, 16, "select state")
+	emitCallFF(ffGo)
+	fmt.Printf("  popq %%rax # index of the chosen case\n")
+	fmt.Printf("  addq $8, %%rsp # free select state\n")
+	for i := 0; i < ncases; i++ {
+		fmt.Printf("  cmpq $%d, %%rax\n", i)
+		fmt.Printf("  je %s\n", labels[i])
+	}
+	if labelDefault != "" {
+		fmt.Printf("  jmp %s\n", labelDefault)
+	} else {
+		fmt.Printf("  jmp %s\n", labelEnd)
+	}
+
+	// the chosen operation can proceed without blocking
+	for i, stmt := range s.Body.List {
+		cc := stmt.(*ast.CommClause)
+		fmt.Printf("  %s:\n", clauseLabels[i])
+		if cc.Comm != nil {
+			emitStmt(cc.Comm)
+		}
+		for _, _s := range cc.Body {
+			emitStmt(_s)
+		}
+		fmt.Printf("  jmp %s\n", labelEnd)
+	}
+	fmt.Printf("  %s:\n", labelEnd)
+}
+
+// evaluate the operands of the call and push the closure
+func emitCallThunk(th *ast.CallThunk) {
+	for i, vr := range th.Tmps {
+		emitNewBox(vr)
+		emitAssignToVar(vr, th.TmpExprs[i])
+	}
+	emitFuncLit(th.Lit, nil)
+}
+
+func emitDeferStmt(s *ast.DeferStmt) {
+	ff := lookupForeignFunc(newQI("runtime", "deferproc"))
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq $%d # depth\n", s.Thunk.Depth)
+	fmt.Printf("  pushq %%rbp # frame\n")
+	emitCallThunk(s.Thunk)
+	emitCallFF(ff)
+}
+
+func emitGoStmt(s *ast.GoStmt) {
+	ff := lookupForeignFunc(newQI("runtime", "newproc"))
+	emitAllocReturnVarsAreaFF(ff)
+	emitCallThunk(s.Thunk)
+	emitCallFF(ff)
+}
+
+func emitBranchStmt(s *ast.BranchStmt) {
+	var labelToGo string
+	switch s.Tok.String() {
+	case "continue":
+		switch target := s.Target.(type) {
+		case *ast.ForStmt:
+			labelToGo = target.LabelPost
+		case *ast.RangeStmt:
+			labelToGo = target.LabelPost
+		default:
+			panic("unexpected container dtype=" + dtypeOf(s.Target))
 		}
 		fmt.Printf("jmp %s # continue\n", labelToGo)
 	case "break":
-		switch s := containerFor.(type) {
+		switch target := s.Target.(type) {
 		case *ast.ForStmt:
-			labelToGo = s.LabelExit
+			labelToGo = target.LabelExit
 		case *ast.RangeStmt:
-			labelToGo = s.LabelExit
+			labelToGo = target.LabelExit
+		case *ast.SwitchStmt:
+			labelToGo = target.LabelExit
+		case *ast.TypeSwitchStmt:
+			labelToGo = target.LabelExit
+		case *ast.SelectStmt:
+			labelToGo = target.LabelExit
 		default:
-			panic("unexpected container dtype=" + dtypeOf(containerFor))
+			panic("unexpected container dtype=" + dtypeOf(s.Target))
 		}
 		fmt.Printf("jmp %s # break\n", labelToGo)
+	case "goto":
+		fmt.Printf("jmp %s # goto\n", s.Target.(*ast.LabeledStmt).Symbol)
+	case "fallthrough":
+		// the jump to the next case is emitted by emitSwitchStmt
 	default:
 		panic("unexpected tok=" + s.Tok)
 	}
 }
 
+func emitLabeledStmt(s *ast.LabeledStmt) {
+	fmt.Printf("  %s:\n", s.Symbol)
+	emitStmt(s.Stmt)
+}
+
 func emitStmt(stmt ast.Stmt) {
 	emitComment(2, "== Statement %s ==\n", dtypeOf(stmt))
+	emitLineEntry(stmtLinePos(stmt))
 	switch s := stmt.(type) {
 	case *ast.BlockStmt:
 		emitBlockStmt(s)
@@ -2011,6 +4368,16 @@ func emitStmt(stmt ast.Stmt) {
 		emitTypeSwitchStmt(s)
 	case *ast.BranchStmt:
 		emitBranchStmt(s)
+	case *ast.LabeledStmt:
+		emitLabeledStmt(s)
+	case *ast.DeferStmt:
+		emitDeferStmt(s)
+	case *ast.GoStmt:
+		emitGoStmt(s)
+	case *ast.SendStmt:
+		emitSendStmt(s)
+	case *ast.SelectStmt:
+		emitSelectStmt(s)
 	default:
 		panic("TBI:" + dtypeOf(stmt))
 	}
@@ -2060,6 +4427,8 @@ func emitFuncDecl(pkgName string, fnc *ast.Func) {
 	} else {
 		symbol = getPackageSymbol(pkgName, fnc.Name)
 	}
+	funcTable = append(funcTable, symbol)
+	addFuncLineEntry(symbol, fnc.Body.Lbrace)
 	fmt.Printf("%s: # args %d, locals %d\n", symbol, int(fnc.Argsarea), int(fnc.Localarea))
 	fmt.Printf("  pushq %%rbp\n")
 	fmt.Printf("  movq %%rsp, %%rbp\n")
@@ -2076,10 +4445,40 @@ func emitFuncDecl(pkgName string, fnc *ast.Func) {
 		fmt.Printf("  subq $%d, %%rsp # local area\n", -fnc.Localarea)
 	}
 
+	currentFunc = fnc
+	if fnc.ClosureVar != nil {
+		fmt.Printf("  movq %%rdx, %d(%%rbp) # closure\n", fnc.ClosureVar.LocalOffset)
+	}
+	// move variables captured by closures to the heap
+	for _, vr := range fnc.Params {
+		if vr.IsEscaped {
+			emitNewBox(vr)
+			fmt.Printf("  leaq %d(%%rbp), %%rax # param \"%s\"\n", vr.LocalOffset, vr.Name)
+			fmt.Printf("  pushq %%rax\n")
+			emitLoadAndPush(vr.Typ)
+			emitVariableAddr(vr)
+			emitStore(vr.Typ, false, false)
+		}
+	}
+	for _, vr := range fnc.Vars {
+		emitNewBox(vr)
+	}
+	if fnc.HasDefer {
+		// results are zero values when the function is recovered from a panic
+		for _, vr := range fnc.Retvars {
+			emitVariableAddr(vr)
+			emitZeroValue(vr.Typ)
+			emitStore(vr.Typ, true, false)
+		}
+	}
+
 	if fnc.Body != nil {
 		emitStmt(fnc.Body)
 	}
 
+	if fnc.HasDefer {
+		emitDeferReturn()
+	}
 	fmt.Printf("  leave\n")
 	fmt.Printf("  ret\n")
 }
@@ -2087,7 +4486,7 @@ func emitFuncDecl(pkgName string, fnc *ast.Func) {
 func emitGlobalVariableComplex(name *ast.Ident, t *Type, val ast.Expr) {
 	typeKind := kind(t)
 	switch typeKind {
-	case T_POINTER:
+	case T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		fmt.Printf("# init global %s:\n", name.Name)
 		lhs := name
 		emitAssign(lhs, val)
@@ -2109,6 +4508,11 @@ func emitGlobalVariable(pkg *PkgContainer, name *ast.Ident, t *Type, val ast.Exp
 			var sl = getStringLiteral(vl)
 			fmt.Printf("  .quad %s\n", sl.label)
 			fmt.Printf("  .quad %d\n", sl.strlen)
+		case *ast.Ident:
+			assert(vl.Obj.Kind == ast.Con, "should be a constant", __func__)
+			var sl = getStringLiteral(getConstStringLiteral(vl))
+			fmt.Printf("  .quad %s\n", sl.label)
+			fmt.Printf("  .quad %d\n", sl.strlen)
 		default:
 			panic("Unsupported global string value")
 		}
@@ -2121,53 +4525,71 @@ func emitGlobalVariable(pkg *PkgContainer, name *ast.Ident, t *Type, val ast.Exp
 			fmt.Printf("  .quad 0 # bool zero value\n")
 			return
 		}
-		switch vl := val.(type) {
-		case *ast.Ident:
-			switch vl.Obj {
-			case gTrue:
-				fmt.Printf("  .quad 1 # bool true\n")
-			case gFalse:
-				fmt.Printf("  .quad 0 # bool false\n")
-			default:
-				panic("")
-			}
-		default:
-			panic("")
+		if !isConstExpr(val) {
+			panic("Unsupported global value")
 		}
-	case T_INT:
+		if evalConst(val).bval {
+			fmt.Printf("  .quad 1 # bool true\n")
+		} else {
+			fmt.Printf("  .quad 0 # bool false\n")
+		}
+	case T_INT, T_INT64, T_UINT, T_UINT64:
 		if val == nil {
 			fmt.Printf("  .quad 0\n")
 			return
 		}
-		switch vl := val.(type) {
-		case *ast.BasicLit:
-			fmt.Printf("  .quad %s\n", vl.Value)
-		default:
+		if !isConstExpr(val) {
 			panic("Unsupported global value")
 		}
-	case T_UINT8:
+		fmt.Printf("  .quad %d\n", convertConst(evalConst(val), t).ival.Int64())
+	case T_INT32, T_UINT32:
+		if val == nil {
+			fmt.Printf("  .long 0\n")
+			return
+		}
+		if !isConstExpr(val) {
+			panic("Unsupported global value")
+		}
+		fmt.Printf("  .long %d\n", convertConst(evalConst(val), t).ival.Int64())
+	case T_INT8, T_UINT8:
 		if val == nil {
 			fmt.Printf("  .byte 0\n")
 			return
 		}
-		switch vl := val.(type) {
-		case *ast.BasicLit:
-			fmt.Printf("  .byte %s\n", vl.Value)
-		default:
+		if !isConstExpr(val) {
 			panic("Unsupported global value")
 		}
-	case T_UINT16:
+		fmt.Printf("  .byte %d\n", convertConst(evalConst(val), t).ival.Int64())
+	case T_INT16, T_UINT16:
 		if val == nil {
 			fmt.Printf("  .word 0\n")
 			return
 		}
-		switch val.(type) {
-		case *ast.BasicLit:
-			fmt.Printf("  .word %s\n", expr2BasicLit(val).Value)
-		default:
+		if !isConstExpr(val) {
 			panic("Unsupported global value")
 		}
-	case T_POINTER:
+		fmt.Printf("  .word %d\n", convertConst(evalConst(val), t).ival.Int64())
+	case T_FLOAT64:
+		if val == nil {
+			fmt.Printf("  .quad 0\n")
+			return
+		}
+		if !isConstExpr(val) {
+			panic("Unsupported global value")
+		}
+		fval := constFloat64(convertConst(evalConst(val), t))
+		fmt.Printf("  .quad %d # %s\n", int(math.Float64bits(fval)), strconv.FormatFloat(fval, 'g', -1, 64))
+	case T_FLOAT32:
+		if val == nil {
+			fmt.Printf("  .long 0\n")
+			return
+		}
+		if !isConstExpr(val) {
+			panic("Unsupported global value")
+		}
+		fval := constFloat64(convertConst(evalConst(val), t))
+		fmt.Printf("  .long %d # %s\n", int(math.Float32bits(float32(fval))), strconv.FormatFloat(fval, 'g', -1, 32))
+	case T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		// will be set in the initGlobal func
 		fmt.Printf("  .quad 0\n")
 	case T_UINTPTR:
@@ -2193,14 +4615,15 @@ func emitGlobalVariable(pkg *PkgContainer, name *ast.Ident, t *Type, val ast.Exp
 		if arrayType.Len == nil {
 			panic("global slice is not supported")
 		}
-		bl := expr2BasicLit(arrayType.Len)
-		var length = evalInt(bl)
+		var length = evalInt(arrayType.Len)
 		emitComment(0, "[emitGlobalVariable] array length uint8=%d\n", length)
 		var zeroValue string
 		knd := kind(e2t(arrayType.Elt))
 		switch knd {
 		case T_INT:
 			zeroValue = "  .quad 0 # int zero value\n"
+		case T_UINTPTR:
+			zeroValue = "  .quad 0 # uintptr zero value\n"
 		case T_UINT8:
 			zeroValue = "  .byte 0 # uint8 zero value\n"
 		case T_STRING:
@@ -2229,36 +4652,34 @@ func generateCode(pkg *PkgContainer) {
 	for _, con := range pkg.stringLiterals {
 		emitComment(0, "string literals\n")
 		fmt.Printf("%s:\n", con.sl.label)
-		fmt.Printf("  .string %s\n", con.sl.value)
+		fmt.Printf("  .string %s\n", strconv.Quote(con.sl.value))
 	}
 
 	for _, spec := range pkg.vars {
-		var val ast.Expr
-		if len(spec.Values) > 0 {
-			val = spec.Values[0]
-		}
-		var t *Type
-		if spec.Type != nil {
-			t = e2t(spec.Type)
+		for i, name := range spec.Names {
+			var val ast.Expr
+			if len(spec.Values) > 0 {
+				val = spec.Values[i]
+			}
+			emitGlobalVariable(pkg, name, obj2var(name.Obj).Typ, val)
+			if hasPointers(obj2var(name.Obj).Typ) {
+				globalRoots = append(globalRoots, obj2var(name.Obj))
+			}
 		}
-
-		emitGlobalVariable(pkg, spec.Name, t, val)
-
 	}
 
 	fmt.Printf("\n")
 	fmt.Printf(".text\n")
+	funcTable = append(funcTable, pkg.name+".__initGlobals")
+	addFuncLineEntry(pkg.name+".__initGlobals", token.NoPos)
 	fmt.Printf("%s.__initGlobals:\n", pkg.name)
 	for _, spec := range pkg.vars {
 		if len(spec.Values) == 0 {
 			continue
 		}
-		val := spec.Values[0]
-		var t *Type
-		if spec.Type != nil {
-			t = e2t(spec.Type)
+		for i, name := range spec.Names {
+			emitGlobalVariableComplex(name, obj2var(name.Obj).Typ, spec.Values[i])
 		}
-		emitGlobalVariableComplex(spec.Name, t, val)
 	}
 	fmt.Printf("  ret\n")
 
@@ -2269,6 +4690,175 @@ func generateCode(pkg *PkgContainer) {
 	fmt.Printf("\n")
 }
 
+// globals which may hold pointers to the heap
+var globalRoots []*Variable
+
+// The garbage collector scans the globals listed in this table.
+// Its layout is the number of entries followed by (address, size) pairs.
+func emitGlobalRoots() {
+	fmt.Printf("# ------- Global Roots ------\n")
+	fmt.Printf(".data\n")
+	fmt.Printf("runtime.globalRoots:\n")
+	fmt.Printf("  .quad %d # len\n", len(globalRoots))
+	for _, vr := range globalRoots {
+		fmt.Printf("  .quad %s\n", vr.GlobalSymbol)
+		fmt.Printf("  .quad %d # size\n", getSizeOfType(vr.Typ))
+	}
+	fmt.Printf("\n")
+}
+
+// the symbols of the functions emitted so far
+var funcTable []string
+
+// The runtime finds the function which contains a pc in this table.
+// Its layout is the number of entries followed by (address, name) pairs.
+// The last entry has no name and marks the end of the compiled code.
+func emitFuncTable() {
+	fmt.Printf("# ------- Func Table ------\n")
+	fmt.Printf(".text\n")
+	fmt.Printf("runtime.etext:\n")
+	fmt.Printf(".data\n")
+	fmt.Printf("runtime.functab:\n")
+	fmt.Printf("  .quad %d # len\n", len(funcTable)+1)
+	for i, symbol := range funcTable {
+		fmt.Printf("  .quad %s\n", symbol)
+		fmt.Printf("  .quad .F%d\n", i)
+	}
+	fmt.Printf("  .quad runtime.etext\n")
+	fmt.Printf("  .quad 0 # no name\n")
+	for i, symbol := range funcTable {
+		fmt.Printf(".F%d:\n", i)
+		fmt.Printf("  .string \"%s\"\n", symbol)
+	}
+	fmt.Printf("\n")
+}
+
+// the code from label on belongs to a source line
+type lineEntry struct {
+	label string
+	file  string // "" for generated code
+	line  int
+}
+
+// the lines of the code emitted so far, in the order of addresses
+var lineTable []*lineEntry
+
+// start a function in the line table. pos is the position of its body, if any.
+func addFuncLineEntry(symbol string, pos token.Pos) {
+	entry := &lineEntry{
+		label: symbol,
+	}
+	if pos != token.NoPos {
+		file := fset.File(pos)
+		entry.file = file.Name()
+		entry.line = file.Line(pos)
+	}
+	lineTable = append(lineTable, entry)
+}
+
+// mark that the code emitted next belongs to the line at pos,
+// unless it continues the line of the previous code.
+func emitLineEntry(pos token.Pos) {
+	if pos == token.NoPos {
+		return
+	}
+	file := fset.File(pos)
+	line := file.Line(pos)
+	last := lineTable[len(lineTable)-1]
+	if last.file == file.Name() && last.line == line {
+		return
+	}
+	entry := &lineEntry{
+		label: fmt.Sprintf(".LN%d", len(lineTable)),
+		file:  file.Name(),
+		line:  line,
+	}
+	fmt.Printf("  %s:\n", entry.label)
+	lineTable = append(lineTable, entry)
+}
+
+// the position of a statement in the line table
+func stmtLinePos(stmt ast.Stmt) token.Pos {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		return s.Lbrace
+	case *ast.ExprStmt:
+		switch x := s.X.(type) {
+		case *ast.CallExpr:
+			return x.Lparen
+		case *ast.UnaryExpr:
+			return x.OpPos
+		}
+	case *ast.DeclStmt:
+		return s.Decl.(*ast.GenDecl).TokPos
+	case *ast.AssignStmt:
+		return s.TokPos
+	case *ast.ReturnStmt:
+		return s.Return
+	case *ast.IfStmt:
+		return s.If
+	case *ast.ForStmt:
+		return s.For
+	case *ast.RangeStmt:
+		return s.For
+	case *ast.IncDecStmt:
+		return s.TokPos
+	case *ast.SwitchStmt:
+		return s.Switch
+	case *ast.TypeSwitchStmt:
+		return s.Switch
+	case *ast.BranchStmt:
+		return s.TokPos
+	case *ast.DeferStmt:
+		return s.Defer
+	case *ast.GoStmt:
+		return s.Go
+	case *ast.SendStmt:
+		return s.Arrow
+	case *ast.SelectStmt:
+		return s.Select
+	}
+	return token.NoPos
+}
+
+// The runtime finds the source line of a pc in this table.
+// Its layout is the number of entries followed by (address, file, line) triples.
+// A file of 0 means generated code, and the last entry marks the end of the compiled code.
+func emitLineTable() {
+	fmt.Printf("# ------- Line Table ------\n")
+	fmt.Printf(".data\n")
+	fmt.Printf("runtime.linetab:\n")
+	fmt.Printf("  .quad %d # len\n", len(lineTable)+1)
+	var files []string
+	for _, entry := range lineTable {
+		fmt.Printf("  .quad %s\n", entry.label)
+		if entry.file == "" {
+			fmt.Printf("  .quad 0 # generated\n")
+		} else {
+			var id int = -1
+			for i, file := range files {
+				if file == entry.file {
+					id = i
+				}
+			}
+			if id < 0 {
+				id = len(files)
+				files = append(files, entry.file)
+			}
+			fmt.Printf("  .quad .LF%d\n", id)
+		}
+		fmt.Printf("  .quad %d # line\n", entry.line)
+	}
+	fmt.Printf("  .quad runtime.etext\n")
+	fmt.Printf("  .quad 0\n")
+	fmt.Printf("  .quad 0\n")
+	for i, file := range files {
+		fmt.Printf(".LF%d:\n", i)
+		fmt.Printf("  .string %s\n", strconv.Quote(file))
+	}
+	fmt.Printf("\n")
+}
+
 func emitDynamicTypes(typeMap []*typeEntry) {
 	// emitting dynamic types
 	fmt.Printf("# ------- Dynamic Types ------\n")
@@ -2281,93 +4871,504 @@ func emitDynamicTypes(typeMap []*typeEntry) {
 		fmt.Printf("  .quad %d\n", id)
 		fmt.Printf("  .quad .S.dtype.%d\n", id)
 		fmt.Printf("  .quad %d\n", len(name))
+		fmt.Printf("  .quad %d # kind\n", getReflectKind(te.typ))
+		fmt.Printf("  .quad %d # size\n", getSizeOfType(te.typ))
+		var nmethods int
+		if isInterface(te.typ) {
+			nmethods = len(getInterfaceMethods(te.typ))
+		} else {
+			nmethods = len(getMethodSet(te.typ))
+		}
+		fmt.Printf("  .quad .M.dtype.%d # methods\n", id)
+		fmt.Printf("  .quad %d\n", nmethods)
+		fmt.Printf("  .quad %d\n", nmethods)
+		if hasEqualFunc(te.typ) {
+			fmt.Printf("  .quad .E.dtype.%d # equal\n", id)
+			fmt.Printf("  .quad .H.dtype.%d # hash\n", id)
+		} else {
+			fmt.Printf("  .quad 0 # equal\n")
+			fmt.Printf("  .quad 0 # hash\n")
+		}
 		fmt.Printf(".S.dtype.%d:\n", id)
 		fmt.Printf("  .string \"%s\"\n", name)
+		emitMethodTable(te)
+		if hasEqualFunc(te.typ) {
+			fmt.Printf(".E.dtype.%d:\n", id)
+			fmt.Printf("  .quad %s\n", getEqualFuncSymbol(te.typ))
+			fmt.Printf(".H.dtype.%d:\n", id)
+			fmt.Printf("  .quad %s\n", getHashFuncSymbol(te.typ))
+		}
+	}
+	fmt.Printf("\n")
+
+	fmt.Printf(".text\n")
+	for _, te := range typeMap {
+		if isInterface(te.typ) {
+			continue
+		}
+		for _, method := range getMethodSet(te.typ) {
+			emitMethodWrapper(te, method)
+		}
+		if hasEqualFunc(te.typ) {
+			emitEqualFunc(te)
+			emitHashFunc(te)
+		}
 	}
 	fmt.Printf("\n")
 }
 
-// --- type ---
-type TypeKind string
+// The equal func of a dynamic type compares two values of the type, and its hash func hashes a value consistently with it.
+// Interface types have none since they are never dynamic types.
+func hasEqualFunc(t *Type) bool {
+	return !isInterface(t) && isComparable(t)
+}
 
-const T_STRING TypeKind = "T_STRING"
-const T_INTERFACE TypeKind = "T_INTERFACE"
-const T_SLICE TypeKind = "T_SLICE"
-const T_BOOL TypeKind = "T_BOOL"
-const T_INT TypeKind = "T_INT"
-const T_INT32 TypeKind = "T_INT32"
-const T_UINT8 TypeKind = "T_UINT8"
-const T_UINT16 TypeKind = "T_UINT16"
-const T_UINTPTR TypeKind = "T_UINTPTR"
-const T_ARRAY TypeKind = "T_ARRAY"
-const T_STRUCT TypeKind = "T_STRUCT"
-const T_POINTER TypeKind = "T_POINTER"
+func getEqualFuncSymbol(t *Type) string {
+	return typeIdToSymbol(getTypeId(t)) + "..eq"
+}
 
-func getTypeOfExpr(expr ast.Expr) *Type {
-	//emitComment(0, "[%s] start\n", __func__)
-	switch e := expr.(type) {
-	case *ast.Ident:
-		if e.Obj == nil {
-			panic(e.Name)
-		}
-		switch e.Obj.Kind {
-		case ast.Var:
-			// injected type is the 1st priority
-			// this use case happens in type switch with short decl var
-			// switch ident := x.(type) {
-			// case T:
-			//    y := ident // <= type of ident cannot be associated directly with ident
-			//
-			if e.Obj.Variable != nil {
-				return e.Obj.Variable.Typ
-			}
-			switch decl := e.Obj.Decl.(type) {
-			case *ast.ValueSpec:
-				var t = &Type{}
-				t.E = decl.Type
-				return t
-			case *ast.Field:
-				var t = &Type{}
-				t.E = decl.Type
-				return t
-			case *ast.AssignStmt: // lhs := rhs
-				return getTypeOfExpr(decl.Rhs[0])
-			default:
-				panic("unkown dtype ")
-			}
-		case ast.Con:
-			switch e.Obj {
-			case gTrue, gFalse:
-				return tBool
-			}
-			switch decl2 := e.Obj.Decl.(type) {
-			case *ast.ValueSpec:
-				return e2t(decl2.Type)
-			default:
-				panic("cannot decide type of cont =" + e.Obj.Name)
+// func(p uintptr, q uintptr) bool reports whether the values at p and q are equal.
+func emitEqualFunc(te *typeEntry) {
+	labelid++
+	labelFalse := fmt.Sprintf(".L.%d.false", labelid)
+	funcTable = append(funcTable, getEqualFuncSymbol(te.typ))
+	addFuncLineEntry(getEqualFuncSymbol(te.typ), token.NoPos)
+	fmt.Printf("%s: # equal func of %s\n", getEqualFuncSymbol(te.typ), te.serialized)
+	fmt.Printf("  pushq %%rbp\n")
+	fmt.Printf("  movq %%rsp, %%rbp\n")
+	emitEqualValues(te.typ, 0, labelFalse)
+	fmt.Printf("  movq $1, 32(%%rbp) # true\n")
+	fmt.Printf("  leave\n")
+	fmt.Printf("  ret\n")
+	fmt.Printf("  %s:\n", labelFalse)
+	fmt.Printf("  movq $0, 32(%%rbp) # false\n")
+	fmt.Printf("  leave\n")
+	fmt.Printf("  ret\n")
+	fmt.Printf("\n")
+}
+
+// compare the values of type t at offset from p and q, and jump to labelFalse if they differ
+func emitEqualValues(t *Type, offset int, labelFalse string) {
+	switch kind(t) {
+	case T_STRUCT:
+		for _, field := range getUnderlyingStructType(t).Fields.List {
+			if getFieldName(field) == "_" {
+				continue
 			}
-		default:
-			panic("2:Obj=" + e.Obj.Name + e.Obj.Kind)
+			emitEqualValues(e2t(field.Type), offset+getStructFieldOffset(field), labelFalse)
 		}
-	case *ast.BasicLit:
-		switch e.Kind.String() {
-		case "STRING":
-			return tString
-		case "INT":
-			return tInt
-		case "CHAR":
-			return tInt32
-		default:
-			panic(e.Kind.String())
+	case T_ARRAY:
+		arrayType := getUnderlyingType(t).E.(*ast.ArrayType)
+		elmType := e2t(arrayType.Elt)
+		elmSize := getSizeOfType(elmType)
+		for i := 0; i < evalInt(arrayType.Len); i++ {
+			emitEqualValues(elmType, offset+elmSize*i, labelFalse)
 		}
-	case *ast.IndexExpr:
-		var list = e.X
-		return getElementTypeOfListType(getTypeOfExpr(list))
-	case *ast.UnaryExpr:
-		switch e.Op.String() {
-		case "+":
-			return getTypeOfExpr(e.X)
-		case "-":
+	case T_STRING:
+		ff := lookupForeignFunc(newQI("runtime", "cmpstrings"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitPushEqualOperand(t, 24, offset)
+		emitPushEqualOperand(t, 16, offset)
+		emitCallFF(ff)
+		emitPopBool("cmpstrings")
+		fmt.Printf("  cmpq $0, %%rax\n")
+		fmt.Printf("  je %s\n", labelFalse)
+	case T_INTERFACE:
+		ff := lookupForeignFunc(newQI("runtime", "cmpinterface"))
+		emitAllocReturnVarsAreaFF(ff)
+		emitPushEqualOperand(t, 24, offset)
+		emitPushEqualOperand(t, 16, offset)
+		emitCallFF(ff)
+		emitPopBool("cmpinterface")
+		fmt.Printf("  cmpq $0, %%rax\n")
+		fmt.Printf("  je %s\n", labelFalse)
+	case T_FLOAT32, T_FLOAT64:
+		// NaN is unordered and equal to nothing
+		emitPushEqualOperand(t, 24, offset)
+		emitPushEqualOperand(t, 16, offset)
+		fmt.Printf("  popq %%rax\n")
+		fmt.Printf("  popq %%rcx\n")
+		fmt.Printf("  movq %%rax, %%xmm0\n")
+		fmt.Printf("  movq %%rcx, %%xmm1\n")
+		fmt.Printf("  ucomisd %%xmm1, %%xmm0\n")
+		fmt.Printf("  jne %s\n", labelFalse)
+		fmt.Printf("  jp %s\n", labelFalse)
+	default:
+		emitPushEqualOperand(t, 24, offset)
+		emitPushEqualOperand(t, 16, offset)
+		fmt.Printf("  popq %%rax\n")
+		fmt.Printf("  popq %%rcx\n")
+		fmt.Printf("  cmpq %%rcx, %%rax\n")
+		fmt.Printf("  jne %s\n", labelFalse)
+	}
+}
+
+// push the value of type t at offset from the pointer parameter at paramOffset(%rbp)
+func emitPushEqualOperand(t *Type, paramOffset int, offset int) {
+	fmt.Printf("  movq %d(%%rbp), %%rax # param\n", paramOffset)
+	fmt.Printf("  addq $%d, %%rax # offset\n", offset)
+	fmt.Printf("  pushq %%rax\n")
+	emitLoadAndPush(t)
+}
+
+func getHashFuncSymbol(t *Type) string {
+	return typeIdToSymbol(getTypeId(t)) + "..hash"
+}
+
+// func(h uintptr, p uintptr) uintptr mixes the value at p into the hash h.
+func emitHashFunc(te *typeEntry) {
+	funcTable = append(funcTable, getHashFuncSymbol(te.typ))
+	addFuncLineEntry(getHashFuncSymbol(te.typ), token.NoPos)
+	fmt.Printf("%s: # hash func of %s\n", getHashFuncSymbol(te.typ), te.serialized)
+	fmt.Printf("  pushq %%rbp\n")
+	fmt.Printf("  movq %%rsp, %%rbp\n")
+	fmt.Printf("  movq 16(%%rbp), %%rax # h\n")
+	fmt.Printf("  movq %%rax, 32(%%rbp) # result\n")
+	emitHashValues(te.typ, 0)
+	fmt.Printf("  leave\n")
+	fmt.Printf("  ret\n")
+	fmt.Printf("\n")
+}
+
+// mix the value of type t at offset from p into the result.
+// The parts compared by emitEqualValues are hashed by their contents, so that equal values have the same hash.
+func emitHashValues(t *Type, offset int) {
+	switch kind(t) {
+	case T_STRUCT:
+		for _, field := range getUnderlyingStructType(t).Fields.List {
+			if getFieldName(field) == "_" {
+				continue
+			}
+			emitHashValues(e2t(field.Type), offset+getStructFieldOffset(field))
+		}
+	case T_ARRAY:
+		arrayType := getUnderlyingType(t).E.(*ast.ArrayType)
+		elmType := e2t(arrayType.Elt)
+		elmSize := getSizeOfType(elmType)
+		for i := 0; i < evalInt(arrayType.Len); i++ {
+			emitHashValues(elmType, offset+elmSize*i)
+		}
+	case T_STRING:
+		emitCallHashFunc("strhash", offset, 0)
+	case T_INTERFACE:
+		emitCallHashFunc("interhash", offset, 0)
+	case T_FLOAT32:
+		emitCallHashFunc("f32hash", offset, 0)
+	case T_FLOAT64:
+		emitCallHashFunc("f64hash", offset, 0)
+	default:
+		emitCallHashFunc("hashBytes", offset, getSizeOfType(t))
+	}
+}
+
+// result = runtime.name(result, p + offset) or runtime.hashBytes(result, p + offset, size)
+func emitCallHashFunc(name string, offset int, size int) {
+	ff := lookupForeignFunc(newQI("runtime", name))
+	emitAllocReturnVarsAreaFF(ff)
+	if size > 0 {
+		fmt.Printf("  pushq $%d # size\n", size)
+	}
+	fmt.Printf("  movq 24(%%rbp), %%rax # p\n")
+	fmt.Printf("  addq $%d, %%rax # offset\n", offset)
+	fmt.Printf("  pushq %%rax\n")
+	fmt.Printf("  pushq 32(%%rbp) # h\n")
+	emitCallFF(ff)
+	fmt.Printf("  popq %%rax\n")
+	fmt.Printf("  movq %%rax, 32(%%rbp) # result\n")
+}
+
+// A method table is a list of (method id, name, code address) entries.
+// The code address of an interface type is 0 since it is only used to check implementation.
+func emitMethodTable(te *typeEntry) {
+	fmt.Printf(".M.dtype.%d:\n", te.id)
+	var names []string
+	if isInterface(te.typ) {
+		for _, m := range getInterfaceMethods(te.typ) {
+			fmt.Printf("  .quad %d # %s\n", getMethodId(m.Name.Name, m.Type.(*ast.FuncType)), m.Name.Name)
+			emitMethodName(te, m.Name.Name)
+			fmt.Printf("  .quad 0\n")
+			names = append(names, m.Name.Name)
+		}
+	} else {
+		for _, method := range getMethodSet(te.typ) {
+			fmt.Printf("  .quad %d # %s\n", getMethodId(method.Name, method.FuncType), method.Name)
+			emitMethodName(te, method.Name)
+			fmt.Printf("  .quad %s\n", getMethodWrapperSymbol(te, method))
+			names = append(names, method.Name)
+		}
+	}
+	for _, name := range names {
+		fmt.Printf(".M.dtype.%d.%s:\n", te.id, name)
+		fmt.Printf("  .string \"%s\"\n", name)
+	}
+}
+
+func emitMethodName(te *typeEntry, name string) {
+	fmt.Printf("  .quad .M.dtype.%d.%s\n", te.id, name)
+	fmt.Printf("  .quad %d\n", len(name))
+}
+
+func getMethodWrapperSymbol(te *typeEntry, method *ast.Method) string {
+	return typeIdToSymbol(te.id) + "." + method.Name
+}
+
+// A method wrapper is called with an interface value as its receiver.
+// It copies the dynamic value to the receiver of the actual method, calls it and copies back the results.
+//
+//   -- stack top of the wrapper (after the prologue)
+//   rbp
+//   return address
+//   ifc.dtype
+//   ifc.data
+//   args
+//   results
+func emitMethodWrapper(te *typeEntry, method *ast.Method) {
+	if method.RcvNamedType == nil {
+		emitEmbeddedInterfaceMethodWrapper(te, method)
+		return
+	}
+	var rcvSize int
+	if method.IsPtrMethod {
+		rcvSize = 8
+	} else {
+		rcvSize = getSizeOfType(e2t(method.RcvNamedType))
+	}
+	argsSize := getTotalFieldsSize(method.FuncType.Params)
+	resultsSize := getTotalFieldsSize(method.FuncType.Results)
+	paramsOffset := 32 // rbp, return address, ifc.dtype, ifc.data
+	symbol := getMethodWrapperSymbol(te, method)
+	ff := lookupForeignFunc(newQI("runtime", "memcopy"))
+
+	funcTable = append(funcTable, symbol)
+	addFuncLineEntry(symbol, token.NoPos)
+	fmt.Printf("%s: # wrapper of %s\n", symbol, getMethodSymbol(method))
+	fmt.Printf("  pushq %%rbp\n")
+	fmt.Printf("  movq %%rsp, %%rbp\n")
+	fmt.Printf("  subq $%d, %%rsp # alloc results and parameters area\n", resultsSize+rcvSize+argsSize)
+
+	// receiver
+	fmt.Printf("  movq %d(%%rbp), %%rax # ifc.data\n", paramsOffset-8)
+	if len(method.Embedded) > 0 {
+		// promoted method
+		if kind(te.typ) == T_POINTER {
+			fmt.Printf("  movq (%%rax), %%rax # dereference\n")
+		}
+		for _, field := range method.Embedded {
+			fmt.Printf("  addq $%d, %%rax # embedded field %s\n", getStructFieldOffset(field), getFieldName(field))
+			if kind(e2t(field.Type)) == T_POINTER {
+				fmt.Printf("  movq (%%rax), %%rax # dereference\n")
+			}
+		}
+	} else if kind(te.typ) == T_POINTER && !method.IsPtrMethod {
+		fmt.Printf("  movq (%%rax), %%rax # dereference\n")
+	}
+	if len(method.Embedded) > 0 && method.IsPtrMethod {
+		fmt.Printf("  movq %%rax, (%%rsp) # receiver\n")
+	} else {
+		fmt.Printf("  movq %%rsp, %%rcx # receiver\n")
+		fmt.Printf("  pushq $%d # size\n", rcvSize)
+		fmt.Printf("  pushq %%rcx # dst\n")
+		fmt.Printf("  pushq %%rax # src\n")
+		emitCallFF(ff)
+	}
+
+	// args
+	if argsSize > 0 {
+		fmt.Printf("  leaq %d(%%rsp), %%rcx # args\n", rcvSize)
+		fmt.Printf("  pushq $%d # size\n", argsSize)
+		fmt.Printf("  pushq %%rcx # dst\n")
+		fmt.Printf("  leaq %d(%%rbp), %%rax # args\n", paramsOffset)
+		fmt.Printf("  pushq %%rax # src\n")
+		emitCallFF(ff)
+	}
+
+	fmt.Printf("  callq %s\n", getMethodSymbol(method))
+	emitFreeParametersArea(rcvSize + argsSize)
+
+	// results
+	if resultsSize > 0 {
+		fmt.Printf("  leaq %d(%%rbp), %%rcx # results\n", paramsOffset+argsSize)
+		fmt.Printf("  pushq $%d # size\n", resultsSize)
+		fmt.Printf("  pushq %%rcx # dst\n")
+		fmt.Printf("  leaq 16(%%rsp), %%rax # results\n")
+		fmt.Printf("  pushq %%rax # src\n")
+		emitCallFF(ff)
+	}
+	fmt.Printf("  leave\n")
+	fmt.Printf("  ret\n")
+	fmt.Printf("\n")
+}
+
+// The wrapper of a method promoted from an embedded interface replaces its receiver with the embedded interface value
+// and jumps to the method wrapper of the dynamic type, which returns to the caller.
+func emitEmbeddedInterfaceMethodWrapper(te *typeEntry, method *ast.Method) {
+	symbol := getMethodWrapperSymbol(te, method)
+	ff := lookupForeignFunc(newQI("runtime", "findMethod"))
+
+	funcTable = append(funcTable, symbol)
+	addFuncLineEntry(symbol, token.NoPos)
+	fmt.Printf("%s: # wrapper of embedded interface method %s\n", symbol, method.Name)
+	fmt.Printf("  pushq %%rbp\n")
+	fmt.Printf("  movq %%rsp, %%rbp\n")
+	fmt.Printf("  movq 24(%%rbp), %%rax # ifc.data\n")
+	if kind(te.typ) == T_POINTER {
+		fmt.Printf("  movq (%%rax), %%rax # dereference\n")
+	}
+	for _, field := range method.Embedded {
+		fmt.Printf("  addq $%d, %%rax # embedded field %s\n", getStructFieldOffset(field), getFieldName(field))
+		if kind(e2t(field.Type)) == T_POINTER {
+			fmt.Printf("  movq (%%rax), %%rax # dereference\n")
+		}
+	}
+	fmt.Printf("  movq (%%rax), %%rcx # embedded ifc.dtype\n")
+	fmt.Printf("  movq 8(%%rax), %%rax # embedded ifc.data\n")
+	fmt.Printf("  movq %%rcx, 16(%%rbp) # ifc.dtype\n")
+	fmt.Printf("  movq %%rax, 24(%%rbp) # ifc.data\n")
+	emitAllocReturnVarsAreaFF(ff)
+	fmt.Printf("  pushq $%d # method id of %s\n", getMethodId(method.Name, method.FuncType), method.Name)
+	fmt.Printf("  pushq %%rcx # ifc.dtype\n")
+	emitCallFF(ff)
+	fmt.Printf("  popq %%rax # method wrapper\n")
+	fmt.Printf("  leave\n")
+	fmt.Printf("  jmp *%%rax\n")
+	fmt.Printf("\n")
+}
+
+// --- type ---
+type TypeKind string
+
+const T_STRING TypeKind = "T_STRING"
+const T_INTERFACE TypeKind = "T_INTERFACE"
+const T_SLICE TypeKind = "T_SLICE"
+const T_BOOL TypeKind = "T_BOOL"
+const T_INT TypeKind = "T_INT"
+const T_INT8 TypeKind = "T_INT8"
+const T_INT16 TypeKind = "T_INT16"
+const T_INT32 TypeKind = "T_INT32"
+const T_INT64 TypeKind = "T_INT64"
+const T_UINT TypeKind = "T_UINT"
+const T_UINT8 TypeKind = "T_UINT8"
+const T_UINT16 TypeKind = "T_UINT16"
+const T_UINT32 TypeKind = "T_UINT32"
+const T_UINT64 TypeKind = "T_UINT64"
+const T_UINTPTR TypeKind = "T_UINTPTR"
+const T_FLOAT32 TypeKind = "T_FLOAT32"
+const T_FLOAT64 TypeKind = "T_FLOAT64"
+const T_ARRAY TypeKind = "T_ARRAY"
+const T_STRUCT TypeKind = "T_STRUCT"
+const T_POINTER TypeKind = "T_POINTER"
+const T_MAP TypeKind = "T_MAP"
+const T_CHAN TypeKind = "T_CHAN"
+const T_FUNC TypeKind = "T_FUNC"
+
+// The type of min or max is the type of a typed argument.
+// Untyped constant arguments take the kind float if any of them is a float.
+func getMinMaxType(args []ast.Expr) *Type {
+	for _, arg := range args {
+		if !isConstExpr(arg) || evalConst(arg).typ != nil {
+			return getTypeOfExpr(arg)
+		}
+	}
+	for _, arg := range args {
+		if evalConst(arg).kind == T_FLOAT64 {
+			return getTypeOfExpr(arg)
+		}
+	}
+	return getTypeOfExpr(args[0])
+}
+
+// An untyped constant operand takes the type of the other operand.
+// Of two untyped operands a float wins over an integer.
+func getOperandType(e *ast.BinaryExpr) *Type {
+	if isNilIdent(e.X) {
+		return getTypeOfExpr(e.Y)
+	}
+	if isConstExpr(e.X) && evalConst(e.X).typ == nil {
+		if evalConst(e.X).kind == T_FLOAT64 && isConstExpr(e.Y) && evalConst(e.Y).typ == nil {
+			return getTypeOfExpr(e.X)
+		}
+		return getTypeOfExpr(e.Y)
+	}
+	return getTypeOfExpr(e.X)
+}
+
+func getTypeOfExpr(expr ast.Expr) *Type {
+	//emitComment(0, "[%s] start\n", __func__)
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Obj == nil {
+			panic(e.Name)
+		}
+		switch e.Obj.Kind {
+		case ast.Var:
+			// injected type is the 1st priority
+			// this use case happens in type switch with short decl var
+			// switch ident := x.(type) {
+			// case T:
+			//    y := ident // <= type of ident cannot be associated directly with ident
+			//
+			if e.Obj.Variable != nil {
+				return e.Obj.Variable.Typ
+			}
+			switch decl := e.Obj.Decl.(type) {
+			case *ast.ValueSpec:
+				var t = &Type{}
+				t.E = decl.Type
+				return t
+			case *ast.Field:
+				var t = &Type{}
+				t.E = decl.Type
+				return t
+			case *ast.AssignStmt: // lhs := rhs
+				return getTypeOfExpr(decl.Rhs[0])
+			default:
+				panic("unkown dtype ")
+			}
+		case ast.Fun:
+			return e2t(e.Obj.Decl.(*ast.FuncDecl).Type)
+		case ast.Con:
+			switch e.Obj {
+			case gTrue, gFalse:
+				return tBool
+			case gIota:
+				return tInt
+			}
+			switch decl2 := e.Obj.Decl.(type) {
+			case *ast.ValueSpec:
+				if decl2.Type == nil {
+					// untyped constant
+					return getTypeOfExpr(getConstValue(e))
+				}
+				return e2t(decl2.Type)
+			default:
+				panic("cannot decide type of cont =" + e.Obj.Name)
+			}
+		default:
+			panic("2:Obj=" + e.Obj.Name + e.Obj.Kind)
+		}
+	case *ast.BasicLit:
+		switch e.Kind.String() {
+		case "STRING":
+			return tString
+		case "INT":
+			return tInt
+		case "FLOAT":
+			return tFloat64
+		case "CHAR":
+			return tInt32
+		default:
+			panic(e.Kind.String())
+		}
+	case *ast.IndexExpr:
+		var list = e.X
+		return getElementTypeOfListType(getTypeOfExpr(list))
+	case *ast.UnaryExpr:
+		switch e.Op.String() {
+		case "+":
+			return getTypeOfExpr(e.X)
+		case "-":
+			return getTypeOfExpr(e.X)
+		case "^":
 			return getTypeOfExpr(e.X)
 		case "!":
 			return tBool
@@ -2380,6 +5381,8 @@ func getTypeOfExpr(expr ast.Expr) *Type {
 			listType := getTypeOfExpr(e.X)
 			elmType := getElementTypeOfListType(listType)
 			return elmType
+		case "<-":
+			return getElementTypeOfListType(getTypeOfExpr(e.X))
 		default:
 			panic(e.Op.String())
 		}
@@ -2410,13 +5413,17 @@ func getTypeOfExpr(expr ast.Expr) *Type {
 		switch e.Op.String() {
 		case "==", "!=", "<", ">", "<=", ">=":
 			return tBool
-		default:
+		case "<<", ">>":
 			return getTypeOfExpr(e.X)
+		default:
+			return getOperandType(e)
 		}
 	case *ast.SelectorExpr:
 		if isQI(e) { // pkg.SomeType
 			ident := lookupForeignIdent(selector2QI(e))
 			return getTypeOfExpr(ident)
+		} else if isMethodSelector(e) { // x.M or T.M
+			return e2t(getMethodSelectorType(e))
 		} else { // (e).field
 			ut := getUnderlyingType(getTypeOfExpr(e.X))
 			var structTypeLiteral *ast.StructType
@@ -2438,6 +5445,8 @@ func getTypeOfExpr(expr ast.Expr) *Type {
 		return e2t(expr2TypeAssertExpr(expr).Type)
 	case *ast.InterfaceType:
 		return tEface
+	case *ast.FuncLit:
+		return e2t(e.Type)
 	default:
 		panic("TBI:dtype=" + dtypeOf(expr))
 	}
@@ -2459,6 +5468,10 @@ func fieldList2Types(fldlist *ast.FieldList) []*Type {
 func getCallResultTypes(e *ast.CallExpr) []*Type {
 	emitComment(2, "[%s] *ast.CallExpr\n", __func__)
 	var fun = e.Fun
+	if isFuncValue(fun) {
+		funcType := getUnderlyingType(getTypeOfExpr(fun)).E.(*ast.FuncType)
+		return fieldList2Types(funcType.Results)
+	}
 	switch fn := fun.(type) {
 	case *ast.Ident:
 		if fn.Obj == nil {
@@ -2479,6 +5492,12 @@ func getCallResultTypes(e *ast.CallExpr) []*Type {
 				return []*Type{e2t(e.Args[0])}
 			case gAppend:
 				return []*Type{e2t(e.Args[0])}
+			case gCopy:
+				return []*Type{tInt}
+			case gMin, gMax:
+				return []*Type{getMinMaxType(e.Args)}
+			case gRecover:
+				return []*Type{tEface}
 			}
 			var decl = fn.Obj.Decl
 			if decl == nil {
@@ -2492,12 +5511,8 @@ func getCallResultTypes(e *ast.CallExpr) []*Type {
 			}
 			panic("[astCallExpr] Fun ident " + fn.Name)
 		}
-	case *ast.ParenExpr: // (X)(e) funcall or conversion
-		if isType(fn.X) {
-			return []*Type{e2t(fn.X)}
-		} else {
-			panic("TBI: what should we do ?")
-		}
+	case *ast.ParenExpr: // (T)(e) conversion
+		return []*Type{e2t(fn.X)}
 	case *ast.ArrayType:
 		return []*Type{e2t(fun)}
 	case *ast.SelectorExpr:
@@ -2507,6 +5522,9 @@ func getCallResultTypes(e *ast.CallExpr) []*Type {
 		if isQI(fn) { // pkg.Sel()
 			ff := lookupForeignFunc(selector2QI(fn))
 			return fieldList2Types(ff.decl.Type.Results)
+		} else if isInterface(getTypeOfExpr(fn.X)) { // ifc.method()
+			var m = lookupInterfaceMethod(getTypeOfExpr(fn.X), fn.Sel)
+			return fieldList2Types(m.Type.(*ast.FuncType).Results)
 		} else { // obj.method()
 			var xType = getTypeOfExpr(fn.X)
 			var method = lookupMethod(xType, fn.Sel)
@@ -2550,12 +5568,30 @@ func serializeType(t *Type) string {
 				return "uintptr"
 			case gInt:
 				return "int"
+			case gInt8:
+				return "int8"
+			case gInt16:
+				return "int16"
+			case gInt32:
+				return "int32"
+			case gInt64:
+				return "int64"
 			case gString:
 				return "string"
+			case gUint:
+				return "uint"
 			case gUint8:
 				return "uint8"
 			case gUint16:
 				return "uint16"
+			case gUint32:
+				return "uint32"
+			case gUint64:
+				return "uint64"
+			case gFloat32:
+				return "float32"
+			case gFloat64:
+				return "float64"
 			case gBool:
 				return "bool"
 			default:
@@ -2587,7 +5623,35 @@ func serializeType(t *Type) string {
 	case *ast.Ellipsis: // x ...T
 		panic("TBD: Ellipsis")
 	case *ast.InterfaceType:
-		return "interface"
+		methods := getInterfaceMethods(t)
+		if len(methods) == 0 {
+			return "interface"
+		}
+		r := "interface {"
+		for i, m := range methods {
+			if i > 0 {
+				r = r + ";"
+			}
+			r = r + " " + serializeMethod(m.Name.Name, m.Type.(*ast.FuncType))
+		}
+		return r + " }"
+	case *ast.MapType:
+		return "map[" + serializeType(e2t(e.Key)) + "]" + serializeType(e2t(e.Value))
+	case *ast.ChanType:
+		switch e.Dir {
+		case ast.SEND:
+			return "chan<- " + serializeType(e2t(e.Value))
+		case ast.RECV:
+			return "<-chan " + serializeType(e2t(e.Value))
+		default:
+			return "chan " + serializeType(e2t(e.Value))
+		}
+	case *ast.FuncType:
+		r := "func(" + serializeFieldList(e.Params) + ")"
+		if e.Results != nil && len(e.Results.List) > 0 {
+			r = r + "(" + serializeFieldList(e.Results) + ")"
+		}
+		return r
 	case *ast.SelectorExpr:
 		qi := selector2QI(e)
 		return string(qi)
@@ -2597,6 +5661,22 @@ func serializeType(t *Type) string {
 	return ""
 }
 
+func serializeFieldList(fieldList *ast.FieldList) string {
+	var r string
+	for i, field := range fieldList.List {
+		if i > 0 {
+			r = r + ","
+		}
+		elp, isEllipsis := field.Type.(*ast.Ellipsis)
+		if isEllipsis {
+			r = r + "..." + serializeType(e2t(elp.Elt))
+		} else {
+			r = r + serializeType(e2t(field.Type))
+		}
+	}
+	return r
+}
+
 func getUnderlyingStructType(t *Type) *ast.StructType {
 	ut := getUnderlyingType(t)
 	return ut.E.(*ast.StructType)
@@ -2611,7 +5691,7 @@ func getUnderlyingType(t *Type) *Type {
 	}
 
 	switch e := t.E.(type) {
-	case *ast.StructType, *ast.ArrayType, *ast.StarExpr, *ast.Ellipsis, *ast.InterfaceType:
+	case *ast.StructType, *ast.ArrayType, *ast.StarExpr, *ast.Ellipsis, *ast.InterfaceType, *ast.MapType, *ast.ChanType, *ast.FuncType:
 		// type literal
 		return t
 	case *ast.Ident:
@@ -2649,14 +5729,30 @@ func kind(t *Type) TypeKind {
 			return T_UINTPTR
 		case gInt:
 			return T_INT
+		case gInt8:
+			return T_INT8
+		case gInt16:
+			return T_INT16
 		case gInt32:
 			return T_INT32
+		case gInt64:
+			return T_INT64
 		case gString:
 			return T_STRING
+		case gUint:
+			return T_UINT
 		case gUint8:
 			return T_UINT8
 		case gUint16:
 			return T_UINT16
+		case gUint32:
+			return T_UINT32
+		case gUint64:
+			return T_UINT64
+		case gFloat32:
+			return T_FLOAT32
+		case gFloat64:
+			return T_FLOAT64
 		case gBool:
 			return T_BOOL
 		default:
@@ -2676,14 +5772,94 @@ func kind(t *Type) TypeKind {
 		return T_SLICE // @TODO is this right ?
 	case *ast.InterfaceType:
 		return T_INTERFACE
+	case *ast.MapType:
+		return T_MAP
+	case *ast.ChanType:
+		return T_CHAN
+	case *ast.FuncType:
+		return T_FUNC
 	}
 	panic("should not reach here")
 }
 
+// Kind numbers are the same as reflect.Kind
+func getReflectKind(t *Type) int {
+	switch kind(t) {
+	case T_BOOL:
+		return 1
+	case T_INT:
+		return 2
+	case T_INT8:
+		return 3
+	case T_INT16:
+		return 4
+	case T_INT32:
+		return 5
+	case T_INT64:
+		return 6
+	case T_UINT:
+		return 7
+	case T_UINT8:
+		return 8
+	case T_UINT16:
+		return 9
+	case T_UINT32:
+		return 10
+	case T_UINT64:
+		return 11
+	case T_UINTPTR:
+		return 12
+	case T_FLOAT32:
+		return 13
+	case T_FLOAT64:
+		return 14
+	case T_ARRAY:
+		return 17
+	case T_CHAN:
+		return 18
+	case T_FUNC:
+		return 19
+	case T_INTERFACE:
+		return 20
+	case T_MAP:
+		return 21
+	case T_POINTER:
+		return 22
+	case T_SLICE:
+		return 23
+	case T_STRING:
+		return 24
+	case T_STRUCT:
+		return 25
+	default:
+		unexpectedKind(kind(t))
+	}
+	return 0
+}
+
 func isInterface(t *Type) bool {
 	return kind(t) == T_INTERFACE
 }
 
+// Slices, maps and functions are comparable only with nil.
+// Structs and arrays are comparable if their fields or elements are.
+func isComparable(t *Type) bool {
+	switch kind(t) {
+	case T_SLICE, T_MAP, T_FUNC:
+		return false
+	case T_ARRAY:
+		arrayType := getUnderlyingType(t).E.(*ast.ArrayType)
+		return isComparable(e2t(arrayType.Elt))
+	case T_STRUCT:
+		for _, field := range getUnderlyingStructType(t).Fields.List {
+			if !isComparable(e2t(field.Type)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func getElementTypeOfListType(t *Type) *Type {
 	switch kind(t) {
 	case T_SLICE, T_ARRAY:
@@ -2697,6 +5873,12 @@ func getElementTypeOfListType(t *Type) *Type {
 		}
 	case T_STRING:
 		return tUint8
+	case T_MAP:
+		mapType := getUnderlyingType(t).E.(*ast.MapType)
+		return e2t(mapType.Value)
+	case T_CHAN:
+		chanType := getUnderlyingType(t).E.(*ast.ChanType)
+		return e2t(chanType.Value)
 	default:
 		unexpectedKind(kind(t))
 	}
@@ -2704,11 +5886,19 @@ func getElementTypeOfListType(t *Type) *Type {
 	return r
 }
 
+func getKeyTypeOfMapType(t *Type) *Type {
+	mapType := getUnderlyingType(t).E.(*ast.MapType)
+	return e2t(mapType.Key)
+}
+
 const SizeOfSlice int = 24
 const SizeOfString int = 16
 const SizeOfInt int = 8
 const SizeOfUint8 int = 1
 const SizeOfUint16 int = 2
+const SizeOfUint32 int = 4
+const SizeOfFloat32 int = 4
+const SizeOfFloat64 int = 8
 const SizeOfPtr int = 8
 const SizeOfInterface int = 16
 
@@ -2719,14 +5909,20 @@ func getSizeOfType(t *Type) int {
 		return SizeOfSlice
 	case T_STRING:
 		return SizeOfString
-	case T_INT:
+	case T_INT, T_INT64, T_UINT, T_UINT64:
 		return SizeOfInt
-	case T_UINTPTR, T_POINTER:
+	case T_FLOAT64:
+		return SizeOfFloat64
+	case T_FLOAT32:
+		return SizeOfFloat32
+	case T_UINTPTR, T_POINTER, T_MAP, T_CHAN, T_FUNC:
 		return SizeOfPtr
-	case T_UINT8:
+	case T_INT8, T_UINT8:
 		return SizeOfUint8
-	case T_UINT16:
+	case T_INT16, T_UINT16:
 		return SizeOfUint16
+	case T_INT32, T_UINT32:
+		return SizeOfUint32
 	case T_BOOL:
 		return SizeOfInt
 	case T_INTERFACE:
@@ -2752,31 +5948,248 @@ func setStructFieldOffset(field *ast.Field, offset int) {
 	field.Offset = offset
 }
 
+// The name of an embedded field is the name of its type.
+func getFieldName(field *ast.Field) string {
+	if field.Name != nil {
+		return field.Name.Name
+	}
+	typ := field.Type
+	star, isStar := typ.(*ast.StarExpr)
+	if isStar {
+		typ = star.X
+	}
+	switch e := typ.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	throw(typ)
+	return ""
+}
+
 func lookupStructField(structType *ast.StructType, selName string) *ast.Field {
+	field := findStructField(structType, selName)
+	if field == nil {
+		panic("Unexpected flow: struct field not found:" + selName)
+	}
+	return field
+}
+
+// direct or promoted field of a struct type
+func findStructField(structType *ast.StructType, selName string) *ast.Field {
 	for _, field := range structType.Fields.List {
-		if field.Name.Name == selName {
+		if getFieldName(field) == selName {
 			return field
 		}
 	}
-	panic("Unexpected flow: struct field not found:" + selName)
-}
-
-func calcStructSizeAndSetFieldOffset(structType *ast.StructType) int {
+	for _, field := range structType.Fields.List {
+		if field.Name == nil {
+			embedded := getStructTypeOfSelector(e2t(field.Type))
+			if embedded != nil {
+				promoted := findStructField(embedded, selName)
+				if promoted != nil {
+					return promoted
+				}
+			}
+		}
+	}
+	var r *ast.Field
+	return r
+}
+
+// struct type of x in a selector x.f, or nil if x is neither a struct nor a pointer to struct
+func getStructTypeOfSelector(t *Type) *ast.StructType {
+	var structType *ast.StructType
+	switch typ := getUnderlyingType(t).E.(type) {
+	case *ast.StructType:
+		structType = typ
+	case *ast.StarExpr:
+		structType, _ = getUnderlyingType(e2t(typ.X)).E.(*ast.StructType)
+	}
+	return structType
+}
+
+// an embedded field reached from a type through the embedded fields path
+type embeddedField struct {
+	t    *Type
+	path []*ast.Field
+}
+
+// The embedded fields through which the field or method selName is promoted to the type t,
+// at the shallowest depth where it is found. It is empty if selName is declared by t itself or not found.
+// ambiguous reports that more than one embedded field at that depth has selName.
+func lookupEmbeddedPath(t *Type, selName string) ([]*ast.Field, bool) {
+	var found []*ast.Field
+	if hasFieldOrMethod(t, selName) {
+		return found, false
+	}
+	// named types at shallower depths, which are not searched again
+	var seen []*namedTypeEntry
+	seen = append(seen, findNamedTypeOf(t))
+	var level []*embeddedField
+	level = appendEmbeddedFields(level, t, found)
+	for len(level) > 0 {
+		var next []*embeddedField
+		var levelSeen []*namedTypeEntry
+		var n int
+		for _, ef := range level {
+			nt := findNamedTypeOf(ef.t)
+			if isSeenNamedType(seen, nt) {
+				continue
+			}
+			levelSeen = append(levelSeen, nt)
+			if hasFieldOrMethod(ef.t, selName) {
+				n++
+				found = ef.path
+			} else {
+				next = appendEmbeddedFields(next, ef.t, ef.path)
+			}
+		}
+		if n > 0 {
+			return found, n > 1
+		}
+		for _, nt := range levelSeen {
+			seen = append(seen, nt)
+		}
+		level = next
+	}
+	return found, false
+}
+
+func isSeenNamedType(seen []*namedTypeEntry, nt *namedTypeEntry) bool {
+	if nt == nil {
+		return false
+	}
+	for _, s := range seen {
+		if s == nt {
+			return true
+		}
+	}
+	return false
+}
+
+func appendEmbeddedFields(list []*embeddedField, t *Type, path []*ast.Field) []*embeddedField {
+	structType := getStructTypeOfSelector(t)
+	if structType == nil {
+		return list
+	}
+	for _, field := range structType.Fields.List {
+		if field.Name == nil {
+			var fieldPath []*ast.Field
+			for _, f := range path {
+				fieldPath = append(fieldPath, f)
+			}
+			fieldPath = append(fieldPath, field)
+			list = append(list, &embeddedField{
+				t:    e2t(field.Type),
+				path: fieldPath,
+			})
+		}
+	}
+	return list
+}
+
+// whether selName is a field or method declared by t itself
+func hasFieldOrMethod(t *Type, selName string) bool {
+	if isInterface(t) {
+		for _, m := range getInterfaceMethods(t) {
+			if m.Name.Name == selName {
+				return true
+			}
+		}
+		return false
+	}
+	structType := getStructTypeOfSelector(t)
+	if structType != nil {
+		for _, field := range structType.Fields.List {
+			if getFieldName(field) == selName {
+				return true
+			}
+		}
+	}
+	nt := findNamedTypeOf(t)
+	if nt == nil {
+		return false
+	}
+	for _, me := range nt.methods {
+		if me.name == selName {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields are aligned as in Go, so that pointers in a struct are on word boundaries.
+func calcStructSizeAndSetFieldOffset(structType *ast.StructType) int {
 	var offset int = 0
 	var fields = structType.Fields.List
 	for _, field := range fields {
+		offset = alignTo(offset, getAlignOfType(e2t(field.Type)))
 		setStructFieldOffset(field, offset)
 		var size = getSizeOfType(e2t(field.Type))
 		offset += size
 	}
-	return offset
+	return alignTo(offset, getAlignOfStruct(structType))
+}
+
+func alignTo(offset int, align int) int {
+	return (offset + align - 1) / align * align
+}
+
+func getAlignOfType(t *Type) int {
+	switch kind(t) {
+	case T_INT8, T_UINT8:
+		return 1
+	case T_INT16, T_UINT16:
+		return 2
+	case T_INT32, T_UINT32, T_FLOAT32:
+		return 4
+	case T_ARRAY:
+		arrayType := getUnderlyingType(t).E.(*ast.ArrayType)
+		return getAlignOfType(e2t(arrayType.Elt))
+	case T_STRUCT:
+		return getAlignOfStruct(getUnderlyingStructType(t))
+	}
+	return 8
+}
+
+func getAlignOfStruct(structType *ast.StructType) int {
+	var align int = 1
+	for _, field := range structType.Fields.List {
+		fieldAlign := getAlignOfType(e2t(field.Type))
+		if fieldAlign > align {
+			align = fieldAlign
+		}
+	}
+	return align
+}
+
+// whether values of t may hold addresses of heap objects, which the garbage collector has to scan
+func hasPointers(t *Type) bool {
+	switch kind(t) {
+	case T_INT, T_INT8, T_INT16, T_INT32, T_INT64, T_UINT, T_UINT8, T_UINT16, T_UINT32, T_UINT64,
+		T_FLOAT32, T_FLOAT64, T_BOOL:
+		return false
+	case T_ARRAY:
+		arrayType := getUnderlyingType(t).E.(*ast.ArrayType)
+		return hasPointers(e2t(arrayType.Elt))
+	case T_STRUCT:
+		for _, field := range getUnderlyingStructType(t).Fields.List {
+			if hasPointers(e2t(field.Type)) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
 }
 
 // --- walk ---
 type sliteral struct {
 	label  string
 	strlen int
-	value  string // raw value/pre/precompiler.go:2150
+	value  string
 }
 
 type stringLiteralsContainer struct {
@@ -2812,6 +6225,38 @@ func registerLocalVariable(fnc *ast.Func, name string, t *Type) *Variable {
 
 var currentFunc *ast.Func
 
+func registerParamsAndResults(fnc *ast.Func, paramFields []*ast.Field, resultFields []*ast.Field) {
+	for _, field := range paramFields {
+		obj := field.Name.Obj
+		setVariable(obj, registerParamVariable(fnc, obj.Name, e2t(field.Type)))
+	}
+
+	for i, field := range resultFields {
+		if field.Name == nil {
+			// unnamed retval
+			registerReturnVariable(fnc, ".r"+strconv.Itoa(i), e2t(field.Type))
+		} else {
+			panic("TBI: named return variable is not supported")
+		}
+	}
+}
+
+// reserve a slot for the heap address of each variable captured by closures
+func registerBoxes(fnc *ast.Func) {
+	for _, vr := range fnc.Params {
+		if vr.IsEscaped {
+			fnc.Localarea -= SizeOfPtr
+			vr.BoxOffset = fnc.Localarea
+		}
+	}
+	for _, vr := range fnc.Vars {
+		if vr.IsEscaped {
+			fnc.Localarea -= SizeOfPtr
+			vr.BoxOffset = fnc.Localarea
+		}
+	}
+}
+
 func getStringLiteral(lit *ast.BasicLit) *sliteral {
 	for _, container := range currentPkg.stringLiterals {
 		if container.lit == lit {
@@ -2827,20 +6272,14 @@ func registerStringLiteral(lit *ast.BasicLit) {
 		panic("no pkgName")
 	}
 
-	var strlen int
-	for _, c := range []uint8(lit.Value) {
-		if c != '\\' {
-			strlen++
-		}
-	}
-
+	value := evalStringLit(lit)
 	label := fmt.Sprintf(".%s.S%d", currentPkg.name, currentPkg.stringIndex)
 	currentPkg.stringIndex++
 
 	sl := &sliteral{
 		label:  label,
-		strlen: strlen - 2,
-		value:  lit.Value,
+		strlen: len(value),
+		value:  value,
 	}
 	cont := &stringLiteralsContainer{
 		sl : sl,
@@ -2952,7 +6391,23 @@ func registerMethod(method *ast.Method) {
 }
 
 func lookupMethod(rcvT *Type, methodName *ast.Ident) *ast.Method {
-	rcvType := rcvT.E
+	// the method set of *T contains all the methods of T
+	ptrT := rcvT
+	if kind(rcvT) != T_POINTER {
+		ptrT = e2t(&ast.StarExpr{X: rcvT.E})
+	}
+	for _, method := range getMethodSet(ptrT) {
+		if method.Name == methodName.Name {
+			return method
+		}
+	}
+
+	panic("method not found: " + methodName.Name)
+	return nil
+}
+
+func findNamedTypeOf(t *Type) *namedTypeEntry {
+	rcvType := t.E
 	rcvPointerType, isPtr := rcvType.(*ast.StarExpr)
 	if isPtr {
 		rcvType = rcvPointerType.X
@@ -2961,172 +6416,966 @@ func lookupMethod(rcvT *Type, methodName *ast.Ident) *ast.Method {
 	switch typ := rcvType.(type) {
 	case *ast.Ident:
 		nt = findNamedType(typ.Obj)
-		if nt == nil {
-			panic(typ.Name + " has no moethodeiverTypeName:")
+	case *ast.SelectorExpr:
+		nt = findNamedType(lookupForeignIdent(selector2QI(typ)).Obj)
+	}
+	return nt
+}
+
+// https://golang.org/ref/spec#Method_sets
+// The method set of *T also contains the methods with receiver T.
+// Methods of embedded fields are promoted unless a shallower one has the same name.
+func getMethodSet(t *Type) []*ast.Method {
+	var methods []*ast.Method
+	isPtr := kind(t) == T_POINTER
+	nt := findNamedTypeOf(t)
+	if nt != nil {
+		for _, me := range nt.methods {
+			if isPtr || !me.method.IsPtrMethod {
+				methods = append(methods, me.method)
+			}
+		}
+	}
+	structType := getStructTypeOfSelector(t)
+	if structType == nil {
+		return methods
+	}
+	for _, field := range structType.Fields.List {
+		if field.Name != nil {
+			continue
+		}
+		ft := e2t(field.Type)
+		if isInterface(ft) {
+			for _, m := range getInterfaceMethods(ft) {
+				// a method of an embedded interface has no receiver type
+				promoted := &ast.Method{
+					Name:     m.Name.Name,
+					FuncType: m.Type.(*ast.FuncType),
+				}
+				promoted.Embedded = append(promoted.Embedded, field)
+				methods = addPromotedMethod(methods, promoted)
+			}
+			continue
+		}
+		if isPtr && kind(ft) != T_POINTER {
+			// the embedded value is addressable through the pointer
+			ft = e2t(&ast.StarExpr{X: field.Type})
+		}
+		for _, m := range getMethodSet(ft) {
+			promoted := &ast.Method{
+				PkgName:      m.PkgName,
+				RcvNamedType: m.RcvNamedType,
+				IsPtrMethod:  m.IsPtrMethod,
+				Name:         m.Name,
+				FuncType:     m.FuncType,
+			}
+			promoted.Embedded = append(promoted.Embedded, field)
+			for _, f := range m.Embedded {
+				promoted.Embedded = append(promoted.Embedded, f)
+			}
+			methods = addPromotedMethod(methods, promoted)
 		}
+	}
+	return methods
+}
+
+func addPromotedMethod(methods []*ast.Method, promoted *ast.Method) []*ast.Method {
+	for i, m := range methods {
+		if m.Name == promoted.Name {
+			if len(promoted.Embedded) < len(m.Embedded) {
+				methods[i] = promoted
+			}
+			return methods
+		}
+	}
+	return append(methods, promoted)
+}
+
+// methods of an interface type including the ones of embedded interfaces
+func getInterfaceMethods(t *Type) []*ast.Field {
+	it := getUnderlyingType(t).E.(*ast.InterfaceType)
+	var methods []*ast.Field
+	if it.Methods == nil {
+		return methods
+	}
+	for _, field := range it.Methods.List {
+		if field.Name == nil {
+			// embedded interface
+			for _, m := range getInterfaceMethods(e2t(field.Type)) {
+				methods = append(methods, m)
+			}
+		} else {
+			methods = append(methods, field)
+		}
+	}
+	return methods
+}
+
+func lookupInterfaceMethod(t *Type, methodName *ast.Ident) *ast.Field {
+	for _, m := range getInterfaceMethods(t) {
+		if m.Name.Name == methodName.Name {
+			return m
+		}
+	}
+	panic("method not found: " + methodName.Name)
+	return nil
+}
+
+// whether x.M or T.M selects a method rather than a field or a package member
+func isMethodSelector(e *ast.SelectorExpr) bool {
+	if isQI(e) {
+		return false
+	}
+	if isType(e.X) {
+		return true
+	}
+	t := getTypeOfExpr(e.X)
+	if isInterface(t) {
+		return true
+	}
+	structType := getStructTypeOfSelector(t)
+	return structType == nil || findStructField(structType, e.Sel.Name) == nil
+}
+
+// the func type of x.M, or of T.M with the receiver as the first parameter
+func getMethodSelectorType(e *ast.SelectorExpr) *ast.FuncType {
+	var rcvType *Type
+	var params []*ast.Field
+	if isType(e.X) {
+		typeExpr := e.X
+		paren, isParen := typeExpr.(*ast.ParenExpr)
+		if isParen {
+			// (*T).M
+			typeExpr = paren.X
+		}
+		rcvType = e2t(typeExpr)
+		params = append(params, &ast.Field{Type: typeExpr})
+	} else {
+		rcvType = getTypeOfExpr(e.X)
+	}
+	var funcType *ast.FuncType
+	if isInterface(rcvType) {
+		funcType = lookupInterfaceMethod(rcvType, e.Sel).Type.(*ast.FuncType)
+	} else {
+		funcType = lookupMethod(rcvType, e.Sel).FuncType
+	}
+	for _, field := range funcType.Params.List {
+		params = append(params, &ast.Field{Type: field.Type})
+	}
+	return &ast.FuncType{
+		Params:  &ast.FieldList{List: params},
+		Results: funcType.Results,
+	}
+}
+
+// name and signature of a method like "Write([]uint8)(int)"
+func serializeMethod(name string, funcType *ast.FuncType) string {
+	return name + serializeType(e2t(funcType))[len("func"):]
+}
+
+// source form of an operand in error messages like "ambiguous selector c.x"
+func serializeExpr(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
 	case *ast.SelectorExpr:
-		qi := selector2QI(typ)
-		t := lookupForeignIdent(qi)
-		nt = findNamedType(t.Obj)
-		if nt == nil {
-			panic(string(qi) + " has no moethodeiverTypeName:")
+		return serializeExpr(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + serializeExpr(e.X)
+	case *ast.ParenExpr:
+		return "(" + serializeExpr(e.X) + ")"
+	case *ast.IndexExpr:
+		return serializeExpr(e.X) + "[" + serializeExpr(e.Index) + "]"
+	case *ast.CallExpr:
+		return serializeExpr(e.Fun) + "(...)"
+	case *ast.BasicLit:
+		return e.Value
+	}
+	return "(expr)"
+}
+
+func hasMethod(t *Type, name string, funcType *ast.FuncType) bool {
+	sig := serializeMethod(name, funcType)
+	if isInterface(t) {
+		for _, m := range getInterfaceMethods(t) {
+			if serializeMethod(m.Name.Name, m.Type.(*ast.FuncType)) == sig {
+				return true
+			}
 		}
+		return false
 	}
+	for _, method := range getMethodSet(t) {
+		if serializeMethod(method.Name, method.FuncType) == sig {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, me := range nt.methods {
-		if me.name == methodName.Name {
-			return me.method
+// static check of the assignability of a value of type t to an interface
+func checkImplements(t *Type, ifcType *Type) {
+	for _, m := range getInterfaceMethods(ifcType) {
+		if !hasMethod(t, m.Name.Name, m.Type.(*ast.FuncType)) {
+			panic(serializeType(t) + " does not implement " + serializeType(ifcType) + " (missing method " + m.Name.Name + ")")
+		}
+	}
+}
+
+// method ids shared by the method tables and the call sites of interface methods
+var methodSignatures []string
+
+func getMethodId(name string, funcType *ast.FuncType) int {
+	sig := serializeMethod(name, funcType)
+	for id, s := range methodSignatures {
+		if s == sig {
+			return id
+		}
+	}
+	methodSignatures = append(methodSignatures, sig)
+	return len(methodSignatures) - 1
+}
+
+func walkExprStmt(s *ast.ExprStmt) {
+	walkExpr(s.X)
+}
+func walkDeclStmt(s *ast.DeclStmt) {
+	genDecl := s.Decl.(*ast.GenDecl)
+	switch genDecl.Tok {
+	case token.CONST:
+		walkConstDecl(genDecl)
+		evalConstDecl(genDecl)
+	case token.VAR:
+		for _, spec := range genDecl.Specs {
+			valSpec := spec.(*ast.ValueSpec)
+			types := getTypesOfValueSpec(valSpec)
+			for i, name := range valSpec.Names {
+				obj := name.Obj
+				setVariable(obj, registerLocalVariable(currentFunc, obj.Name, types[i]))
+			}
+			for _, v := range valSpec.Values {
+				walkExpr(v)
+			}
+		}
+	default:
+		throw(genDecl)
+	}
+}
+
+// An omitted expression list of a const spec is equivalent to the preceding one.
+func walkConstDecl(genDecl *ast.GenDecl) {
+	var prev *ast.ValueSpec
+	for _, spec := range genDecl.Specs {
+		valSpec := spec.(*ast.ValueSpec)
+		if len(valSpec.Values) == 0 {
+			valSpec.Type = prev.Type
+			valSpec.Values = prev.Values
+		} else {
+			for _, v := range valSpec.Values {
+				walkExpr(v)
+			}
+		}
+		prev = valSpec
+	}
+}
+
+// Constants are folded when declared so that errors are reported even if they are unused.
+// A folded string constant gets its own literal unless it is declared by a literal.
+func evalConstDecl(genDecl *ast.GenDecl) {
+	for _, spec := range genDecl.Specs {
+		valSpec := spec.(*ast.ValueSpec)
+		for _, name := range valSpec.Names {
+			cv := evalConst(name)
+			if cv.kind != T_STRING {
+				continue
+			}
+			_, isLit := getConstValue(name).(*ast.BasicLit)
+			if isLit {
+				continue
+			}
+			lit := &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: strconv.Quote(cv.sval),
+			}
+			registerStringLiteral(lit)
+			constStringLiterals = append(constStringLiterals, &constStringLiteral{
+				obj: name.Obj,
+				lit: lit,
+			})
+		}
+	}
+}
+
+// types of the variables declared by a var spec
+func getTypesOfValueSpec(spec *ast.ValueSpec) []*Type {
+	var types []*Type
+	if spec.Type != nil {
+		for i := 0; i < len(spec.Names); i++ {
+			types = append(types, e2t(spec.Type))
+		}
+		return types
+	}
+	if len(spec.Values) == 0 {
+		panic("invalid syntax")
+	}
+	if len(spec.Names) > 1 && len(spec.Values) == 1 {
+		// var a, b = f()
+		return getCallResultTypes(spec.Values[0].(*ast.CallExpr))
+	}
+	// infer types from rhs
+	for _, v := range spec.Values {
+		typ := getTypeOfExpr(v)
+		if typ == nil || typ.E == nil {
+			panic("rhs should have a type")
+		}
+		types = append(types, typ)
+	}
+	return types
+}
+
+func walkAssignStmt(s *ast.AssignStmt) {
+	if s.Tok.String() == ":=" {
+		// short var decl
+		rhs0 := s.Rhs[0]
+		walkExpr(rhs0)
+		// infer type
+		var typ0 *Type
+		switch rhs := rhs0.(type) {
+		case *ast.CallExpr:
+			types := getCallResultTypes(rhs)
+			typ0 = types[0]
+		case *ast.TypeAssertExpr:
+			typ0 = getTypeOfExpr(rhs0)
+			if len(s.Lhs) == 2 { // lhs0, lhs1 := x.(T)
+				// declare lhs1 as an ok variable
+				okObj := s.Lhs[1].(*ast.Ident).Obj
+				//throw(okObj)
+				setVariable(okObj, registerLocalVariable(currentFunc, okObj.Name, tBool))
+			}
+		case *ast.UnaryExpr:
+			typ0 = getTypeOfExpr(rhs0)
+			if len(s.Lhs) == 2 { // lhs0, lhs1 := <-ch
+				// declare lhs1 as an ok variable
+				okObj := s.Lhs[1].(*ast.Ident).Obj
+				setVariable(okObj, registerLocalVariable(currentFunc, okObj.Name, tBool))
+			}
+		case *ast.IndexExpr:
+			typ0 = getTypeOfExpr(rhs0)
+			if len(s.Lhs) == 2 { // lhs0, lhs1 := m[k]
+				// declare lhs1 as an ok variable
+				okObj := s.Lhs[1].(*ast.Ident).Obj
+				setVariable(okObj, registerLocalVariable(currentFunc, okObj.Name, tBool))
+			}
+		default:
+			typ0 = getTypeOfExpr(rhs0)
+		}
+
+		if typ0 != nil && typ0.E != nil {
+		} else {
+			panic("type inference is not supported")
+		}
+		obj0 := s.Lhs[0].(*ast.Ident).Obj
+		setVariable(obj0, registerLocalVariable(currentFunc, obj0.Name, typ0))
+	} else {
+		for _, lhs := range s.Lhs {
+			walkExpr(lhs)
+		}
+		walkExpr(s.Rhs[0])
+	}
+}
+func walkReturnStmt(s *ast.ReturnStmt) {
+	s.Node = &ast.NodeReturnStmt{
+		Fnc: currentFunc,
+	}
+	for _, rt := range s.Results {
+		walkExpr(rt)
+	}
+}
+func walkIfStmt(s *ast.IfStmt) {
+	if s.Init != nil {
+		walkStmt(s.Init)
+	}
+	walkExpr(s.Cond)
+	for _, s := range s.Body.List {
+		walkStmt(s)
+	}
+	if s.Else != nil {
+		walkStmt(s.Else)
+	}
+}
+func walkForStmt(s *ast.ForStmt) {
+	s.Outer = currentFor
+	currentFor = s
+	breakables = append(breakables, s)
+	if s.Init != nil {
+		walkStmt(s.Init)
+	}
+	if s.Cond != nil {
+		walkExpr(s.Cond)
+	}
+	if s.Post != nil {
+		walkStmt(s.Post)
+	}
+	walkStmt(newStmt(s.Body))
+	breakables = breakables[:len(breakables)-1]
+	currentFor = s.Outer
+}
+func walkRangeStmt(s *ast.RangeStmt) {
+	walkExpr(s.X)
+	s.Outer = currentFor
+	currentFor = s
+	breakables = append(breakables, s)
+	listType := getTypeOfExpr(s.X)
+	if kind(listType) == T_MAP || kind(listType) == T_CHAN {
+		s.Itervar = registerLocalVariable(currentFunc, ".range.iter", tUintptr)
+	} else {
+		s.Lenvar = registerLocalVariable(currentFunc, ".range.len", tInt)
+		s.Indexvar = registerLocalVariable(currentFunc, ".range.index", tInt)
+	}
+
+	if s.Tok.String() == ":=" {
+		keyIdent := expr2Ident(s.Key)
+		var keyType *Type = tInt
+		if kind(listType) == T_MAP {
+			keyType = getKeyTypeOfMapType(listType)
+		} else if kind(listType) == T_CHAN {
+			keyType = getElementTypeOfListType(listType)
+		}
+		setVariable(keyIdent.Obj, registerLocalVariable(currentFunc, keyIdent.Name, keyType))
+
+		// determine type of Value
+		if s.Value != nil {
+			elmType := getElementTypeOfListType(listType)
+			if kind(listType) == T_STRING {
+				elmType = tInt32 // rune
+			}
+			valueIdent := expr2Ident(s.Value)
+			setVariable(valueIdent.Obj, registerLocalVariable(currentFunc, valueIdent.Name, elmType))
+		}
+	} else {
+		if s.Key != nil {
+			walkExpr(s.Key)
 		}
+		if s.Value != nil {
+			walkExpr(s.Value)
+		}
+	}
+	var _s = s.Body
+	walkStmt(_s)
+	breakables = breakables[:len(breakables)-1]
+	currentFor = s.Outer
+}
+func walkIncDecStmt(s *ast.IncDecStmt) {
+	walkExpr(s.X)
+}
+func walkBlockStmt(s *ast.BlockStmt) {
+	for _, _s := range s.List {
+		walkStmt(_s)
+	}
+}
+func walkBranchStmt(s *ast.BranchStmt) {
+	switch s.Tok.String() {
+	case "break", "continue":
+		s.Target = findBranchTarget(s)
+	case "goto":
+		// the label may be defined later
+		currentFunc.Gotos = append(currentFunc.Gotos, s)
+		currentFunc.UsedLabels = append(currentFunc.UsedLabels, s.Label)
+	case "fallthrough":
+		if s.Target == nil {
+			panic("fallthrough statement out of place")
+		}
+	default:
+		panic("unexpected tok=" + s.Tok)
+	}
+}
+
+var labeledStmtId int
+
+func walkLabeledStmt(s *ast.LabeledStmt) {
+	if lookupLabel(currentFunc, s.Label.Name) != nil {
+		panic("label " + s.Label.Name + " already defined")
+	}
+	labeledStmtId++
+	s.Symbol = fmt.Sprintf(".L.label.%d.%s", labeledStmtId, s.Label.Name)
+	currentFunc.Labels = append(currentFunc.Labels, s)
+	walkStmt(s.Stmt)
+}
+
+func lookupLabel(fnc *ast.Func, name string) *ast.LabeledStmt {
+	for _, s := range fnc.Labels {
+		if s.Label.Name == name {
+			return s
+		}
+	}
+	var r *ast.LabeledStmt
+	return r
+}
+
+func isLoop(s ast.Stmt) bool {
+	switch s.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		return true
+	}
+	return false
+}
+
+// the innermost enclosing statement, or the enclosing one with the label
+func findBranchTarget(s *ast.BranchStmt) ast.Stmt {
+	isContinue := s.Tok.String() == "continue"
+	if s.Label == "" {
+		for i := len(breakables) - 1; i >= 0; i-- {
+			if !isContinue || isLoop(breakables[i]) {
+				return breakables[i]
+			}
+		}
+		if isContinue {
+			panic("continue is not in a loop")
+		}
+		panic("break is not in a loop, switch, or select")
+	}
+	labeled := lookupLabel(currentFunc, s.Label)
+	if labeled == nil {
+		panic(s.Tok.String() + " label not defined: " + s.Label)
+	}
+	currentFunc.UsedLabels = append(currentFunc.UsedLabels, s.Label)
+	for _, b := range breakables {
+		if isSameStmt(b, labeled.Stmt) && (!isContinue || isLoop(b)) {
+			return b
+		}
+	}
+	panic("invalid " + s.Tok.String() + " label " + s.Label)
+	return nil
+}
+
+// Every label must be used by a goto, break or continue statement.
+func checkUnusedLabels(fnc *ast.Func) {
+	for _, labeled := range fnc.Labels {
+		var used bool
+		for _, name := range fnc.UsedLabels {
+			if name == labeled.Label.Name {
+				used = true
+			}
+		}
+		if !used {
+			panic("label " + labeled.Label.Name + " defined and not used")
+		}
+	}
+}
+
+// The label of a goto must not be in a block which does not contain the goto,
+// and the jump must not skip variable declarations in the block of the label.
+func resolveGotos(fnc *ast.Func, body *ast.BlockStmt) {
+	for _, s := range fnc.Gotos {
+		labeled := lookupLabel(fnc, s.Label)
+		if labeled == nil {
+			panic("label " + s.Label + " not defined")
+		}
+		s.Target = labeled
+		gotoPos := &stmtPos{}
+		findStmtPos(body, s, gotoPos)
+		labelPos := &stmtPos{}
+		findStmtPos(body, labeled, labelPos)
+		d := len(labelPos.blocks) - 1
+		if d >= len(gotoPos.blocks) || !isSameStmt(gotoPos.blocks[d], labelPos.blocks[d]) {
+			panic("goto " + s.Label + " jumps into block")
+		}
+		list := getStmtList(labelPos.blocks[d])
+		for i := gotoPos.indexes[d] + 1; i < labelPos.indexes[d]; i++ {
+			if isVarDeclStmt(list[i]) {
+				panic("goto " + s.Label + " jumps over variable declaration")
+			}
+		}
+	}
+}
+
+// position of a statement: the enclosing statement lists from the outermost one and the index in each of them
+type stmtPos struct {
+	blocks  []ast.Stmt // *ast.BlockStmt, *ast.CaseClause or *ast.CommClause
+	indexes []int
+}
+
+func getStmtList(block ast.Stmt) []ast.Stmt {
+	switch b := block.(type) {
+	case *ast.BlockStmt:
+		return b.List
+	case *ast.CaseClause:
+		return b.Body
+	case *ast.CommClause:
+		return b.Body
+	}
+	throw(block)
+	return nil
+}
+
+func findStmtPos(block ast.Stmt, target ast.Stmt, pos *stmtPos) bool {
+	for i, s := range getStmtList(block) {
+		pos.blocks = append(pos.blocks, block)
+		pos.indexes = append(pos.indexes, i)
+		if isSameStmt(s, target) || findStmtPosIn(s, target, pos) {
+			return true
+		}
+		pos.blocks = pos.blocks[:len(pos.blocks)-1]
+		pos.indexes = pos.indexes[:len(pos.indexes)-1]
+	}
+	return false
+}
+
+// search the blocks nested in a statement
+func findStmtPosIn(stmt ast.Stmt, target ast.Stmt, pos *stmtPos) bool {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		return findStmtPos(s, target, pos)
+	case *ast.LabeledStmt:
+		return isSameStmt(s.Stmt, target) || findStmtPosIn(s.Stmt, target, pos)
+	case *ast.IfStmt:
+		if findStmtPos(s.Body, target, pos) {
+			return true
+		}
+		return s.Else != nil && findStmtPosIn(s.Else, target, pos)
+	case *ast.ForStmt:
+		return findStmtPos(s.Body, target, pos)
+	case *ast.RangeStmt:
+		return findStmtPos(s.Body, target, pos)
+	case *ast.SwitchStmt:
+		return findStmtPosInClauses(s.Body, target, pos)
+	case *ast.TypeSwitchStmt:
+		return findStmtPosInClauses(s.Body, target, pos)
+	case *ast.SelectStmt:
+		return findStmtPosInClauses(s.Body, target, pos)
+	}
+	return false
+}
+
+func findStmtPosInClauses(body *ast.BlockStmt, target ast.Stmt, pos *stmtPos) bool {
+	for _, clause := range body.List {
+		if findStmtPos(clause, target, pos) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVarDeclStmt(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.DeclStmt:
+		return s.Decl.(*ast.GenDecl).Tok == token.VAR
+	case *ast.AssignStmt:
+		return s.Tok.String() == ":="
+	case *ast.LabeledStmt:
+		return isVarDeclStmt(s.Stmt)
+	}
+	return false
+}
+
+// Interface values hold a copy of the data, so statements are compared by their pointers.
+func isSameStmt(a ast.Stmt, b ast.Stmt) bool {
+	switch x := a.(type) {
+	case *ast.ForStmt:
+		y, ok := b.(*ast.ForStmt)
+		return ok && x == y
+	case *ast.RangeStmt:
+		y, ok := b.(*ast.RangeStmt)
+		return ok && x == y
+	case *ast.SwitchStmt:
+		y, ok := b.(*ast.SwitchStmt)
+		return ok && x == y
+	case *ast.TypeSwitchStmt:
+		y, ok := b.(*ast.TypeSwitchStmt)
+		return ok && x == y
+	case *ast.SelectStmt:
+		y, ok := b.(*ast.SelectStmt)
+		return ok && x == y
+	case *ast.BlockStmt:
+		y, ok := b.(*ast.BlockStmt)
+		return ok && x == y
+	case *ast.CaseClause:
+		y, ok := b.(*ast.CaseClause)
+		return ok && x == y
+	case *ast.CommClause:
+		y, ok := b.(*ast.CommClause)
+		return ok && x == y
+	case *ast.LabeledStmt:
+		y, ok := b.(*ast.LabeledStmt)
+		return ok && x == y
+	case *ast.BranchStmt:
+		y, ok := b.(*ast.BranchStmt)
+		return ok && x == y
 	}
+	return false
+}
 
-	panic("method not found: " + methodName.Name)
-	return nil
+// defer f(x) is compiled as
+//   tmp1 := f; tmp2 := x
+//   runtime.deferproc(func() { tmp1(tmp2) }, frame, depth)
+func walkDeferStmt(s *ast.DeferStmt) {
+	currentFunc.HasDefer = true
+	s.Thunk = walkCallThunk(s.Call)
 }
 
-func walkExprStmt(s *ast.ExprStmt) {
-	walkExpr(s.X)
+func walkGoStmt(s *ast.GoStmt) {
+	s.Thunk = walkCallThunk(s.Call)
 }
-func walkDeclStmt(s *ast.DeclStmt) {
-	genDecl := s.Decl.(*ast.GenDecl)
-	declSpec := genDecl.Spec
-	switch spec := declSpec.(type) {
-	case *ast.ValueSpec:
-		if spec.Type == nil { // var x = e
-			if len(spec.Values) == 0 {
-				panic("invalid syntax")
-			}
-			// infer type from rhs
-			val := spec.Values[0]
-			logf("inferring type of variable %s\n", spec.Name.Name)
-			typ := getTypeOfExpr(val)
-			if typ == nil || typ.E == nil {
-				panic("rhs should have a type")
+
+// wrap the call in a closure which takes no arguments
+func walkCallThunk(call *ast.CallExpr) *ast.CallThunk {
+	th := &ast.CallThunk{}
+	funcLit, isFuncLit := call.Fun.(*ast.FuncLit)
+	if isFuncLit && len(call.Args) == 0 {
+		// defer func() {...}() or go func() {...}()
+		walkFuncLit(funcLit)
+		th.Lit = funcLit
+		th.Depth = 1
+		return th
+	}
+	th.Depth = 2
+	if isInterfaceMethodCall(call) {
+		th.Depth = 3 // through the method wrapper
+	}
+
+	var fun ast.Expr = call.Fun
+	if isFuncValue(fun) {
+		fun = newThunkTmp(th, fun)
+	} else {
+		sel, isSel := fun.(*ast.SelectorExpr)
+		if isSel && !isQI(sel) {
+			// method call: the receiver is evaluated now
+			fun = &ast.SelectorExpr{
+				X:   newThunkTmp(th, methodReceiver(sel)),
+				Sel: sel.Sel,
 			}
-			spec.Type = typ.E
-		}
-		t := e2t(spec.Type)
-		obj := spec.Name.Obj
-		setVariable(obj, registerLocalVariable(currentFunc, obj.Name, t))
-		if len(spec.Values) > 0 {
-			walkExpr(spec.Values[0])
 		}
 	}
-}
-func walkAssignStmt(s *ast.AssignStmt) {
-	if s.Tok.String() == ":=" {
-		// short var decl
-		rhs0 := s.Rhs[0]
-		walkExpr(rhs0)
-		// infer type
-		var typ0 *Type
-		switch rhs := rhs0.(type) {
-		case *ast.CallExpr:
-			types := getCallResultTypes(rhs)
-			typ0 = types[0]
-		case *ast.TypeAssertExpr:
-			typ0 = getTypeOfExpr(rhs0)
-			if len(s.Lhs) == 2 { // lhs0, lhs1 := x.(T)
-				// declare lhs1 as an ok variable
-				okObj := s.Lhs[1].(*ast.Ident).Obj
-				//throw(okObj)
-				setVariable(okObj, registerLocalVariable(currentFunc, okObj.Name, tBool))
-			}
-		default:
-			typ0 = getTypeOfExpr(rhs0)
+	var args []ast.Expr
+	for _, arg := range call.Args {
+		if !isConstOperand(arg) {
+			arg = newThunkTmp(th, arg)
 		}
+		args = append(args, arg)
+	}
 
-		if typ0 != nil && typ0.E != nil {
-		} else {
-			panic("type inference is not supported")
-		}
-		obj0 := s.Lhs[0].(*ast.Ident).Obj
-		setVariable(obj0, registerLocalVariable(currentFunc, obj0.Name, typ0))
-	} else {
-		walkExpr(s.Rhs[0])
+	th.Lit = &ast.FuncLit{
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ExprStmt{
+					X: &ast.CallExpr{
+						Fun:      fun,
+						Args:     args,
+						Ellipsis: call.Ellipsis,
+					},
+				},
+			},
+		},
 	}
-}
-func walkReturnStmt(s *ast.ReturnStmt) {
-	s.Node = &ast.NodeReturnStmt{
-		Fnc: currentFunc,
+	walkFuncLit(th.Lit)
+	return th
+}
+
+// x.M as a value is a closure which calls the method on x evaluated now:
+//   tmp := x; func(p0 P0, ...) R { return tmp.M(p0, ...) }
+// The method expression T.M takes the receiver as the first parameter:
+//   func(p0 T, p1 P1, ...) R { return p0.M(p1, ...) }
+func walkMethodValue(e *ast.SelectorExpr) *ast.CallThunk {
+	th := &ast.CallThunk{}
+	funcType := getMethodSelectorType(e)
+	var params []*ast.Field
+	var args []ast.Expr
+	for i, field := range funcType.Params.List {
+		param := newParamField(".p"+strconv.Itoa(i), field.Type)
+		params = append(params, param)
+		args = append(args, &ast.Ident{
+			Name: param.Name.Name,
+			Obj:  param.Name.Obj,
+		})
 	}
-	for _, rt := range s.Results {
-		walkExpr(rt)
+	var rcv ast.Expr
+	if isType(e.X) {
+		rcv = args[0]
+		args = args[1:]
+	} else {
+		rcv = newThunkTmp(th, methodReceiver(e))
 	}
-}
-func walkIfStmt(s *ast.IfStmt) {
-	if s.Init != nil {
-		walkStmt(s.Init)
+	call := &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   rcv,
+			Sel: &ast.Ident{Name: e.Sel.Name},
+		},
+		Args: args,
 	}
-	walkExpr(s.Cond)
-	for _, s := range s.Body.List {
-		walkStmt(s)
+	if len(params) > 0 {
+		_, isVariadic := params[len(params)-1].Type.(*ast.Ellipsis)
+		if isVariadic {
+			call.Ellipsis = 1
+		}
 	}
-	if s.Else != nil {
-		walkStmt(s.Else)
+	var body ast.Stmt
+	if funcType.Results == nil || len(funcType.Results.List) == 0 {
+		body = &ast.ExprStmt{X: call}
+	} else {
+		body = &ast.ReturnStmt{Results: []ast.Expr{call}}
 	}
+	th.Lit = &ast.FuncLit{
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{List: params},
+			Results: funcType.Results,
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{body},
+		},
+	}
+	walkFuncLit(th.Lit)
+	return th
 }
-func walkForStmt(s *ast.ForStmt) {
-	s.Outer = currentFor
-	currentFor = s
-	if s.Init != nil {
-		walkStmt(s.Init)
+
+// the receiver which x.M binds: &x for a pointer method of an addressable x,
+// *x for a value method of a pointer x, and x otherwise
+func methodReceiver(e *ast.SelectorExpr) ast.Expr {
+	t := getTypeOfExpr(e.X)
+	if isInterface(t) {
+		return e.X
 	}
-	if s.Cond != nil {
-		walkExpr(s.Cond)
+	method := lookupMethod(t, e.Sel)
+	if method.IsPtrMethod && kind(t) != T_POINTER {
+		// x.M is (&x).M
+		ident, isIdent := e.X.(*ast.Ident)
+		if isIdent && ident.Obj.Variable != nil {
+			// the pointer may outlive the frame in a goroutine
+			ident.Obj.Variable.IsEscaped = true
+		}
+		return &ast.UnaryExpr{
+			Op: token.Token("&"),
+			X:  e.X,
+		}
 	}
-	if s.Post != nil {
-		walkStmt(s.Post)
+	if !method.IsPtrMethod && kind(t) == T_POINTER {
+		// x.M is (*x).M, which copies *x now
+		return &ast.StarExpr{
+			X: e.X,
+		}
 	}
-	walkStmt(newStmt(s.Body))
-	currentFor = s.Outer
+	return e.X
 }
-func walkRangeStmt(s *ast.RangeStmt) {
-	walkExpr(s.X)
-	s.Outer = currentFor
-	currentFor = s
-	var _s = s.Body
-	walkStmt(_s)
-	var lenvar = registerLocalVariable(currentFunc, ".range.len", tInt)
-	var indexvar = registerLocalVariable(currentFunc, ".range.index", tInt)
 
-	if s.Tok.String() == ":=" {
-		listType := getTypeOfExpr(s.X)
+func newParamField(name string, typ ast.Expr) *ast.Field {
+	field := &ast.Field{
+		Name: &ast.Ident{Name: name},
+		Type: typ,
+	}
+	field.Name.Obj = &ast.Object{
+		Kind: ast.Var,
+		Name: name,
+		Decl: field,
+	}
+	return field
+}
 
-		keyIdent := expr2Ident(s.Key)
-		//@TODO map key can be any type
-		//keyType := getKeyTypeOfListType(listType)
-		var keyType *Type = tInt
-		setVariable(keyIdent.Obj, registerLocalVariable(currentFunc, keyIdent.Name, keyType))
+// store the value of e in a hidden local variable
+func newThunkTmp(th *ast.CallThunk, e ast.Expr) ast.Expr {
+	walkExpr(e)
+	vr := registerLocalVariable(currentFunc, ".thunk.tmp", getTypeOfExpr(e))
+	th.Tmps = append(th.Tmps, vr)
+	th.TmpExprs = append(th.TmpExprs, e)
+	return &ast.Ident{
+		Name: vr.Name,
+		Obj: &ast.Object{
+			Kind:     ast.Var,
+			Name:     vr.Name,
+			Variable: vr,
+		},
+	}
+}
 
-		// determine type of Value
-		elmType := getElementTypeOfListType(listType)
-		valueIdent := expr2Ident(s.Value)
-		setVariable(valueIdent.Obj, registerLocalVariable(currentFunc, valueIdent.Name, elmType))
+// types and constants need not be evaluated in advance
+func isConstOperand(e ast.Expr) bool {
+	switch x := e.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return x.Obj.Kind != ast.Var
 	}
-	s.Lenvar = lenvar
-	s.Indexvar = indexvar
-	currentFor = s.Outer
+	return isType(e)
 }
-func walkIncDecStmt(s *ast.IncDecStmt) {
-	walkExpr(s.X)
+
+func walkSendStmt(s *ast.SendStmt) {
+	walkExpr(s.Chan)
+	walkExpr(s.Value)
 }
-func walkBlockStmt(s *ast.BlockStmt) {
-	for _, _s := range s.List {
-		walkStmt(_s)
+
+// The channels and the values to send are evaluated once before choosing a case.
+// They are stored in hidden local variables.
+func walkSelectStmt(s *ast.SelectStmt) {
+	breakables = append(breakables, s)
+	for _, stmt := range s.Body.List {
+		cc := stmt.(*ast.CommClause)
+		if cc.Comm != nil {
+			walkStmt(cc.Comm)
+		}
+		switch comm := cc.Comm.(type) {
+		case *ast.SendStmt:
+			chanType := getTypeOfExpr(comm.Chan)
+			comm.Chan = newSelectTmp(s, comm.Chan, chanType)
+			comm.Value = newSelectTmp(s, comm.Value, getElementTypeOfListType(chanType))
+		case *ast.ExprStmt:
+			recv := comm.X.(*ast.UnaryExpr)
+			recv.X = newSelectTmp(s, recv.X, getTypeOfExpr(recv.X))
+		case *ast.AssignStmt:
+			recv := comm.Rhs[0].(*ast.UnaryExpr)
+			recv.X = newSelectTmp(s, recv.X, getTypeOfExpr(recv.X))
+		}
+		for _, _s := range cc.Body {
+			walkStmt(_s)
+		}
 	}
+	breakables = breakables[:len(breakables)-1]
 }
-func walkBranchStmt(s *ast.BranchStmt) {
-	s.CurrentFor = currentFor
+
+func newSelectTmp(s *ast.SelectStmt, e ast.Expr, t *Type) ast.Expr {
+	vr := registerLocalVariable(currentFunc, ".select.tmp", t)
+	s.Tmps = append(s.Tmps, vr)
+	s.TmpExprs = append(s.TmpExprs, e)
+	return &ast.Ident{
+		Name: vr.Name,
+		Obj: &ast.Object{
+			Kind:     ast.Var,
+			Name:     vr.Name,
+			Variable: vr,
+		},
+	}
 }
+
 func walkSwitchStmt(s *ast.SwitchStmt) {
 	if s.Tag != nil {
 		walkExpr(s.Tag)
 	}
+	cases := s.Body.List
+	for i, c := range cases {
+		if hasFallthrough(stmt2CaseClause(c)) {
+			if i == len(cases)-1 {
+				panic("cannot fallthrough final case in switch")
+			}
+			body := stmt2CaseClause(c).Body
+			body[len(body)-1].(*ast.BranchStmt).Target = cases[i+1]
+		}
+	}
+	breakables = append(breakables, s)
 	walkStmt(s.Body)
+	breakables = breakables[:len(breakables)-1]
+}
+
+// fallthrough is allowed only as the last statement of a case
+func hasFallthrough(cc *ast.CaseClause) bool {
+	if len(cc.Body) == 0 {
+		return false
+	}
+	branchStmt, ok := cc.Body[len(cc.Body)-1].(*ast.BranchStmt)
+	return ok && branchStmt.Tok.String() == "fallthrough"
 }
 func walkTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 	typeSwitch := &ast.NodeTypeSwitchStmt{}
 	s.Node = typeSwitch
+	breakables = append(breakables, s)
 	var assignIdent *ast.Ident
 	switch s2 := s.Assign.(type) {
 	case *ast.ExprStmt:
@@ -3172,6 +7421,7 @@ func walkTypeSwitchStmt(s *ast.TypeSwitchStmt) {
 			assignIdent.Obj.Variable = nil
 		}
 	}
+	breakables = breakables[:len(breakables)-1]
 }
 func walkCaseClause(s *ast.CaseClause) {
 	for _, e_ := range s.List {
@@ -3209,6 +7459,16 @@ func walkStmt(stmt ast.Stmt) {
 		walkCaseClause(s)
 	case *ast.BranchStmt:
 		walkBranchStmt(s)
+	case *ast.LabeledStmt:
+		walkLabeledStmt(s)
+	case *ast.DeferStmt:
+		walkDeferStmt(s)
+	case *ast.GoStmt:
+		walkGoStmt(s)
+	case *ast.SendStmt:
+		walkSendStmt(s)
+	case *ast.SelectStmt:
+		walkSelectStmt(s)
 	default:
 		throw(stmt)
 	}
@@ -3216,10 +7476,98 @@ func walkStmt(stmt ast.Stmt) {
 
 var currentFor ast.Stmt
 
+// loops, switches and selects enclosing the statement being walked
+var breakables []ast.Stmt
+
 func walkIdent(e *ast.Ident) {
+	if e.Obj == nil || e.Obj.Kind != ast.Var || e.Obj.Variable == nil {
+		return
+	}
+	vr := e.Obj.Variable
+	if vr.IsGlobal {
+		return
+	}
+	// a local variable of an outer func is captured by every func literal in between
+	for fnc := currentFunc; fnc != nil && !isLocalVariableOf(fnc, vr); fnc = fnc.Outer {
+		vr.IsEscaped = true
+		if getCaptureIndex(fnc, vr) < 0 {
+			fnc.Captures = append(fnc.Captures, vr)
+		}
+	}
+}
+
+func isLocalVariableOf(fnc *ast.Func, vr *Variable) bool {
+	for _, v := range fnc.Params {
+		if v == vr {
+			return true
+		}
+	}
+	for _, v := range fnc.Retvars {
+		if v == vr {
+			return true
+		}
+	}
+	for _, v := range fnc.Vars {
+		if v == vr {
+			return true
+		}
+	}
+	return false
+}
+
+func walkFuncLit(e *ast.FuncLit) {
+	outerFunc := currentFunc
+	outerFor := currentFor
+	outerBreakables := breakables
+	var outerName string = "glob"
+	if outerFunc != nil {
+		outerName = outerFunc.Name
+	}
+	currentPkg.funcLitIndex++
+	fnc := &ast.Func{
+		Name:      fmt.Sprintf("%s.func%d", outerName, currentPkg.funcLitIndex),
+		FuncType:  e.Type,
+		Localarea: 0,
+		Argsarea:  16,
+		Outer:     outerFunc,
+	}
+	e.Func = fnc
+	currentFunc = fnc
+	currentFor = nil
+	breakables = nil
+
+	var resultFields []*ast.Field
+	if e.Type.Results != nil {
+		resultFields = e.Type.Results.List
+	}
+	registerParamsAndResults(fnc, e.Type.Params.List, resultFields)
+	for _, stmt := range e.Body.List {
+		walkStmt(stmt)
+	}
+	resolveGotos(fnc, e.Body)
+	checkUnusedLabels(fnc)
+	if len(fnc.Captures) > 0 {
+		fnc.ClosureVar = registerLocalVariable(fnc, ".closure", tUintptr)
+	}
+	registerBoxes(fnc)
+	fnc.Body = e.Body
+	currentPkg.funcs = append(currentPkg.funcs, fnc)
+
+	currentFunc = outerFunc
+	currentFor = outerFor
+	breakables = outerBreakables
+}
+
+func walkFuncType(e *ast.FuncType) {
 }
 func walkCallExpr(e *ast.CallExpr) {
-	walkExpr(e.Fun)
+	sel, isSel := e.Fun.(*ast.SelectorExpr)
+	if isSel && !isType(sel.X) {
+		// x.M() calls the method without making a method value
+		walkSelectorExpr(sel)
+	} else {
+		walkExpr(e.Fun)
+	}
 	// Replace __func__ ident by a string literal
 	var basicLit *ast.BasicLit
 	var newArg ast.Expr
@@ -3241,6 +7589,7 @@ func walkCallExpr(e *ast.CallExpr) {
 func walkBasicLit(e *ast.BasicLit) {
 	switch e.Kind.String() {
 	case "INT":
+	case "FLOAT":
 	case "CHAR":
 	case "STRING":
 		registerStringLiteral(e)
@@ -3282,6 +7631,22 @@ func walkStarExpr(e *ast.StarExpr) {
 }
 func walkSelectorExpr(e *ast.SelectorExpr) {
 	walkExpr(e.X)
+	if isQI(e) || isType(e.X) {
+		return
+	}
+	// x.f where f is promoted from an embedded field E is rewritten to x.E.f
+	var embedded, ambiguous = lookupEmbeddedPath(getTypeOfExpr(e.X), e.Sel.Name)
+	if ambiguous {
+		panic("ambiguous selector " + serializeExpr(e))
+	}
+	for _, field := range embedded {
+		e.X = &ast.SelectorExpr{
+			X: e.X,
+			Sel: &ast.Ident{
+				Name: getFieldName(field),
+			},
+		}
+	}
 }
 
 // []T(e)
@@ -3295,6 +7660,12 @@ func walkKeyValueExpr(e *ast.KeyValueExpr) {
 	walkExpr(e.Key)
 	walkExpr(e.Value)
 }
+func walkMapType(e *ast.MapType) {
+	// first argument of make(). Nothing to do.
+}
+func walkChanType(e *ast.ChanType) {
+	// first argument of make(). Nothing to do.
+}
 func walkInterfaceType(e *ast.InterfaceType) {
 	// interface{}(e)  conversion. Nothing to do.
 }
@@ -3324,6 +7695,10 @@ func walkExpr(expr ast.Expr) {
 		walkStarExpr(e)
 	case *ast.SelectorExpr:
 		walkSelectorExpr(e)
+		if isMethodSelector(e) {
+			// x.M or T.M as a value
+			e.Thunk = walkMethodValue(e)
+		}
 	case *ast.ArrayType:
 		walkArrayType(e) // []T(e)
 	case *ast.ParenExpr:
@@ -3332,8 +7707,16 @@ func walkExpr(expr ast.Expr) {
 		walkKeyValueExpr(e)
 	case *ast.InterfaceType:
 		walkInterfaceType(e)
+	case *ast.MapType:
+		walkMapType(e)
+	case *ast.ChanType:
+		walkChanType(e)
 	case *ast.TypeAssertExpr:
 		walkTypeAssertExpr(e)
+	case *ast.FuncLit:
+		walkFuncLit(e)
+	case *ast.FuncType:
+		walkFuncType(e)
 	default:
 		panic("TBI:" + dtypeOf(expr))
 	}
@@ -3392,22 +7775,24 @@ func walk(pkg *PkgContainer) {
 	var typeSpecs []*ast.TypeSpec
 	var funcDecls []*ast.FuncDecl
 	var varSpecs []*ast.ValueSpec
-	var constSpecs []*ast.ValueSpec
+	var constDecls []*ast.GenDecl
 
 	for _, decl := range pkg.Decls {
 		switch dcl := decl.(type) {
 		case *ast.GenDecl:
-			switch spec := dcl.Spec.(type) {
-			case *ast.TypeSpec:
-				typeSpecs = append(typeSpecs, spec)
-			case *ast.ValueSpec:
-				if spec.Name.Obj.Kind == ast.Var {
-					varSpecs = append(varSpecs, spec)
-				} else if spec.Name.Obj.Kind == ast.Con {
-					constSpecs = append(constSpecs, spec)
-				} else {
-					panic("Unexpected")
+			switch dcl.Tok {
+			case token.TYPE:
+				for _, spec := range dcl.Specs {
+					typeSpecs = append(typeSpecs, spec.(*ast.TypeSpec))
+				}
+			case token.VAR:
+				for _, spec := range dcl.Specs {
+					varSpecs = append(varSpecs, spec.(*ast.ValueSpec))
 				}
+			case token.CONST:
+				constDecls = append(constDecls, dcl)
+			default:
+				panic("Unexpected")
 			}
 		case *ast.FuncDecl:
 			funcDecls = append(funcDecls, dcl)
@@ -3456,27 +7841,40 @@ func walk(pkg *PkgContainer) {
 		}
 	}
 
-	for _, constSpec := range constSpecs {
-		walkExpr(constSpec.Values[0])
+	for _, constDecl := range constDecls {
+		walkConstDecl(constDecl)
+	}
+	for _, constDecl := range constDecls {
+		evalConstDecl(constDecl)
+	}
+	for _, constDecl := range constDecls {
+		for _, spec := range constDecl.Specs {
+			for _, nameIdent := range spec.(*ast.ValueSpec).Names {
+				exportEntry := &exportEntry{
+					qi:  newQI(pkg.name, nameIdent.Name),
+					any: nameIdent,
+				}
+				ExportedQualifiedIdents = append(ExportedQualifiedIdents, exportEntry)
+			}
+		}
 	}
 
+	currentFunc = nil
 	for _, valSpec := range varSpecs {
-		var nameIdent = valSpec.Name
-		assert(nameIdent.Obj.Kind == ast.Var, "should be Var", __func__)
-		if valSpec.Type == nil {
-			var val = valSpec.Values[0]
-			var t = getTypeOfExpr(val)
-			valSpec.Type = t.E
-		}
-		setVariable(nameIdent.Obj, newGlobalVariable(pkg.name, nameIdent.Obj.Name, e2t(valSpec.Type)))
-		pkg.vars = append(pkg.vars, valSpec)
-		exportEntry := &exportEntry{
-			qi:  newQI(pkg.name, nameIdent.Name),
-			any: nameIdent,
+		assert(len(valSpec.Values) == 0 || len(valSpec.Values) == len(valSpec.Names), "TBI: global variables of multi-value expressions", __func__)
+		types := getTypesOfValueSpec(valSpec)
+		for i, nameIdent := range valSpec.Names {
+			assert(nameIdent.Obj.Kind == ast.Var, "should be Var", __func__)
+			setVariable(nameIdent.Obj, newGlobalVariable(pkg.name, nameIdent.Obj.Name, types[i]))
+			exportEntry := &exportEntry{
+				qi:  newQI(pkg.name, nameIdent.Name),
+				any: nameIdent,
+			}
+			ExportedQualifiedIdents = append(ExportedQualifiedIdents, exportEntry)
 		}
-		ExportedQualifiedIdents = append(ExportedQualifiedIdents, exportEntry)
-		if len(valSpec.Values) > 0 {
-			walkExpr(valSpec.Values[0])
+		pkg.vars = append(pkg.vars, valSpec)
+		for _, v := range valSpec.Values {
+			walkExpr(v)
 		}
 	}
 
@@ -3506,24 +7904,15 @@ func walk(pkg *PkgContainer) {
 			}
 		}
 
-		for _, field := range paramFields {
-			obj := field.Name.Obj
-			setVariable(obj, registerParamVariable(fnc, obj.Name, e2t(field.Type)))
-		}
-
-		for i, field := range resultFields {
-			if field.Name == nil {
-				// unnamed retval
-				registerReturnVariable(fnc, ".r"+strconv.Itoa(i), e2t(field.Type))
-			} else {
-				panic("TBI: named return variable is not supported")
-			}
-		}
+		registerParamsAndResults(fnc, paramFields, resultFields)
 
 		if funcDecl.Body != nil {
 			for _, stmt := range funcDecl.Body.List {
 				walkStmt(stmt)
 			}
+			resolveGotos(fnc, funcDecl.Body)
+			checkUnusedLabels(fnc)
+			registerBoxes(fnc)
 			fnc.Body = funcDecl.Body
 
 			if funcDecl.Recv != nil { // Method
@@ -3556,6 +7945,10 @@ var gFalse = &ast.Object{
 	Kind: ast.Con,
 	Name: "false",
 }
+var gIota = &ast.Object{
+	Kind: ast.Con,
+	Name: "iota",
+}
 
 var gString = &ast.Object{
 	Kind: ast.Typ,
@@ -3567,11 +7960,31 @@ var gInt = &ast.Object{
 	Name: "int",
 }
 
+var gInt8 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "int8",
+}
+
+var gInt16 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "int16",
+}
+
 var gInt32 = &ast.Object{
 	Kind: ast.Typ,
 	Name: "int32",
 }
 
+var gInt64 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "int64",
+}
+
+var gUint = &ast.Object{
+	Kind: ast.Typ,
+	Name: "uint",
+}
+
 var gUint8 = &ast.Object{
 	Kind: ast.Typ,
 	Name: "uint8",
@@ -3581,6 +7994,26 @@ var gUint16 = &ast.Object{
 	Kind: ast.Typ,
 	Name: "uint16",
 }
+
+var gUint32 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "uint32",
+}
+
+var gUint64 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "uint64",
+}
+
+var gFloat32 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "float32",
+}
+
+var gFloat64 = &ast.Object{
+	Kind: ast.Typ,
+	Name: "float64",
+}
 var gUintptr = &ast.Object{
 	Kind: ast.Typ,
 	Name: "uintptr",
@@ -3604,6 +8037,11 @@ var gAppend = &ast.Object{
 	Name: "append",
 }
 
+var gCopy = &ast.Object{
+	Kind: ast.Fun,
+	Name: "copy",
+}
+
 var gLen = &ast.Object{
 	Kind: ast.Fun,
 	Name: "len",
@@ -3617,12 +8055,37 @@ var gPanic = &ast.Object{
 	Kind: ast.Fun,
 	Name: "panic",
 }
+var gDelete = &ast.Object{
+	Kind: ast.Fun,
+	Name: "delete",
+}
+var gRecover = &ast.Object{
+	Kind: ast.Fun,
+	Name: "recover",
+}
+var gClose = &ast.Object{
+	Kind: ast.Fun,
+	Name: "close",
+}
+var gMin = &ast.Object{
+	Kind: ast.Fun,
+	Name: "min",
+}
+var gMax = &ast.Object{
+	Kind: ast.Fun,
+	Name: "max",
+}
+var gClear = &ast.Object{
+	Kind: ast.Fun,
+	Name: "clear",
+}
 
 var tInt *Type
 var tInt32 *Type // Rune
 var tUint8 *Type
 var tUint16 *Type
 var tUintptr *Type
+var tFloat64 *Type
 var tString *Type
 var tEface *Type
 var tBool *Type
@@ -3630,7 +8093,8 @@ var generalSlice ast.Expr
 
 func isPredeclaredType(obj *ast.Object) bool {
 	switch obj {
-	case gUintptr, gInt, gInt32, gString, gUint8, gUint16, gBool:
+	case gUintptr, gInt, gInt8, gInt16, gInt32, gInt64, gString,
+		gUint, gUint8, gUint16, gUint32, gUint64, gFloat32, gFloat64, gBool:
 		return true
 	}
 	return false
@@ -3642,11 +8106,13 @@ func createUniverse() *ast.Scope {
 	objects := []*ast.Object{
 		gNil,
 		// constants
-		gTrue, gFalse,
+		gTrue, gFalse, gIota,
 		// types
-		gString, gUintptr, gBool, gInt, gUint8, gUint16,
+		gString, gUintptr, gBool, gInt, gInt8, gInt16, gInt32, gInt64,
+		gUint, gUint8, gUint16, gUint32, gUint64, gFloat32, gFloat64,
 		// funcs
-		gNew, gMake, gAppend, gLen, gCap, gPanic,
+		gNew, gMake, gAppend, gCopy, gLen, gCap, gPanic, gDelete, gRecover, gClose,
+		gMin, gMax, gClear,
 	}
 	for _, obj := range objects {
 		universe.Insert(obj)
@@ -3657,11 +8123,16 @@ func createUniverse() *ast.Scope {
 		Name: "byte",
 		Obj:  gUint8,
 	})
+	universe.Objects = append(universe.Objects, &ast.ObjectEntry{
+		Name: "rune",
+		Obj:  gInt32,
+	})
 
 	return universe
 }
 
 // --- builder ---
+var fset *token.FileSet
 var currentPkg *PkgContainer
 
 type PkgContainer struct {
@@ -3673,6 +8144,7 @@ type PkgContainer struct {
 	funcs          []*ast.Func
 	stringLiterals []*stringLiteralsContainer
 	stringIndex    int
+	funcLitIndex   int
 	Decls          []ast.Decl
 }
 
@@ -3718,7 +8190,8 @@ func isStdLib(pth string) bool {
 }
 
 func getImportPathsFromFile(file string) []string {
-	astFile0 := parseImports(file)
+	fset := token.NewFileSet()
+	astFile0 := parseImports(fset, file)
 	var importPaths []string
 	for _, importSpec := range astFile0.Imports {
 		rawValue := importSpec.Path
@@ -3760,9 +8233,10 @@ func removeLeafNode(tree []*depEntry, sortedPaths []string) []*depEntry {
 	return newTree
 }
 
+// A node is a leaf once all of its children are sorted, including the ones found earlier in the same pass.
 func collectLeafNode(sortedPaths []string, tree []*depEntry) []string {
 	for _, entry := range tree {
-		if len(entry.children) == 0 {
+		if isLeafNode(entry, sortedPaths) {
 			// leaf node
 			logf("Found leaf node: %s\n", entry.path)
 			logf("  num children: %d\n", len(entry.children))
@@ -3772,6 +8246,15 @@ func collectLeafNode(sortedPaths []string, tree []*depEntry) []string {
 	return sortedPaths
 }
 
+func isLeafNode(entry *depEntry, sortedPaths []string) bool {
+	for _, child := range entry.children {
+		if !mylib.InArray(child, sortedPaths) {
+			return false
+		}
+	}
+	return true
+}
+
 func sortDepTree(tree []*depEntry) []string {
 	var sortedPaths []string
 
@@ -3906,7 +8389,7 @@ func buildPackage(_pkg *PkgContainer, universe *ast.Scope) {
 	pkgScope := ast.NewScope(universe)
 	for _, file := range _pkg.files {
 		logf("Parsing file: %s\n", file)
-		af := parseFile(file, false)
+		af := parseFile(fset, file, false)
 		_pkg.name = af.Name
 		_pkg.astFiles = append(_pkg.astFiles, af)
 		for _, oe := range af.Scope.Objects {
@@ -3951,12 +8434,12 @@ func buildPackage(_pkg *PkgContainer, universe *ast.Scope) {
 func showHelp() {
 	fmt.Printf("Usage:\n")
 	fmt.Printf("    babygo version:  show version\n")
-	fmt.Printf("    babygo [-DF] [-DG] filename\n")
+	fmt.Printf("    babygo [-DF] [-DG] [-B] filename\n")
 }
 
 func main() {
 	srcPath = os.Getenv("GOPATH") + "/src"
-	prjSrcPath = srcPath + "/github.com/DQNEO/babygo/src/"
+	prjSrcPath = srcPath + "/github.com/DQNEO/babygo/src"
 
 	if len(os.Args) == 1 {
 		showHelp()
@@ -4013,6 +8496,12 @@ func main() {
 			Obj:  gUintptr,
 		},
 	}
+	tFloat64 = &Type{
+		E: &ast.Ident{
+			Name: "float64",
+			Obj:  gFloat64,
+		},
+	}
 
 	tString = &Type{
 		E: &ast.Ident{
@@ -4040,6 +8529,8 @@ func main() {
 			debugFrontEnd = true
 		case "-DG":
 			debugCodeGen = true
+		case "-B":
+			disableBoundsCheck = true
 		default:
 			inputFiles = append(inputFiles, arg)
 		}
@@ -4060,6 +8551,7 @@ func main() {
 		files: inputFiles,
 	})
 
+	fset = token.NewFileSet()
 	var universe = createUniverse()
 	for _, _pkg := range packagesToBuild {
 		currentPkg = _pkg
@@ -4067,6 +8559,9 @@ func main() {
 	}
 
 	emitDynamicTypes(typeMap)
+	emitGlobalRoots()
+	emitFuncTable()
+	emitLineTable()
 }
 
 func obj2var(obj *ast.Object) *Variable {
@@ -27,9 +27,9 @@ func readFile(filename string) []uint8 {
 	return readbytes
 }
 
-func (p *parser) init(src []uint8) {
+func (p *parser) init(file *token.File, src []uint8) {
 	var s = p.scanner
-	s.Init(src)
+	s.Init(file, src)
 	p.next()
 }
 
@@ -186,12 +186,62 @@ func (p *parser) parseArrayType() ast.Expr {
 	})
 }
 
+func (p *parser) parseMapType() ast.Expr {
+	p.expect("map", __func__)
+	p.expect("[", __func__)
+	var keyType = p.parseType()
+	p.expect("]", __func__)
+	var valueType = p.parseType()
+	return (&ast.MapType{
+		Key:   keyType,
+		Value: valueType,
+	})
+}
+
+// chan T, chan<- T or <-chan T
+func (p *parser) parseChanType() ast.Expr {
+	var dir = ast.SEND + ast.RECV
+	if p.tok.tok == "chan" {
+		p.next() // consume "chan"
+		if p.tok.tok == "<-" {
+			p.next() // consume "<-"
+			dir = ast.SEND
+		}
+	} else {
+		p.expect("<-", __func__)
+		p.expect("chan", __func__)
+		dir = ast.RECV
+	}
+	var value = p.parseType()
+	return (&ast.ChanType{
+		Dir:   dir,
+		Value: value,
+	})
+}
+
+func (p *parser) parseFuncType() *ast.FuncType {
+	p.expect("func", __func__)
+	var scope = ast.NewScope(p.topScope) // function scope
+	var sig = p.parseSignature(scope)
+	return &ast.FuncType{
+		Params:  sig.Params,
+		Results: sig.Results,
+	}
+}
+
 func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 
 	var varType = p.parseVarType(false)
 	var typ = p.tryVarType(false)
 
 	p.expectSemi(__func__)
+	if typ == nil {
+		// embedded field: T, *T or pkg.T
+		p.resolve(varType)
+		return &ast.Field{
+			Type: varType,
+		}
+	}
 	ident := expr2Ident(varType)
 	var field = &ast.Field{
 		Type: typ,
@@ -223,6 +273,59 @@ func (p *parser) parseStructType() ast.Expr {
 	})
 }
 
+func (p *parser) parseMethodSpec() *ast.Field {
+	var ident = p.parseIdent()
+	var field *ast.Field
+	if p.tok.tok == "(" {
+		// method
+		var scope = ast.NewScope(p.topScope) // function scope
+		var sig = p.parseSignature(scope)
+		field = &ast.Field{
+			Name: ident,
+			Type: &ast.FuncType{
+				Params:  sig.Params,
+				Results: sig.Results,
+			},
+		}
+	} else {
+		// embedded interface
+		var typ ast.Expr = ident
+		if p.tok.tok == "." {
+			p.next() // consume "."
+			p.resolve(ident)
+			typ = &ast.SelectorExpr{
+				X:   ident,
+				Sel: p.parseIdent(),
+			}
+		} else {
+			p.resolve(typ)
+		}
+		field = &ast.Field{
+			Type: typ,
+		}
+	}
+	p.expectSemi(__func__)
+	return field
+}
+
+func (p *parser) parseInterfaceType() ast.Expr {
+	p.expect("interface", __func__)
+	p.expect("{", __func__)
+
+	var list []*ast.Field
+	for p.tok.tok == "IDENT" {
+		var field *ast.Field = p.parseMethodSpec()
+		list = append(list, field)
+	}
+	p.expect("}", __func__)
+
+	return (&ast.InterfaceType{
+		Methods: &ast.FieldList{
+			List: list,
+		},
+	})
+}
+
 func (p *parser) parseTypeName() ast.Expr {
 	logf(" [%s] begin\n", __func__)
 	var ident = p.parseIdent()
@@ -253,14 +356,14 @@ func (p *parser) tryIdentOrType() ast.Expr {
 		return p.parseStructType()
 	case "*":
 		return p.parsePointerType()
+	case "map":
+		return p.parseMapType()
+	case "chan", "<-":
+		return p.parseChanType()
+	case "func":
+		return p.parseFuncType()
 	case "interface":
-		p.next()
-		p.expect("{", __func__)
-		// @TODO parser method sets
-		p.expect("}", __func__)
-		return (&ast.InterfaceType{
-			Methods: nil,
-		})
+		return p.parseInterfaceType()
 	case "(":
 		p.next()
 		var _typ = p.parseType()
@@ -470,7 +573,7 @@ func (p *parser) parseOperand() ast.Expr {
 		p.tryResolve(eIdent, true)
 		logf("   end %s\n", __func__)
 		return eIdent
-	case "INT", "STRING", "CHAR":
+	case "INT", "FLOAT", "STRING", "CHAR":
 		var basicLit = &ast.BasicLit{
 			Kind:  token.Token(p.tok.tok),
 			Value: p.tok.lit,
@@ -487,6 +590,8 @@ func (p *parser) parseOperand() ast.Expr {
 		return (&ast.ParenExpr{
 			X: x,
 		})
+	case "func":
+		return p.parseFuncTypeOrLit()
 	}
 
 	var typ = p.tryIdentOrType()
@@ -498,12 +603,35 @@ func (p *parser) parseOperand() ast.Expr {
 	return typ
 }
 
+func (p *parser) parseFuncTypeOrLit() ast.Expr {
+	p.expect("func", __func__)
+	var scope = ast.NewScope(p.topScope) // function scope
+	var sig = p.parseSignature(scope)
+	var typ = &ast.FuncType{
+		Params:  sig.Params,
+		Results: sig.Results,
+	}
+	if p.tok.tok != "{" {
+		return typ
+	}
+
+	var exprLev = parserExprLev
+	parserExprLev = 0
+	var body = p.parseBody(scope)
+	parserExprLev = exprLev
+	return (&ast.FuncLit{
+		Type: typ,
+		Body: body,
+	})
+}
+
 func (p *parser) parseRhsOrType() ast.Expr {
 	var x = p.parseExpr()
 	return x
 }
 
 func (p *parser) parseCallExpr(fn ast.Expr) ast.Expr {
+	var lparen = p.tok.pos
 	p.expect("(", __func__)
 	logf(" [parsePrimaryExpr] p.tok.tok=%s\n", p.tok.tok)
 	var list []ast.Expr
@@ -530,6 +658,7 @@ func (p *parser) parseCallExpr(fn ast.Expr) ast.Expr {
 	p.expect(")", __func__)
 	return (&ast.CallExpr{
 		Fun:      fn,
+		Lparen:   lparen,
 		Args:     list,
 		Ellipsis: ellipsis,
 	})
@@ -660,7 +789,7 @@ func isLiteralType(expr ast.Expr) bool {
 		return isExprIdent(e.X)
 	case *ast.ArrayType:
 	case *ast.StructType:
-	//case *ast.MapType:
+	case *ast.MapType:
 	default:
 		return false
 	}
@@ -669,6 +798,7 @@ func isLiteralType(expr ast.Expr) bool {
 }
 
 func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
+	var lbrack = p.tok.pos
 	p.expect("[", __func__)
 	var index = make([]ast.Expr, 3, 3)
 	if p.tok.tok != ":" {
@@ -689,6 +819,7 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
 		var sliceExpr = &ast.SliceExpr{
 			Slice3: false,
 			X:      x,
+			Lbrack: lbrack,
 			Low:    index[0],
 			High:   index[1],
 		}
@@ -700,6 +831,7 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
 
 	var indexExpr = &ast.IndexExpr{}
 	indexExpr.X = x
+	indexExpr.Lbrack = lbrack
 	indexExpr.Index = index[0]
 	return (indexExpr)
 }
@@ -708,14 +840,16 @@ func (p *parser) parseUnaryExpr() ast.Expr {
 	var r ast.Expr
 	logf("   begin parseUnaryExpr()\n")
 	switch p.tok.tok {
-	case "+", "-", "!", "&":
+	case "+", "-", "!", "^", "&":
+		var pos = p.tok.pos
 		var tok = p.tok.tok
 		p.next()
 		var x = p.parseUnaryExpr()
 		logf(" [DEBUG] unary op = %s\n", tok)
 		r = (&ast.UnaryExpr{
-			X:  x,
-			Op: token.Token(tok),
+			OpPos: pos,
+			X:     x,
+			Op:    token.Token(tok),
 		})
 		return r
 	case "*":
@@ -725,6 +859,22 @@ func (p *parser) parseUnaryExpr() ast.Expr {
 			X: x,
 		})
 		return r
+	case "<-":
+		var pos = p.tok.pos
+		p.next() // consume "<-"
+		if p.tok.tok == "chan" {
+			// <-chan T
+			var chanType = p.parseChanType().(*ast.ChanType)
+			chanType.Dir = ast.RECV
+			return chanType
+		}
+		var x = p.parseUnaryExpr()
+		r = (&ast.UnaryExpr{
+			OpPos: pos,
+			X:     x,
+			Op:    token.Token("<-"),
+		})
+		return r
 	}
 	r = p.parsePrimaryExpr()
 	logf("   end parseUnaryExpr()\n")
@@ -741,9 +891,9 @@ func precedence(op string) int {
 		return 2
 	case "==", "!=", "<", "<=", ">", ">=":
 		return 3
-	case "+", "-":
+	case "+", "-", "|", "^":
 		return 4
-	case "*", "/", "%":
+	case "*", "/", "%", "<<", ">>", "&", "&^":
 		return 5
 	default:
 		return 0
@@ -801,6 +951,7 @@ func makeExpr(s ast.Stmt) ast.Expr {
 
 func (p *parser) parseForStmt() ast.Stmt {
 	logf(" begin %s\n", __func__)
+	var pos = p.tok.pos
 	p.expect("for", __func__)
 	p.openScope()
 
@@ -857,6 +1008,7 @@ func (p *parser) parseForStmt() ast.Stmt {
 
 		rangeX = expr2UnaryExpr(as.Rhs[0]).X
 		var rangeStmt = &ast.RangeStmt{}
+		rangeStmt.For = pos
 		rangeStmt.Key = key
 		rangeStmt.Value = value
 		rangeStmt.X = rangeX
@@ -867,6 +1019,7 @@ func (p *parser) parseForStmt() ast.Stmt {
 		return newStmt(rangeStmt)
 	}
 	var forStmt = &ast.ForStmt{}
+	forStmt.For = pos
 	forStmt.Init = s1
 	forStmt.Cond = makeExpr(s2)
 	forStmt.Post = s3
@@ -877,6 +1030,7 @@ func (p *parser) parseForStmt() ast.Stmt {
 }
 
 func (p *parser) parseIfStmt() ast.Stmt {
+	var pos = p.tok.pos
 	p.expect("if", __func__)
 	parserExprLev = -1
 	var condStmt ast.Stmt = p.parseSimpleStmt(false)
@@ -898,6 +1052,7 @@ func (p *parser) parseIfStmt() ast.Stmt {
 		p.expectSemi(__func__)
 	}
 	var ifStmt = &ast.IfStmt{}
+	ifStmt.If = pos
 	ifStmt.Cond = cond
 	ifStmt.Body = body
 	ifStmt.Else = else_
@@ -944,6 +1099,7 @@ func isTypeSwitchGuard(stmt ast.Stmt) bool {
 }
 
 func (p *parser) parseSwitchStmt() ast.Stmt {
+	var pos = p.tok.pos
 	p.expect("switch", __func__)
 	p.openScope()
 
@@ -971,17 +1127,56 @@ func (p *parser) parseSwitchStmt() ast.Stmt {
 	p.closeScope()
 	if typeSwitch {
 		return newStmt(&ast.TypeSwitchStmt{
+			Switch: pos,
 			Assign: s2,
 			Body:   body,
 		})
 	} else {
 		return newStmt(&ast.SwitchStmt{
-			Body: body,
-			Tag:  makeExpr(s2),
+			Switch: pos,
+			Body:   body,
+			Tag:    makeExpr(s2),
 		})
 	}
 }
 
+func (p *parser) parseCommClause() *ast.CommClause {
+	p.openScope()
+	var comm ast.Stmt
+	if p.tok.tok == "case" {
+		p.next() // consume "case"
+		comm = p.parseSimpleStmt(false)
+	} else {
+		p.expect("default", __func__)
+	}
+	p.expect(":", __func__)
+	var body = p.parseStmtList()
+	var r = &ast.CommClause{}
+	r.Comm = comm
+	r.Body = body
+	p.closeScope()
+	return r
+}
+
+func (p *parser) parseSelectStmt() ast.Stmt {
+	var pos = p.tok.pos
+	p.expect("select", __func__)
+	p.expect("{", __func__)
+	var list []ast.Stmt
+	for p.tok.tok == "case" || p.tok.tok == "default" {
+		var cc = p.parseCommClause()
+		list = append(list, newStmt(cc))
+	}
+	p.expect("}", __func__)
+	p.expectSemi(__func__)
+	var body = &ast.BlockStmt{}
+	body.List = list
+	return newStmt(&ast.SelectStmt{
+		Select: pos,
+		Body:   body,
+	})
+}
+
 func (p *parser) parseLhsList() []ast.Expr {
 	logf(" [%s] start\n", __func__)
 	var list = p.parseExprList()
@@ -992,13 +1187,14 @@ func (p *parser) parseLhsList() []ast.Expr {
 func (p *parser) parseSimpleStmt(isRangeOK bool) ast.Stmt {
 	logf(" begin %s\n", __func__)
 	var x = p.parseLhsList()
+	pos := p.tok.pos
 	stok := p.tok.tok
 	var isRange = false
 	var y ast.Expr
 	var rangeX ast.Expr
 	var rangeUnary *ast.UnaryExpr
 	switch stok {
-	case ":=", "=", "+=", "-=":
+	case ":=", "=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<=", ">>=", "&^=":
 		var assignToken = stok
 		p.next() // consume =
 		if isRangeOK && p.tok.tok == "range" {
@@ -1013,6 +1209,7 @@ func (p *parser) parseSimpleStmt(isRangeOK bool) ast.Stmt {
 			y = p.parseExpr() // rhs
 		}
 		var as = &ast.AssignStmt{}
+		as.TokPos = pos
 		as.Tok = token.Token(assignToken)
 		as.Lhs = x
 		as.Rhs = make([]ast.Expr, 1, 1)
@@ -1033,12 +1230,20 @@ func (p *parser) parseSimpleStmt(isRangeOK bool) ast.Stmt {
 		exprStmt.X = x[0]
 		logf(" parseSimpleStmt end ; %s\n", __func__)
 		return newStmt(exprStmt)
+	case "<-":
+		p.next() // consume "<-"
+		var sendStmt = &ast.SendStmt{}
+		sendStmt.Chan = x[0]
+		sendStmt.Arrow = pos
+		sendStmt.Value = p.parseExpr()
+		return newStmt(sendStmt)
 	}
 
 	switch stok {
 	case "++", "--":
 		var sInc = &ast.IncDecStmt{}
 		sInc.X = x[0]
+		sInc.TokPos = pos
 		sInc.Tok = token.Token(stok)
 		p.next() // consume "++" or "--"
 		return newStmt(sInc)
@@ -1054,25 +1259,41 @@ func (p *parser) parseStmt() ast.Stmt {
 	logf(" = begin %s\n", __func__)
 	var s ast.Stmt
 	switch p.tok.tok {
-	case "var":
-		var genDecl = p.parseDecl("var")
+	case "var", "const":
+		var genDecl = p.parseDecl(p.tok.tok)
 		s = newStmt(&ast.DeclStmt{
 			Decl: genDecl,
 		})
 		logf(" = end parseStmt()\n")
-	case "IDENT", "*":
+	case
+		// operands
+		"IDENT", "INT", "FLOAT", "CHAR", "STRING", "func", "(",
+		// composite types
+		"[", "struct", "map", "chan", "interface",
+		// unary operators
+		"+", "-", "*", "&", "^", "<-", "!":
 		s = p.parseSimpleStmt(false)
-		p.expectSemi(__func__)
+		if p.tok.tok == ":" {
+			s = p.parseLabeledStmt(s)
+		} else {
+			p.expectSemi(__func__)
+		}
 	case "return":
 		s = p.parseReturnStmt()
-	case "break", "continue":
+	case "break", "continue", "goto", "fallthrough":
 		s = p.parseBranchStmt(p.tok.tok)
 	case "if":
 		s = p.parseIfStmt()
 	case "switch":
 		s = p.parseSwitchStmt()
+	case "select":
+		s = p.parseSelectStmt()
 	case "for":
 		s = p.parseForStmt()
+	case "defer":
+		s = p.parseDeferStmt()
+	case "go":
+		s = p.parseGoStmt()
 	default:
 		panic2(__func__, "TBI 3:"+p.tok.tok)
 	}
@@ -1101,16 +1322,46 @@ func (p *parser) parseRhsList() []ast.Expr {
 }
 
 func (p *parser) parseBranchStmt(tok string) ast.Stmt {
+	var pos = p.tok.pos
 	p.expect(tok, __func__)
-
-	p.expectSemi(__func__)
-
 	var branchStmt = &ast.BranchStmt{}
+	branchStmt.TokPos = pos
 	branchStmt.Tok = token.Token(tok)
+	if tok != "fallthrough" && p.tok.tok == "IDENT" {
+		branchStmt.Label = p.parseIdent().Name
+	}
+	if tok == "goto" && branchStmt.Label == "" {
+		panic2(__func__, "label expected after goto")
+	}
+
+	p.expectSemi(__func__)
 	return newStmt(branchStmt)
 }
 
+// L: stmt
+func (p *parser) parseLabeledStmt(x ast.Stmt) ast.Stmt {
+	exprStmt, ok := x.(*ast.ExprStmt)
+	if !ok || !isExprIdent(exprStmt.X) {
+		panic2(__func__, "label must be an identifier")
+	}
+	p.expect(":", __func__)
+	var stmt ast.Stmt
+	if p.tok.tok == "}" {
+		// a label at the end of a block labels an empty statement
+		stmt = &ast.BlockStmt{}
+	} else {
+		stmt = p.parseStmt()
+	}
+	return newStmt(&ast.LabeledStmt{
+		Label: &ast.Ident{
+			Name: expr2Ident(exprStmt.X).Name,
+		},
+		Stmt: stmt,
+	})
+}
+
 func (p *parser) parseReturnStmt() ast.Stmt {
+	var pos = p.tok.pos
 	p.expect("return", __func__)
 	var x []ast.Expr
 	if p.tok.tok != ";" && p.tok.tok != "}" {
@@ -1118,10 +1369,41 @@ func (p *parser) parseReturnStmt() ast.Stmt {
 	}
 	p.expectSemi(__func__)
 	var returnStmt = &ast.ReturnStmt{}
+	returnStmt.Return = pos
 	returnStmt.Results = x
 	return newStmt(returnStmt)
 }
 
+func (p *parser) parseDeferStmt() ast.Stmt {
+	var pos = p.tok.pos
+	p.expect("defer", __func__)
+	var x = p.parseExpr()
+	p.expectSemi(__func__)
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		panic2(__func__, "expression in defer must be function call")
+	}
+	var deferStmt = &ast.DeferStmt{}
+	deferStmt.Defer = pos
+	deferStmt.Call = call
+	return newStmt(deferStmt)
+}
+
+func (p *parser) parseGoStmt() ast.Stmt {
+	var pos = p.tok.pos
+	p.expect("go", __func__)
+	var x = p.parseExpr()
+	p.expectSemi(__func__)
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		panic2(__func__, "expression in go must be function call")
+	}
+	var goStmt = &ast.GoStmt{}
+	goStmt.Go = pos
+	goStmt.Call = call
+	return newStmt(goStmt)
+}
+
 func (p *parser) parseStmtList() []ast.Stmt {
 	var list []ast.Stmt
 	for p.tok.tok != "}" && p.tok.tok != "EOF" && p.tok.tok != "case" && p.tok.tok != "default" {
@@ -1132,6 +1414,7 @@ func (p *parser) parseStmtList() []ast.Stmt {
 }
 
 func (p *parser) parseBody(scope *ast.Scope) *ast.BlockStmt {
+	var lbrace = p.tok.pos
 	p.expect("{", __func__)
 	p.topScope = scope
 	logf(" begin parseStmtList()\n")
@@ -1141,11 +1424,13 @@ func (p *parser) parseBody(scope *ast.Scope) *ast.BlockStmt {
 	p.closeScope()
 	p.expect("}", __func__)
 	var r = &ast.BlockStmt{}
+	r.Lbrace = lbrace
 	r.List = list
 	return r
 }
 
 func (p *parser) parseBlockStmt() *ast.BlockStmt {
+	var lbrace = p.tok.pos
 	p.expect("{", __func__)
 	p.openScope()
 	logf(" begin parseStmtList()\n")
@@ -1154,42 +1439,44 @@ func (p *parser) parseBlockStmt() *ast.BlockStmt {
 	p.closeScope()
 	p.expect("}", __func__)
 	var r = &ast.BlockStmt{}
+	r.Lbrace = lbrace
 	r.List = list
 	return r
 }
 
+// var, const and type declarations with or without parentheses
 func (p *parser) parseDecl(keyword string) *ast.GenDecl {
-	var r *ast.GenDecl
-	switch p.tok.tok {
-	case "var":
-		p.expect(keyword, __func__)
-		var ident = p.parseIdent()
-		var typ = p.parseType()
-		var value ast.Expr
-		if p.tok.tok == "=" {
-			p.next()
-			value = p.parseExpr()
+	var pos = p.tok.pos
+	p.expect(keyword, __func__)
+	var specs []ast.Spec
+	if p.tok.tok == "(" {
+		p.next()
+		var iota int
+		for p.tok.tok != ")" && p.tok.tok != "EOF" {
+			specs = append(specs, p.parseSpec(keyword, iota))
+			iota++
 		}
+		p.expect(")", __func__)
 		p.expectSemi(__func__)
-		var valSpec = &ast.ValueSpec{}
-		valSpec.Name = ident
-		valSpec.Type = typ
-		if value != nil {
-			valSpec.Values = append(valSpec.Values, value)
-		}
-		declare(valSpec, p.topScope, ast.Var, ident)
-		r = &ast.GenDecl{}
-		r.Spec = valSpec
-		return r
-	default:
-		panic2(__func__, "TBI\n")
+	} else {
+		specs = append(specs, p.parseSpec(keyword, 0))
+	}
+	return &ast.GenDecl{
+		TokPos: pos,
+		Tok:    token.Token(keyword),
+		Specs:  specs,
 	}
-	return r
+}
+
+func (p *parser) parseSpec(keyword string, iota int) ast.Spec {
+	if keyword == "type" {
+		return p.parserTypeSpec()
+	}
+	return p.parseValueSpec(keyword, iota)
 }
 
 func (p *parser) parserTypeSpec() *ast.TypeSpec {
 	logf(" [%s] start\n", __func__)
-	p.expect("type", __func__)
 	var ident = p.parseIdent()
 	logf(" decl type %s\n", ident.Name)
 
@@ -1208,29 +1495,38 @@ func (p *parser) parserTypeSpec() *ast.TypeSpec {
 	return spec
 }
 
-func (p *parser) parseValueSpec(keyword string) *ast.ValueSpec {
+// iota is the index of the spec in a const declaration
+func (p *parser) parseValueSpec(keyword string, iota int) *ast.ValueSpec {
 	logf(" [parserValueSpec] start\n")
-	p.expect(keyword, __func__)
-	var ident = p.parseIdent()
-	logf(" var = %s\n", ident.Name)
+	var idents []*ast.Ident
+	for {
+		var ident = p.parseIdent()
+		logf(" var = %s\n", ident.Name)
+		idents = append(idents, ident)
+		if p.tok.tok != "," {
+			break
+		}
+		p.next()
+	}
 	var typ = p.parseType()
-	var value ast.Expr
+	var values []ast.Expr
 	if p.tok.tok == "=" {
 		p.next()
-		value = p.parseExpr()
+		values = p.parseRhsList()
 	}
 	p.expectSemi(__func__)
 	var spec = &ast.ValueSpec{}
-	spec.Name = ident
+	spec.Names = idents
 	spec.Type = typ
-	if value != nil {
-		spec.Values = append(spec.Values, value)
-	}
+	spec.Values = values
+	spec.Iota = iota
 	var kind = ast.Con
 	if keyword == "var" {
 		kind = ast.Var
 	}
-	declare(spec, p.topScope, kind, ident)
+	for _, ident := range idents {
+		declare(spec, p.topScope, kind, ident)
+	}
 	logf(" [parserValueSpec] end\n")
 	return spec
 }
@@ -1314,21 +1610,12 @@ func (p *parser) parseFile(importsOnly bool) *ast.File {
 
 	for !importsOnly && p.tok.tok != "EOF" {
 		switch p.tok.tok {
-		case "var", "const":
-			var spec = p.parseValueSpec(p.tok.tok)
-			var genDecl = &ast.GenDecl{}
-			genDecl.Spec = spec
-			decl = genDecl
+		case "var", "const", "type":
+			decl = p.parseDecl(p.tok.tok)
 		case "func":
 			logf("\n\n")
 			decl = p.parseFuncDecl()
 			//logf(" func decl parsed:%s\n", decl.funcDecl.Name.Name)
-		case "type":
-			var spec = p.parserTypeSpec()
-			var genDecl = &ast.GenDecl{}
-			genDecl.Spec = spec
-			decl = genDecl
-			logf(" type parsed:%s\n", "")
 		default:
 			panic2(__func__, "TBI:"+p.tok.tok)
 		}
@@ -1369,19 +1656,19 @@ func (p *parser) parseFile(importsOnly bool) *ast.File {
 	return f
 }
 
-func parseImports(filename string) *ast.File {
-	return parseFile(filename, true)
+func parseImports(fset *token.FileSet, filename string) *ast.File {
+	return parseFile(fset, filename, true)
 }
 
 func readSource(filename string) []uint8 {
 	return readFile(filename)
 }
 
-func parseFile(filename string, importsOnly bool) *ast.File {
+func parseFile(fset *token.FileSet, filename string, importsOnly bool) *ast.File {
 	var text = readSource(filename)
 
 	var p = &parser{}
 	p.scanner = &scanner{}
-	p.init(text)
+	p.init(fset.AddFile(filename, -1, len(text)), text)
 	return p.parseFile(importsOnly)
 }
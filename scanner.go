@@ -3,9 +3,11 @@ package main
 import (
 	"github.com/DQNEO/babygo/lib/mylib"
 	"github.com/DQNEO/babygo/lib/strconv"
+	"github.com/DQNEO/babygo/lib/token"
 )
 
 type scanner struct {
+	file       *token.File
 	src        []uint8
 	ch         uint8
 	offset     int
@@ -16,6 +18,9 @@ type scanner struct {
 func (s *scanner) next() {
 	if s.nextOffset < len(s.src) {
 		s.offset = s.nextOffset
+		if s.ch == '\n' {
+			s.file.AddLine(s.offset)
+		}
 		s.ch = s.src[s.offset]
 		s.nextOffset++
 	} else {
@@ -26,7 +31,7 @@ func (s *scanner) next() {
 
 var keywords []string
 
-func (s *scanner) Init(src []uint8) {
+func (s *scanner) Init(file *token.File, src []uint8) {
 	// https://golang.org/ref/spec#Keywords
 	keywords = []string{
 		"break", "default", "func", "interface", "select",
@@ -35,6 +40,7 @@ func (s *scanner) Init(src []uint8) {
 		"const", "fallthrough", "if", "range", "type",
 		"continue", "for", "import", "return", "var",
 	}
+	s.file = file
 	s.src = src
 	s.offset = 0
 	s.ch = ' '
@@ -43,8 +49,9 @@ func (s *scanner) Init(src []uint8) {
 	s.next()
 }
 
+// Bytes of non-ASCII characters are taken as letters.
 func isLetter(ch uint8) bool {
-	if ch == '_' {
+	if ch == '_' || ch >= 128 {
 		return true
 	}
 	return ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z')
@@ -54,6 +61,10 @@ func isDecimal(ch uint8) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHex(ch uint8) bool {
+	return isDecimal(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
 func (s *scanner) scanIdentifier() string {
 	var offset = s.offset
 	for isLetter(s.ch) || isDecimal(s.ch) {
@@ -62,12 +73,45 @@ func (s *scanner) scanIdentifier() string {
 	return string(s.src[offset:s.offset])
 }
 
-func (s *scanner) scanNumber() string {
+func (s *scanner) scanDigits(base int) {
+	for s.ch == '_' || (base == 16 && isHex(s.ch)) || (base != 16 && isDecimal(s.ch)) {
+		s.next()
+	}
+}
+
+// integer or decimal floating-point literal
+// https://golang.org/ref/spec#Integer_literals
+func (s *scanner) scanNumber() (string, string) {
 	var offset = s.offset
-	for isDecimal(s.ch) {
+	var tok = "INT"
+	if s.ch == '0' {
 		s.next()
+		switch s.ch {
+		case 'x', 'X':
+			s.next()
+			s.scanDigits(16)
+			return string(s.src[offset:s.offset]), tok
+		case 'b', 'B', 'o', 'O':
+			s.next()
+			s.scanDigits(10)
+			return string(s.src[offset:s.offset]), tok
+		}
 	}
-	return string(s.src[offset:s.offset])
+	s.scanDigits(10)
+	if s.ch == '.' {
+		tok = "FLOAT"
+		s.next()
+		s.scanDigits(10)
+	}
+	if s.ch == 'e' || s.ch == 'E' {
+		tok = "FLOAT"
+		s.next()
+		if s.ch == '+' || s.ch == '-' {
+			s.next()
+		}
+		s.scanDigits(10)
+	}
+	return string(s.src[offset:s.offset]), tok
 }
 
 func (s *scanner) scanString() string {
@@ -87,6 +131,20 @@ func (s *scanner) scanString() string {
 	return string(s.src[offset:s.offset])
 }
 
+// https://golang.org/ref/spec#String_literals
+func (s *scanner) scanRawString() string {
+	// '`' opening already consumed
+	var offset = s.offset - 1
+	for s.ch != '`' {
+		if s.ch == 1 {
+			panic2(__func__, "raw string literal not terminated")
+		}
+		s.next()
+	}
+	s.next() // consume ending '`'
+	return string(s.src[offset:s.offset])
+}
+
 func (s *scanner) scanChar() string {
 	// '\'' opening already consumed
 	var offset = s.offset - 1
@@ -113,10 +171,38 @@ func (s *scanner) scanComment() string {
 	return string(s.src[offset:s.offset])
 }
 
+func (s *scanner) scanBlockComment() string {
+	// '/' opening already consumed
+	var offset = s.offset - 1
+	s.next() // consume '*'
+	for !(s.ch == '*' && s.nextOffset < len(s.src) && s.src[s.nextOffset] == '/') {
+		if s.ch == 1 {
+			panic2(__func__, "comment not terminated")
+		}
+		s.next()
+	}
+	s.next()
+	s.next()
+	return string(s.src[offset:s.offset])
+}
+
+// reports whether the block comment starting at the current '*' contains a newline
+func (s *scanner) blockCommentHasNewline() bool {
+	for i := s.nextOffset; i+1 < len(s.src); i++ {
+		if s.src[i] == '*' && s.src[i+1] == '/' {
+			return false
+		}
+		if s.src[i] == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
 type TokenContainer struct {
-	pos int    // what's this ?
-	tok string // token.Token
-	lit string // raw data
+	pos token.Pos // the position of the first character
+	tok string    // token.Token
+	lit string    // raw data
 }
 
 // https://golang.org/ref/spec#Tokens
@@ -129,6 +215,7 @@ func (s *scanner) skipWhitespace() {
 func (s *scanner) Scan() *TokenContainer {
 	s.skipWhitespace()
 	var tc = &TokenContainer{}
+	tc.pos = s.file.Pos(s.offset)
 	var lit string
 	var tok string
 	var insertSemi bool
@@ -145,10 +232,9 @@ func (s *scanner) Scan() *TokenContainer {
 			insertSemi = true
 			tok = "IDENT"
 		}
-	} else if isDecimal(ch) {
+	} else if isDecimal(ch) || (ch == '.' && isDecimal(s.src[s.nextOffset])) {
 		insertSemi = true
-		lit = s.scanNumber()
-		tok = "INT"
+		lit, tok = s.scanNumber()
 	} else {
 		s.next()
 		switch ch {
@@ -160,6 +246,10 @@ func (s *scanner) Scan() *TokenContainer {
 			insertSemi = true
 			lit = s.scanString()
 			tok = "STRING"
+		case '`': // back quote
+			insertSemi = true
+			lit = s.scanRawString()
+			tok = "STRING"
 		case '\'': // single quote
 			insertSemi = true
 			lit = s.scanChar()
@@ -239,9 +329,15 @@ func (s *scanner) Scan() *TokenContainer {
 				tok = "*"
 			}
 		case '/':
-			if s.ch == '/' {
+			if s.ch == '/' || s.ch == '*' {
 				// comment
-				// @TODO block comment
+				// A block comment without newlines is like a space, and otherwise it acts like a newline.
+				if s.ch == '*' && !s.blockCommentHasNewline() {
+					lit = s.scanBlockComment()
+					tc.lit = lit
+					tc.tok = "COMMENT"
+					return tc
+				}
 				if s.insertSemi {
 					s.ch = '/'
 					s.offset = s.offset - 1
@@ -251,9 +347,14 @@ func (s *scanner) Scan() *TokenContainer {
 					s.insertSemi = false
 					return tc
 				}
-				lit = s.scanComment()
+				if s.ch == '*' {
+					lit = s.scanBlockComment()
+				} else {
+					lit = s.scanComment()
+				}
 				tok = "COMMENT"
 			} else if s.ch == '=' {
+				s.next()
 				tok = "/="
 			} else {
 				tok = "/"
@@ -365,7 +466,6 @@ func (s *scanner) Scan() *TokenContainer {
 		}
 	}
 	tc.lit = lit
-	tc.pos = 0
 	tc.tok = tok
 	s.insertSemi = insertSemi
 	return tc
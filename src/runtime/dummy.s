@@ -6,15 +6,3 @@ TEXT	 runtime·Write(SB), NOSPLIT
 
 TEXT	 runtime·Syscall(SB), NOSPLIT
     RET
-
-TEXT	 runtime·makeSlice1(SB), NOSPLIT
-    RET
-
-TEXT	 runtime·makeSlice8(SB), NOSPLIT
-    RET
-
-TEXT	 runtime·makeSlice16(SB), NOSPLIT
-    RET
-
-TEXT	 runtime·makeSlice24(SB), NOSPLIT
-    RET
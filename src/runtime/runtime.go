@@ -3,13 +3,6 @@ package runtime
 
 import "unsafe"
 
-const heapSize uintptr = 620205360
-
-var heapHead uintptr
-var heapCurrent uintptr
-var heapTail uintptr
-
-const SYS_BRK int = 12
 const SYS_EXIT int = 60
 
 var __argv__ []*uint8 // C argv
@@ -25,12 +18,6 @@ type envEntry struct {
 
 var Envs []*envEntry
 
-func heapInit() {
-	heapHead = brk(0)
-	heapTail = brk(heapHead + heapSize)
-	heapCurrent = heapHead
-}
-
 // Inital stack layout is illustrated in this page
 // http://asm.sourceforge.net/articles/startup.html#st
 func envInit() {
@@ -99,215 +86,2499 @@ func runtime_args() []string {
 	return r
 }
 
-func brk(addr uintptr) uintptr {
-	var ret uintptr
-	ret = Syscall(uintptr(SYS_BRK), addr, uintptr(0), uintptr(0))
-	return ret
+// a deferred call
+type _defer struct {
+	fn    func()
+	rbp   uintptr // frame of the function which executed the defer statement
+	depth int     // calls from fn to the deferred function, including the call of fn
+	link  *_defer
 }
 
-func panic(ifc interface{}) {
-	switch x := ifc.(type) {
-	case string:
-		var s = "panic: " + x + "\n\n"
-		Write(2, []uint8(s))
-		Syscall(uintptr(SYS_EXIT), 1, uintptr(0), uintptr(0))
-	default:
-		var s = "panic: " + "Unknown type" + "\n\n"
-		Write(2, []uint8(s))
-		Syscall(uintptr(SYS_EXIT), 1, uintptr(0), uintptr(0))
+type _panic struct {
+	arg       interface{}
+	recovered bool
+	fp        uintptr // frame of runtime.panic, which runs the deferred calls
+	depth     int     // depth of the deferred call running
+	link      *_panic
+}
+
+var deferStack *_defer
+var panicStack *_panic
+
+func deferproc(fn func(), rbp uintptr, depth int) {
+	var d *_defer = new(_defer)
+	d.fn = fn
+	d.rbp = rbp
+	d.depth = depth
+	d.link = deferStack
+	deferStack = d
+}
+
+// run the deferred calls of the frame in LIFO order
+func deferreturn(rbp uintptr) {
+	for deferStack != nil && deferStack.rbp == rbp {
+		var d *_defer = deferStack
+		deferStack = d.link
+		d.fn()
 	}
 }
 
-func memzeropad(addr1 uintptr, size uintptr) {
-	var p *uint8 = (*uint8)(unsafe.Pointer(addr1))
-	var isize int = int(size)
+// recover stops the panicking only when it is called directly by a deferred function
+func recover() interface{} {
+	if panicStack == nil || panicStack.recovered {
+		return nil
+	}
+	var fp uintptr = *(*uintptr)(unsafe.Pointer(getfp())) // frame of the caller
 	var i int
-	var up uintptr
-	for i = 0; i < isize; i++ {
-		*p = 0
-		up = uintptr(unsafe.Pointer(p)) + 1
-		p = (*uint8)(unsafe.Pointer(up))
+	for i = 0; i < panicStack.depth; i++ {
+		fp = *(*uintptr)(unsafe.Pointer(fp))
 	}
+	if fp != panicStack.fp {
+		return nil
+	}
+	panicStack.recovered = true
+	curg.sig = 0
+	return panicStack.arg
 }
 
-func memcopy(src uintptr, dst uintptr, length int) {
-	var i int
-	var srcp *uint8
-	var dstp *uint8
-	for i = 0; i < length; i++ {
-		srcp = (*uint8)(unsafe.Pointer(src + uintptr(i)))
-		dstp = (*uint8)(unsafe.Pointer(dst + uintptr(i)))
-		*dstp = *srcp
+// return to the caller of the frame. (implemented in asm)
+func returnFrom(rbp uintptr)
+
+func panic(ifc interface{}) {
+	var p *_panic = new(_panic)
+	p.arg = ifc
+	p.fp = getfp()
+	p.link = panicStack
+	panicStack = p
+	for deferStack != nil {
+		var d *_defer = deferStack
+		deferStack = d.link
+		p.depth = d.depth
+		d.fn()
+		if p.recovered {
+			panicStack = p.link
+			// the function which deferred d returns normally
+			deferreturn(d.rbp)
+			returnFrom(d.rbp)
+		}
+	}
+
+	var s = printpanics(p)
+	if curg.sig != 0 {
+		s = s + sigdesc(curg)
 	}
+	s = s + "\n" + traceback(getfp())
+	Write(2, []uint8(s))
+	Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
 }
 
-func malloc(size uintptr) uintptr {
-	if heapCurrent+size > heapTail {
-		Write(2, []uint8("malloc exceeded heap max"))
-		Syscall(uintptr(SYS_EXIT), 1, uintptr(0), uintptr(0))
-		return 0
+// the chain of panics from the first one, each raised while running the deferred calls of the previous one
+func printpanics(p *_panic) string {
+	var s string
+	if p.link != nil {
+		s = printpanics(p.link) + "\t"
 	}
-	var r uintptr
-	r = heapCurrent
-	heapCurrent = heapCurrent + size
-	memzeropad(r, size)
-	return r
+	s = s + "panic: " + printpanicval(p.arg)
+	if p.recovered {
+		s = s + " [recovered]"
+	}
+	return s + "\n"
 }
 
-func makeSlice(elmSize int, slen int, scap int) (uintptr, int, int) {
-	var size uintptr = uintptr(elmSize * scap)
-	var addr uintptr = malloc(size)
-	return addr, slen, scap
+// the methods which print panic values
+type errorer interface {
+	Error() string
 }
 
-func append1(old []uint8, elm uint8) (uintptr, int, int) {
-	var new_ []uint8
-	var elmSize int = 1
+type stringer interface {
+	String() string
+}
 
-	var oldlen int = len(old)
-	var newlen int = oldlen + 1
+// the reflect.Kind of the basic types printed by panic
+const kindBool int = 1
+const kindInt int = 2
+const kindUint int = 7
+const kindUintptr int = 12
+const kindFloat32 int = 13
+const kindFloat64 int = 14
 
-	if cap(old) >= newlen {
-		new_ = old[0:newlen]
-	} else {
-		var newcap int
-		if oldlen == 0 {
-			newcap = 1
+// the representation of a panic value, as Go prints it
+func printpanicval(v interface{}) string {
+	switch x := v.(type) {
+	case errorer:
+		return x.Error()
+	case stringer:
+		return x.String()
+	}
+	var e *eface = (*eface)(unsafe.Pointer(&v))
+	if e.dtype == nil {
+		return "nil"
+	}
+	var t *dtype = e.dtype
+	var s string
+	if t.kind == kindBool {
+		if *(*uint8)(unsafe.Pointer(e.data)) != 0 {
+			s = "true"
 		} else {
-			newcap = oldlen * 2
+			s = "false"
 		}
-		new_ = makeSlice1(elmSize, newlen, newcap)
-		var oldSize int = oldlen * elmSize
-		if oldlen > 0 {
-			memcopy(uintptr(unsafe.Pointer(&old[0])), uintptr(unsafe.Pointer(&new_[0])), oldSize)
+	} else if t.kind >= kindInt && t.kind < kindUint {
+		s = itoa(loadInt(e.data, t.size))
+	} else if t.kind >= kindUint && t.kind <= kindUintptr {
+		s = utoa(loadUint(e.data, t.size))
+	} else if t.kind == kindFloat32 {
+		s = ftoa(float64(*(*float32)(unsafe.Pointer(e.data))), 32)
+	} else if t.kind == kindFloat64 {
+		s = ftoa(*(*float64)(unsafe.Pointer(e.data)), 64)
+	} else if t.kind == kindString {
+		s = *(*string)(unsafe.Pointer(e.data))
+		if isNamedType(t) {
+			s = "\"" + s + "\""
 		}
+	} else {
+		return "(" + t.name + ") " + hex(e.data)
+	}
+	if isNamedType(t) {
+		return t.name + "(" + s + ")"
 	}
+	return s
+}
 
-	new_[oldlen] = elm
-	return uintptr(unsafe.Pointer(&new_[0])), newlen, cap(new_)
+// report whether t is a defined type like "main.T" rather than a predeclared one
+func isNamedType(t *dtype) bool {
+	var i int
+	for i = 0; i < len(t.name); i++ {
+		if t.name[i] == '.' {
+			return true
+		}
+	}
+	return false
 }
 
-func append8(old []int, elm int) (uintptr, int, int) {
-	var new_ []int
-	var elmSize int = 8
+// the shortest decimal representation of f which reads back as a float of bitSize bits,
+// as strconv.FormatFloat(f, 'g', -1, bitSize) returns it
+func ftoa(f float64, bitSize int) string {
+	if f != f {
+		return "NaN"
+	}
+	var sign string
+	if f < 0 || (f == 0 && 1/f < 0) {
+		sign = "-"
+		f = -f
+	}
+	if f > 1.7976931348623157e308 {
+		if sign == "" {
+			return "+Inf"
+		}
+		return "-Inf"
+	}
+	if f == 0 {
+		return sign + "0"
+	}
+	var digits, dp = shortestDigits(f, bitSize)
+	var exp = dp - 1
+	if exp < -4 || exp >= 6 {
+		// d.ddde±dd
+		var s = sign + digits[0:1]
+		if len(digits) > 1 {
+			s = s + "." + digits[1:]
+		}
+		if exp < 0 {
+			s = s + "e-"
+			exp = -exp
+		} else {
+			s = s + "e+"
+		}
+		if exp < 10 {
+			s = s + "0"
+		}
+		return s + itoa(exp)
+	}
+	// ddd.ddd
+	if dp <= 0 {
+		return sign + "0." + zeros(-dp) + digits
+	}
+	if dp >= len(digits) {
+		return sign + digits + zeros(dp-len(digits))
+	}
+	return sign + digits[0:dp] + "." + digits[dp:]
+}
 
-	var oldlen int = len(old)
-	var newlen int = oldlen + 1
+func zeros(n int) string {
+	var s string
+	var i int
+	for i = 0; i < n; i++ {
+		s = s + "0"
+	}
+	return s
+}
 
-	if cap(old) >= newlen {
-		new_ = old[0:newlen]
-	} else {
-		var newcap int
-		if oldlen == 0 {
-			newcap = 1
-		} else {
-			newcap = oldlen * 2
+// the fewest decimal digits of f > 0 without trailing zeros which read back as f,
+// and the position of the decimal point: f ~ 0.digits * 10^dp
+func shortestDigits(f float64, bitSize int) (string, int) {
+	var n int
+	var m int
+	var dp int
+	for n = 1; n <= 17; n++ {
+		m, dp = roundDigits(f, n)
+		var g = scaleByPow10(float64(m), dp-n)
+		if bitSize == 32 {
+			g = float64(float32(g))
+		}
+		if g == f {
+			break
 		}
-		new_ = makeSlice8(elmSize, newlen, newcap)
-		var oldSize int = oldlen * elmSize
-		if oldlen > 0 {
-			memcopy(uintptr(unsafe.Pointer(&old[0])), uintptr(unsafe.Pointer(&new_[0])), oldSize)
+	}
+	for m%10 == 0 {
+		m = m / 10
+	}
+	return itoa(m), dp
+}
+
+// f > 0 rounded to n significant digits as an integer m, and dp such that f ~ m * 10^(dp-n)
+func roundDigits(f float64, n int) (int, int) {
+	var dp int
+	for f >= scaleByPow10(1, dp) {
+		dp++
+	}
+	for f < scaleByPow10(1, dp-1) {
+		dp--
+	}
+	var x = scaleByPow10(f, n-dp)
+	var m = int(x)
+	// round half to even
+	if x-float64(m) > 0.5 || (x-float64(m) == 0.5 && m%2 == 1) {
+		m++
+	}
+	if float64(m) >= scaleByPow10(1, n) {
+		m = m / 10
+		dp++
+	}
+	return m, dp
+}
+
+// x * 10^n
+func scaleByPow10(x float64, n int) float64 {
+	for n > 22 {
+		x = x * 1e22
+		n = n - 22
+	}
+	for n < -22 {
+		x = x / 1e22
+		n = n + 22
+	}
+	var p float64 = 1
+	var i int
+	if n < 0 {
+		for i = 0; i < -n; i++ {
+			p = p * 10
 		}
+		return x / p
+	}
+	for i = 0; i < n; i++ {
+		p = p * 10
+	}
+	return x * p
+}
+
+// load the signed integer of size bytes at addr
+func loadInt(addr uintptr, size int) int {
+	switch size {
+	case 1:
+		return int(*(*int8)(unsafe.Pointer(addr)))
+	case 2:
+		return int(*(*int16)(unsafe.Pointer(addr)))
+	case 4:
+		return int(*(*int32)(unsafe.Pointer(addr)))
 	}
+	return *(*int)(unsafe.Pointer(addr))
+}
 
-	new_[oldlen] = elm
-	return uintptr(unsafe.Pointer(&new_[0])), newlen, cap(new_)
+// load the unsigned integer of size bytes at addr
+func loadUint(addr uintptr, size int) uintptr {
+	switch size {
+	case 1:
+		return uintptr(*(*uint8)(unsafe.Pointer(addr)))
+	case 2:
+		return uintptr(*(*uint16)(unsafe.Pointer(addr)))
+	case 4:
+		return uintptr(*(*uint32)(unsafe.Pointer(addr)))
+	}
+	return *(*uintptr)(unsafe.Pointer(addr))
 }
 
-func append16(old []string, elm string) (uintptr, int, int) {
-	var new_ []string
-	var elmSize int = 16
+// A runtime error is a panic value raised by the runtime, which implements Go's runtime.Error.
+type errorString string
 
-	var oldlen int = len(old)
-	var newlen int = oldlen + 1
+func (e errorString) RuntimeError() {
+}
 
-	if cap(old) >= newlen {
-		new_ = old[0:newlen]
-	} else {
-		var newcap int
-		if oldlen == 0 {
-			newcap = 1
+func (e errorString) Error() string {
+	return "runtime error: " + string(e)
+}
+
+// A runtime error whose message has no "runtime error: " prefix, like Go's plainError.
+type plainError string
+
+func (e plainError) RuntimeError() {
+}
+
+func (e plainError) Error() string {
+	return string(e)
+}
+
+// called when a shift count is negative
+func panicshift() {
+	panic(errorString("negative shift amount"))
+}
+
+// called when a divisor is zero
+func panicdivide() {
+	panic(errorString("integer divide by zero"))
+}
+
+// the kinds of bounds checks. (the compiler depends on the values)
+const boundsIndex int = 0      // s[x]: 0 <= x < len(s)
+const boundsSliceAlen int = 1  // s[:x]: 0 <= x <= len(s)
+const boundsSliceAcap int = 2  // s[:x]: 0 <= x <= cap(s)
+const boundsSliceB int = 3     // s[x:y]: 0 <= x <= y
+const boundsSlice3Alen int = 4 // s[::x]: 0 <= x <= len(s)
+const boundsSlice3Acap int = 5 // s[::x]: 0 <= x <= cap(s)
+const boundsSlice3B int = 6    // s[:x:y]: 0 <= x <= y
+const boundsSlice3C int = 7    // s[x:y:]: 0 <= x <= y
+
+// called when an index or slice expression is out of range.
+// A negative x is reported without y, as Go does.
+func panicBounds(code int, x int, y int) {
+	var xs = itoa(x)
+	var ys = itoa(y)
+	var s string
+	switch code {
+	case boundsIndex:
+		s = "index out of range [" + xs + "]"
+		if x >= 0 {
+			s = s + " with length " + ys
+		}
+	case boundsSliceAlen, boundsSliceAcap:
+		s = "slice bounds out of range [:" + xs + "]"
+	case boundsSliceB:
+		if x < 0 {
+			s = "slice bounds out of range [" + xs + ":]"
+		} else {
+			s = "slice bounds out of range [" + xs + ":" + ys + "]"
+		}
+	case boundsSlice3Alen, boundsSlice3Acap:
+		s = "slice bounds out of range [::" + xs + "]"
+	case boundsSlice3B:
+		if x < 0 {
+			s = "slice bounds out of range [:" + xs + ":]"
+		} else {
+			s = "slice bounds out of range [:" + xs + ":" + ys + "]"
+		}
+	case boundsSlice3C:
+		if x < 0 {
+			s = "slice bounds out of range [" + xs + "::]"
 		} else {
-			newcap = oldlen * 2
+			s = "slice bounds out of range [" + xs + ":" + ys + ":]"
 		}
-		new_ = makeSlice16(elmSize, newlen, newcap)
-		var oldSize int = oldlen * elmSize
-		if oldlen > 0 {
-			memcopy(uintptr(unsafe.Pointer(&old[0])), uintptr(unsafe.Pointer(&new_[0])), oldSize)
+	}
+	if x >= 0 {
+		switch code {
+		case boundsSliceAlen, boundsSlice3Alen:
+			s = s + " with length " + ys
+		case boundsSliceAcap, boundsSlice3Acap:
+			s = s + " with capacity " + ys
 		}
 	}
+	panic(errorString(s))
+}
 
-	new_[oldlen] = elm
-	return uintptr(unsafe.Pointer(&new_[0])), newlen, cap(new_)
+// the decimal representation of x
+func itoa(x int) string {
+	if x < 0 {
+		return "-" + itoa(-x)
+	}
+	var digit = []uint8{uint8('0' + x%10)}
+	if x < 10 {
+		return string(digit)
+	}
+	return itoa(x/10) + string(digit)
 }
 
-func append24(old [][]int, elm []int) (uintptr, int, int) {
-	var new_ [][]int
-	var elmSize int = 24
+func utoa(x uintptr) string {
+	var digit = []uint8{uint8('0' + x%10)}
+	if x < 10 {
+		return string(digit)
+	}
+	return utoa(x/10) + string(digit)
+}
 
-	var oldlen int = len(old)
-	var newlen int = oldlen + 1
+const SIGBUS int = 7
+const SIGSEGV int = 11
 
-	if cap(old) >= newlen {
-		new_ = old[0:newlen]
+// called by runtime.sigtramp on the signal stack
+func sighandler(sig int, info uintptr, ctxt uintptr) {
+	var gp *g = curg
+	gp.sig = sig
+	gp.sigcode = int(*(*int32)(unsafe.Pointer(info + 8)))          // siginfo.si_code
+	gp.sigaddr = *(*uintptr)(unsafe.Pointer(info + 16))            // siginfo.si_addr
+	gp.sigpc = *(*uintptr)(unsafe.Pointer(ctxt + 40 + 16*8))       // ucontext.uc_mcontext.rip
+	var sp uintptr = *(*uintptr)(unsafe.Pointer(ctxt + 40 + 15*8)) // ucontext.uc_mcontext.rsp
+	var overflow bool
+	if gp.stack != 0 {
+		overflow = gp.sigaddr >= gp.stack-stackGuard && gp.sigaddr < gp.stack
 	} else {
-		var newcap int
-		if oldlen == 0 {
-			newcap = 1
-		} else {
-			newcap = oldlen * 2
+		// The kernel grows the stack of the main goroutine up to its limit.
+		overflow = gp.sigaddr < gp.stackHi && gp.sigaddr+stackGuard > sp
+	}
+	if overflow {
+		stackOverflow(gp, sp, *(*uintptr)(unsafe.Pointer(ctxt + 40 + 10*8))) // ucontext.uc_mcontext.rbp
+	}
+}
+
+// report the overflow of the stack of gp, which faulted with the stack pointer sp
+// and the frame pointer fp, and exit.
+func stackOverflow(gp *g, sp uintptr, fp uintptr) {
+	gcoff = true // The stack of gp cannot be scanned from the signal stack.
+	var s string
+	if gp.stack != 0 {
+		s = "runtime: goroutine stack exceeds " + utoa(goroutineStackSize) + "-byte limit\n"
+		s = s + "runtime: sp=" + hex(sp) + " stack=[" + hex(gp.stack) + ", " + hex(gp.stackHi) + "]\n"
+	}
+	s = s + "fatal error: stack overflow\n\n"
+	s = s + "goroutine " + itoa(gp.goid) + " [running]:\n"
+	var n int
+	var name string = funcname(gp.sigpc)
+	if !isRuntimeFunc(name) {
+		s = s + traceFrame(name, gp.sigpc, true)
+		n++
+	}
+	if gp.sigpc == funcentry(gp.sigpc) {
+		// The fault is at the prologue, before the frame pointer is saved.
+		var pc uintptr = *(*uintptr)(unsafe.Pointer(sp))
+		name = funcname(pc)
+		if !isRuntimeFunc(name) {
+			s = s + traceFrame(name, pc, false)
+			n++
 		}
-		new_ = makeSlice24(elmSize, newlen, newcap)
-		var oldSize int = oldlen * elmSize
-		if oldlen > 0 {
-			memcopy(uintptr(unsafe.Pointer(&old[0])), uintptr(unsafe.Pointer(&new_[0])), oldSize)
+	}
+	s = s + tracebackFrames(fp, n)
+	Write(2, []uint8(s))
+	Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+}
+
+// called as if by the faulting instruction, as arranged by runtime.sigtramp
+func sigpanic() {
+	var gp *g = curg
+	if gp.sigaddr < 4096 {
+		panic(errorString("invalid memory address or nil pointer dereference"))
+	}
+	var s = "unexpected fault address " + hex(gp.sigaddr) + "\n" + "fatal error: fault\n" + sigdesc(gp) + "\n"
+	s = s + traceback(getfp())
+	Write(2, []uint8(s))
+	Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+}
+
+// "[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x401000]\n"
+func sigdesc(gp *g) string {
+	var name string
+	if gp.sig == SIGBUS {
+		name = "SIGBUS: bus error"
+	} else {
+		name = "SIGSEGV: segmentation violation"
+	}
+	return "[signal " + name + " code=" + hex(uintptr(gp.sigcode)) + " addr=" + hex(gp.sigaddr) + " pc=" + hex(gp.sigpc) + "]\n"
+}
+
+// return the address of the table of the functions emitted by the compiler. (implemented in asm)
+func getFuncTab() uintptr
+
+// return the address of the line table emitted by the compiler. (implemented in asm)
+func getLineTab() uintptr
+
+// return the frame pointer of the caller. (implemented in asm)
+func getfp() uintptr
+
+// find the last entry of a table of (address, ...) records of size bytes which starts at or before pc.
+// It returns 0 if there is none.
+func findEntry(tab uintptr, size uintptr, pc uintptr) uintptr {
+	var n uintptr = *(*uintptr)(unsafe.Pointer(tab))
+	var found uintptr
+	var entry uintptr
+	var i uintptr
+	for i = 0; i < n; i++ {
+		var e uintptr = tab + 8 + i*size
+		var addr uintptr = *(*uintptr)(unsafe.Pointer(e))
+		if addr <= pc && addr >= entry {
+			entry = addr
+			found = e
 		}
 	}
+	return found
+}
 
-	new_[oldlen] = elm
-	return uintptr(unsafe.Pointer(&new_[0])), newlen, cap(new_)
+// the entry address of the function which contains pc
+func funcentry(pc uintptr) uintptr {
+	var e uintptr = findEntry(getFuncTab(), 16, pc)
+	if e == 0 {
+		return 0
+	}
+	return *(*uintptr)(unsafe.Pointer(e))
 }
 
-func catstrings(a string, b string) string {
-	var totallen = len(a) + len(b)
-	var r = make([]uint8, totallen, totallen+1) // +1 is a workaround for syscall.Open. see runtime.s
-	var i int
-	for i = 0; i < len(a); i = i + 1 {
-		r[i] = a[i]
+// the name of the function which contains pc
+func funcname(pc uintptr) string {
+	var e uintptr = findEntry(getFuncTab(), 16, pc)
+	if e == 0 {
+		return "?"
 	}
-	var j int
-	for j = 0; j < len(b); j = j + 1 {
-		r[i+j] = b[j]
+	var name uintptr = *(*uintptr)(unsafe.Pointer(e + 8))
+	if name == 0 {
+		return "?"
 	}
-	return string(r)
+	return cstring2string((*uint8)(unsafe.Pointer(name)))
 }
 
-func cmpstrings(a string, b string) bool {
-	if len(a) != len(b) {
-		return false
+// the source file and line of the code at pc
+func funcline(pc uintptr) (string, int) {
+	var e uintptr = findEntry(getLineTab(), 24, pc)
+	if e == 0 {
+		return "?", 0
+	}
+	var file uintptr = *(*uintptr)(unsafe.Pointer(e + 8))
+	if file == 0 {
+		return "<autogenerated>", 1
 	}
+	var line uintptr = *(*uintptr)(unsafe.Pointer(e + 16))
+	return cstring2string((*uint8)(unsafe.Pointer(file))), int(line)
+}
+
+// "main.(*T).m" for the symbol "main.$T.m" of a method with a pointer receiver
+func funcPrintName(name string) string {
 	var i int
-	for i = 0; i < len(a); i++ {
-		if a[i] != b[i] {
-			return false
+	var j int
+	for i = 0; i < len(name); i++ {
+		if name[i] == '$' {
+			for j = i + 1; j < len(name); j++ {
+				if name[j] == '.' {
+					return name[:i] + "(*" + name[i+1:j] + ")" + name[j:]
+				}
+			}
 		}
 	}
-	return true
+	return name
 }
 
-// Two interface values are equal if they have identical dynamic types and equal dynamic values or if both have value nil.
-func cmpinterface(a uintptr, b uintptr, c uintptr, d uintptr) bool {
-	if a == c && b == d {
-		return true
+// the stack trace of the current goroutine from the caller of the frame at fp,
+// found by following the saved frame pointers. The frames of the runtime are omitted.
+func traceback(fp uintptr) string {
+	return "goroutine " + itoa(curg.goid) + " [running]:\n" + tracebackFrames(fp, 0)
+}
+
+const tracebackMaxFrames = 100
+
+// the frames of the callers found from fp, after n frames which have been printed
+func tracebackFrames(fp uintptr, n int) string {
+	var s string
+	var faulted bool // pc is the faulting instruction rather than a return address
+	for fp != 0 && fp < curg.stackHi {
+		var pc uintptr = *(*uintptr)(unsafe.Pointer(fp + 8))
+		var next uintptr = *(*uintptr)(unsafe.Pointer(fp))
+		var name string = funcname(pc)
+		if name == "?" {
+			break // the code of runtime.s
+		}
+		if !isRuntimeFunc(name) {
+			if n == tracebackMaxFrames {
+				return s + "...additional frames elided...\n"
+			}
+			s = s + traceFrame(name, pc, faulted)
+			n++
+		}
+		faulted = name == "runtime.sigpanic"
+		if next <= fp {
+			break
+		}
+		fp = next
+	}
+	return s
+}
+
+func isRuntimeFunc(name string) bool {
+	return len(name) >= 8 && name[:8] == "runtime."
+}
+
+// "main.f()\n\t/path/to/file.go:12 +0x1f\n"
+func traceFrame(name string, pc uintptr, faulted bool) string {
+	var linepc uintptr = pc
+	if !faulted {
+		linepc = pc - 1 // the call instruction
+	}
+	var file string
+	var line int
+	file, line = funcline(linepc)
+	return funcPrintName(name) + "()\n" + "\t" + file + ":" + itoa(line) + " +" + hex(pc-funcentry(pc)) + "\n"
+}
+
+// the hexadecimal representation of x, as "0x1f"
+func hex(x uintptr) string {
+	var digits = "0123456789abcdef"
+	var digit = []uint8{digits[x%16]}
+	if x < 16 {
+		return "0x" + string(digit)
+	}
+	return hex(x/16) + string(digit)
+}
+
+// a goroutine
+type g struct {
+	sp         uintptr // saved stack pointer. (must be the first field)
+	fn         func()
+	deferStack *_defer
+	panicStack *_panic
+	schedlink  *g
+	parked     bool
+	ticket     int     // incremented every time the goroutine starts waiting
+	stack      uintptr // the bottom of the stack, above its guard. 0 for the main goroutine
+	stackHi    uintptr // the top of the stack
+	alllink    *g
+	dead       bool
+	sig        int // the signal which caused the panic in progress, if any
+	sigcode    int
+	sigaddr    uintptr
+	sigpc      uintptr
+	goid       int
+}
+
+// The stack of a goroutine is a mapping of its own, below which stackGuard bytes are mapped
+// inaccessible, so that a goroutine which overflows its stack faults instead of overwriting
+// the memory below.
+const goroutineStackSize uintptr = 262144
+const stackGuard uintptr = 65536
+
+const SYS_MPROTECT int = 10
+const protNone int = 0
+
+var curg *g
+var goidgen int
+var allgs *g         // the goroutines whose stacks are scanned by the collector
+var gfree *g         // exited goroutines whose stacks are reused, linked by schedlink
+var stackSys uintptr // bytes mapped for the stacks of the goroutines
+
+var mainStackTop uintptr // set by rt0_go
+
+// run queue
+var runqHead *g
+var runqTail *g
+
+func schedinit() {
+	curg = new(g) // main goroutine
+	goidgen = 1
+	curg.goid = goidgen
+	curg.stackHi = mainStackTop
+	allgs = curg
+}
+
+func runqput(gp *g) {
+	gp.schedlink = nil
+	if runqTail == nil {
+		runqHead = gp
+	} else {
+		runqTail.schedlink = gp
+	}
+	runqTail = gp
+}
+
+func runqget() *g {
+	var gp *g = runqHead
+	if gp == nil {
+		return nil
+	}
+	runqHead = gp.schedlink
+	if runqHead == nil {
+		runqTail = nil
+	}
+	gp.schedlink = nil
+	return gp
+}
+
+// create a goroutine which runs fn
+func newproc(fn func()) {
+	var newg *g = new(g)
+	newg.fn = fn
+	goidgen++
+	newg.goid = goidgen
+	if gfree != nil {
+		newg.stack = gfree.stack
+		gfree = gfree.schedlink
+	} else {
+		newg.stack = stackalloc()
+	}
+	newg.stackHi = newg.stack + goroutineStackSize
+	newg.sp = initStack(newg.stackHi)
+	newg.alllink = allgs
+	allgs = newg
+	runqput(newg)
+}
+
+// map a stack with a guard below it, and return its bottom
+func stackalloc() uintptr {
+	var size uintptr = stackGuard + goroutineStackSize
+	var addr uintptr = mmap(0, size, protReadWrite, mapPrivateAnon, -1, 0)
+	if int(addr) < 0 {
+		Write(2, []uint8("fatal error: runtime: cannot allocate stack\n\n"))
+		Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+	}
+	Syscall(uintptr(SYS_MPROTECT), addr, stackGuard, uintptr(protNone))
+	stackSys = stackSys + size
+	return addr + stackGuard
+}
+
+// set up a stack which starts at gostart on the first switch. (implemented in asm)
+func initStack(top uintptr) uintptr
+
+// save the context into from and resume to. (implemented in asm)
+func gogo(from *g, to *g)
+
+// the entry point of a new goroutine, called from gostart
+func goentry() {
+	var fn func() = curg.fn
+	fn()
+	goexit()
+}
+
+func goexit() {
+	curg.dead = true
+	// The stack is not reused until the next newproc, which runs after schedule has switched away.
+	curg.schedlink = gfree
+	gfree = curg
+	schedule()
+}
+
+const sigStackSize uintptr = 65536
+const SYS_SIGALTSTACK int = 131
+
+var sigstack [3]uintptr // stack_t
+
+// set up the signal stack, so that the signal handler runs even if the stack of a goroutine overflows
+func minit() {
+	sigstack[0] = sysAlloc(sigStackSize) // ss_sp
+	sigstack[2] = sigStackSize           // ss_size
+	Syscall(uintptr(SYS_SIGALTSTACK), uintptr(unsafe.Pointer(&sigstack)), uintptr(0), uintptr(0))
+}
+
+// put the current goroutine to sleep until ready is called
+func park() {
+	curg.parked = true
+	schedule()
+}
+
+func ready(gp *g) {
+	if gp.parked {
+		gp.parked = false
+		runqput(gp)
+	}
+}
+
+// Gosched yields the processor, allowing other goroutines to run.
+func Gosched() {
+	runqput(curg)
+	schedule()
+}
+
+// switch to the next runnable goroutine.
+// The current goroutine must have been queued somewhere or be exiting.
+func schedule() {
+	var gp *g = runqget()
+	if gp == nil {
+		var s = "fatal error: all goroutines are asleep - deadlock!\n\n"
+		Write(2, []uint8(s))
+		Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+	}
+	if gp == curg {
+		return
+	}
+	var old *g = curg
+	old.deferStack = deferStack
+	old.panicStack = panicStack
+	curg = gp
+	deferStack = gp.deferStack
+	panicStack = gp.panicStack
+	gogo(old, gp)
+}
+
+// a channel
+type hchan struct {
+	elemSize  int
+	buf       uintptr // circular queue of dataqsiz elements
+	dataqsiz  int
+	qcount    int
+	sendx     int
+	recvx     int
+	closed    bool
+	sendq     *sudog // blocked senders
+	sendqTail *sudog
+	waitq     *waiter
+}
+
+// a sender blocked on a channel
+type sudog struct {
+	g    *g
+	elem uintptr // address of the value to send
+	done bool
+	next *sudog
+}
+
+// a goroutine waiting for the state of a channel to change
+type waiter struct {
+	g      *g
+	ticket int
+	isRecv bool
+	next   *waiter
+}
+
+func makechan(elemSize int, size int) uintptr {
+	if size < 0 || (elemSize > 0 && size > maxAlloc/elemSize) {
+		panic(plainError("makechan: size out of range"))
+	}
+	var c *hchan = new(hchan)
+	c.elemSize = elemSize
+	c.dataqsiz = size
+	if size > 0 {
+		c.buf = malloc(uintptr(size * elemSize))
+	}
+	return uintptr(unsafe.Pointer(c))
+}
+
+func chanlen(c *hchan) int {
+	if c == nil {
+		return 0
+	}
+	return c.qcount
+}
+
+func chancap(c *hchan) int {
+	if c == nil {
+		return 0
+	}
+	return c.dataqsiz
+}
+
+func isLiveWaiter(w *waiter) bool {
+	return w.ticket == w.g.ticket
+}
+
+// register the current goroutine as a waiter of c
+func addWaiter(c *hchan, isRecv bool) {
+	if isRecv {
+		// a select may be waiting for a receiver to send to
+		wakeWaiters(c, false)
+	}
+	var w *waiter = new(waiter)
+	w.g = curg
+	w.ticket = curg.ticket
+	w.isRecv = isRecv
+	w.next = c.waitq
+	c.waitq = w
+}
+
+// wake up the waiters of c. Waiting receivers are left if all is false.
+func wakeWaiters(c *hchan, all bool) {
+	var w *waiter = c.waitq
+	c.waitq = nil
+	for w != nil {
+		var next *waiter = w.next
+		if isLiveWaiter(w) {
+			if (all || !w.isRecv) && w.g.parked {
+				ready(w.g)
+			} else {
+				w.next = c.waitq
+				c.waitq = w
+			}
+		}
+		w = next
+	}
+}
+
+func hasRecvWaiter(c *hchan) bool {
+	var w *waiter
+	for w = c.waitq; w != nil; w = w.next {
+		if w.isRecv && isLiveWaiter(w) && w.g.parked {
+			return true
+		}
 	}
 	return false
 }
 
-func Write(fd int, p []byte) int
-func Syscall(trap uintptr, a1 uintptr, a2 uintptr, a3 uintptr) uintptr
+func dequeueSender(c *hchan) *sudog {
+	var sg *sudog = c.sendq
+	if sg == nil {
+		return nil
+	}
+	c.sendq = sg.next
+	if c.sendq == nil {
+		c.sendqTail = nil
+	}
+	return sg
+}
 
-// Actually this is an alias to makeSlice
-func makeSlice1(elmSize int, slen int, scap int) []uint8
-func makeSlice8(elmSize int, slen int, scap int) []int
-func makeSlice16(elmSize int, slen int, scap int) []string
-func makeSlice24(elmSize int, slen int, scap int) [][]int
+// elem is the address of the value to send
+func chansend(c *hchan, elem uintptr) {
+	if c == nil {
+		park() // block forever
+	}
+	if c.closed {
+		panic(plainError("send on closed channel"))
+	}
+	if c.qcount < c.dataqsiz {
+		memcopy(elem, c.buf+uintptr(c.sendx*c.elemSize), c.elemSize)
+		c.sendx = (c.sendx + 1) % c.dataqsiz
+		c.qcount++
+		wakeWaiters(c, true)
+		return
+	}
+
+	// wait for a receiver to take the value
+	var sg *sudog = new(sudog)
+	sg.g = curg
+	sg.elem = elem
+	if c.sendqTail == nil {
+		c.sendq = sg
+	} else {
+		c.sendqTail.next = sg
+	}
+	c.sendqTail = sg
+	wakeWaiters(c, true)
+	for !sg.done {
+		if c.closed {
+			panic(plainError("send on closed channel"))
+		}
+		curg.ticket++
+		park()
+	}
+}
+
+// returns the address of the received value and whether it was sent by a send operation.
+// The address points to a zero value if the channel is closed and empty.
+func chanrecv(c *hchan) (uintptr, bool) {
+	if c == nil {
+		park() // block forever
+	}
+	for {
+		var sg *sudog
+		if c.qcount > 0 {
+			var elem uintptr = malloc(uintptr(c.elemSize))
+			memcopy(c.buf+uintptr(c.recvx*c.elemSize), elem, c.elemSize)
+			c.recvx = (c.recvx + 1) % c.dataqsiz
+			c.qcount--
+			// move a blocked sender's value into the freed slot
+			sg = dequeueSender(c)
+			if sg != nil {
+				memcopy(sg.elem, c.buf+uintptr(c.sendx*c.elemSize), c.elemSize)
+				c.sendx = (c.sendx + 1) % c.dataqsiz
+				c.qcount++
+				sg.done = true
+				ready(sg.g)
+			}
+			wakeWaiters(c, true)
+			return elem, true
+		}
+		sg = dequeueSender(c)
+		if sg != nil {
+			sg.done = true
+			ready(sg.g)
+			return sg.elem, true
+		}
+		if c.closed {
+			return getZeroArea(uintptr(c.elemSize)), false
+		}
+		curg.ticket++
+		addWaiter(c, true)
+		park()
+	}
+}
+
+func closechan(c *hchan) {
+	if c == nil {
+		panic(plainError("close of nil channel"))
+	}
+	if c.closed {
+		panic(plainError("close of closed channel"))
+	}
+	c.closed = true
+	// blocked senders will panic
+	var sg *sudog = dequeueSender(c)
+	for sg != nil {
+		ready(sg.g)
+		sg = dequeueSender(c)
+	}
+	wakeWaiters(c, true)
+}
+
+// a select statement
+type selectState struct {
+	cases []*scase
+}
+
+type scase struct {
+	c      *hchan
+	isSend bool
+}
+
+func newselect() uintptr {
+	var sel *selectState = new(selectState)
+	return uintptr(unsafe.Pointer(sel))
+}
+
+func selectcase(sel *selectState, c *hchan, isSend bool) {
+	var sc *scase = new(scase)
+	sc.c = c
+	sc.isSend = isSend
+	sel.cases = append(sel.cases, sc)
+}
+
+// whether the operation of the case can proceed without blocking.
+// A send to an unbuffered channel is ready if a receiver is waiting.
+func isReadyCase(sc *scase) bool {
+	var c *hchan = sc.c
+	if c == nil {
+		return false
+	}
+	if sc.isSend {
+		return c.closed || c.qcount < c.dataqsiz || hasRecvWaiter(c)
+	}
+	return c.qcount > 0 || c.sendq != nil || c.closed
+}
+
+// returns the index of a ready case, or -1 if no case is ready and block is false.
+// The caller performs the operation of the chosen case.
+func selectgo(sel *selectState, block bool) int {
+	for {
+		for i, sc := range sel.cases {
+			if isReadyCase(sc) {
+				return i
+			}
+		}
+		if !block {
+			return -1
+		}
+		curg.ticket++
+		for _, sc := range sel.cases {
+			if sc.c != nil {
+				addWaiter(sc.c, !sc.isSend)
+			}
+		}
+		park()
+	}
+}
+
+// zero size bytes at addr1. (implemented in asm)
+func memzeropad(addr1 uintptr, size uintptr)
+
+func memcopy(src uintptr, dst uintptr, length int) {
+	var i int
+	var srcp *uint8
+	var dstp *uint8
+	for i = 0; i < length; i++ {
+		srcp = (*uint8)(unsafe.Pointer(src + uintptr(i)))
+		dstp = (*uint8)(unsafe.Pointer(dst + uintptr(i)))
+		*dstp = *srcp
+	}
+}
+
+// memmove is like memcopy, but the regions may overlap.
+func memmove(src uintptr, dst uintptr, length int) {
+	if dst <= src || dst >= src+uintptr(length) {
+		memcopy(src, dst, length)
+		return
+	}
+	var i int
+	var srcp *uint8
+	var dstp *uint8
+	for i = length - 1; i >= 0; i-- {
+		srcp = (*uint8)(unsafe.Pointer(src + uintptr(i)))
+		dstp = (*uint8)(unsafe.Pointer(dst + uintptr(i)))
+		*dstp = *srcp
+	}
+}
+
+// The heap is made of arenas of arenaSize bytes, which are mapped with mmap as the heap grows.
+// The pages of an arena are grouped into spans. A small object is allocated from a span
+// of objects of its size class, a medium object gets a span of its own,
+// and a large object gets a mapping of its own.
+//
+// Arenas and large objects are mapped in slots of arenaSize bytes from arenaBase,
+// and arenaIndex tells what is in each slot, so the object which an address points into is found quickly.
+//
+// The garbage collector marks and sweeps conservatively: any word in the roots, which are
+// the globals listed by the compiler and the stacks of the goroutines, or in a marked object
+// keeps the object it points into alive. Objects allocated as noscan hold no pointers and are not scanned.
+const pageShift = 13 // (runtime.scanRange depends on this)
+const pageSize uintptr = 1 << pageShift
+const arenaShift = 22 // (runtime.scanRange depends on this)
+const arenaSize uintptr = 1 << arenaShift
+const pagesPerArena uintptr = arenaSize / pageSize
+const arenaBase uintptr = 0xc000000000 // (runtime.scanRange depends on this)
+const arenaSlots uintptr = 16384       // 64GB. (runtime.scanRange depends on this)
+const slotReserved uintptr = 2         // an arenaIndex entry for a slot mapped by someone else
+
+const maxSmallSize uintptr = 32768  // larger objects get spans of their own
+const maxSpanSize uintptr = 1048576 // larger objects get mappings of their own
+const maxObjsPerSpan uintptr = 1024 // 8 byte objects in a page
+const numClasses = 74
+const markStackLen uintptr = 262144 // (runtime.scanRange depends on this)
+const minHeapGoal uintptr = 4194304
+
+const SYS_MMAP int = 9
+const SYS_MUNMAP int = 11
+const protReadWrite int = 3
+const mapPrivateAnon int = 34
+const mapFixedNoreplace int = 1048576
+
+// a run of pages in an arena, or a large object. (runtime.scanRange depends on the first fields)
+type mspan struct {
+	start     uintptr // the address of the first page
+	elemSize  uintptr // 0 if the pages are free
+	nelems    uintptr
+	allocBits uintptr // a byte for each object, which is 1 if the object is allocated
+	markBits  uintptr // a byte for each object, which is 1 if the object is marked
+	noscan    bool
+	large     bool
+	npages    uintptr
+	needzero  bool    // the free memory of the span may not be zero
+	freeindex uintptr // objects before this index are allocated
+	nfree     uintptr
+	next      *mspan // in the list of the spans of a size class, or of the free spans
+	prev      *mspan // in the list of the free spans
+	allnext   *mspan // in the list of the spans in use
+}
+
+const spanStructSize uintptr = 112
+
+var arenaIndex uintptr // for each slot, the page table of an arena, or the span of a large object with the low bit set
+var freeSpans *mspan   // runs of free pages
+var usedSpans *mspan
+var spanCache *mspan // span structs to reuse
+
+var classSpans [148]uintptr // the spans with free objects, for each size class and for noscan or not
+var classSize [numClasses]uintptr
+var classPages [numClasses]uintptr
+var smallClass [129]uint8 // the size class of sizes up to 1024, indexed by size / 8
+var largeClass [249]uint8 // the size class of larger sizes, indexed by (size - 1024) / 128
+
+var markStack uintptr // pairs of the address and the size of objects marked but not scanned yet
+var markTop uintptr
+var markOverflow bool // the mark stack was full and some marked objects were not pushed
+
+var heapLive uintptr // bytes of the objects in use
+var heapGoal uintptr // heapLive which triggers the next collection
+var heapSys uintptr  // bytes mapped for arenas and large objects
+var otherSys uintptr // bytes mapped for the metadata of the heap
+var maxHeap uintptr  // the limit of heapSys. 0 if there is no limit
+
+var totalAlloc uintptr
+var numMallocs uintptr
+var numFrees uintptr
+
+var numGC int
+var gctrace bool
+var gcoff bool // set when the collector must not run
+
+func heapInit() {
+	arenaIndex = sysAlloc(arenaSlots * 8)
+	markStack = sysAlloc(markStackLen * 16)
+	initSizeClasses()
+	heapGoal = minHeapGoal
+}
+
+// Size classes are spaced by 8 bytes up to 32, by 16 bytes up to 128, and then by an eighth of the power of two below.
+// A span of a class has as few pages as leave less than an eighth of the span unused.
+func initSizeClasses() {
+	var size uintptr = 8
+	var c int
+	for size <= maxSmallSize {
+		var npages uintptr = 1
+		for (npages*pageSize)%size > npages*pageSize/8 {
+			npages++
+		}
+		classSize[c] = size
+		classPages[c] = npages
+		c++
+		if size < 32 {
+			size = size + 8
+		} else if size < 128 {
+			size = size + 16
+		} else {
+			size = size + 1<<uintptr(bitLen(size)-4)
+		}
+	}
+	if c != numClasses {
+		panic("runtime: wrong number of size classes")
+	}
+
+	c = 0
+	var i int
+	for i = 0; i < len(smallClass); i++ {
+		for classSize[c] < uintptr(i*8) {
+			c++
+		}
+		smallClass[i] = uint8(c)
+	}
+	for i = 0; i < len(largeClass); i++ {
+		for classSize[c] < uintptr(1024+i*128) {
+			c++
+		}
+		largeClass[i] = uint8(c)
+	}
+}
+
+// the number of bits needed to represent x. (implemented in asm)
+func bitLen(x uintptr) int
+
+func sizeToClass(size uintptr) int {
+	if size <= 1024 {
+		return int(smallClass[(size+7)>>3])
+	}
+	return int(largeClass[(size-1024+127)>>7])
+}
+
+// read the settings of the collector from the environment.
+// GODEBUG is a comma separated list of name=value pairs, and BABYGO_MAXHEAP limits the heap
+// in bytes, optionally with a K, M or G suffix.
+func gcinit() {
+	var s string = runtime_getenv("GODEBUG")
+	var start int
+	var i int
+	for i = 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == ',' {
+			if s[start:i] == "gctrace=1" {
+				gctrace = true
+			}
+			start = i + 1
+		}
+	}
+
+	s = runtime_getenv("BABYGO_MAXHEAP")
+	var n uintptr
+	for i = 0; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		n = n*10 + uintptr(s[i]-'0')
+	}
+	if i == len(s)-1 {
+		switch s[i] {
+		case 'K', 'k':
+			n = n << 10
+		case 'M', 'm':
+			n = n << 20
+		case 'G', 'g':
+			n = n << 30
+		default:
+			return
+		}
+	} else if i != len(s) {
+		return
+	}
+	maxHeap = n
+}
+
+// map memory anywhere for the metadata of the heap
+func sysAlloc(size uintptr) uintptr {
+	var addr uintptr = mmap(0, size, protReadWrite, mapPrivateAnon, -1, 0)
+	if int(addr) < 0 {
+		Write(2, []uint8("fatal error: runtime: cannot map memory\n\n"))
+		Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+	}
+	otherSys = otherSys + size
+	return addr
+}
+
+// map size bytes at free slots. It returns 0 if the heap cannot grow.
+func mapSlots(size uintptr) uintptr {
+	if maxHeap != 0 && heapSys+size > maxHeap {
+		return 0
+	}
+	var n uintptr = (size + arenaSize - 1) >> arenaShift
+	var i uintptr
+	var run uintptr
+	for i = 0; i < arenaSlots; i++ {
+		if slotEntry(i) != 0 {
+			run = 0
+			continue
+		}
+		run++
+		if run < n {
+			continue
+		}
+		var first uintptr = i + 1 - n
+		var addr uintptr = arenaBase + first<<arenaShift
+		var r uintptr = mmap(addr, size, protReadWrite, mapPrivateAnon|mapFixedNoreplace, -1, 0)
+		if r == addr {
+			heapSys = heapSys + size
+			return addr
+		}
+		if int(r) >= 0 {
+			// an old kernel took the address as a hint
+			Syscall(uintptr(SYS_MUNMAP), r, size, uintptr(0))
+		}
+		// give up the slots, which someone else uses
+		var j uintptr
+		for j = first; j <= i; j++ {
+			setSlotEntry(j, slotReserved)
+		}
+		run = 0
+	}
+	return 0
+}
+
+func slotEntry(i uintptr) uintptr {
+	return *(*uintptr)(unsafe.Pointer(arenaIndex + i*8))
+}
+
+func setSlotEntry(i uintptr, v uintptr) {
+	*(*uintptr)(unsafe.Pointer(arenaIndex + i*8)) = v
+}
+
+// func mmap(addr uintptr, length uintptr, prot int, flags int, fd int, offset int) uintptr (implemented in asm)
+func mmap(addr uintptr, length uintptr, prot int, flags int, fd int, offset int) uintptr
+
+// memory for the metadata of the heap, which is never freed
+var persistentNext uintptr
+var persistentEnd uintptr
+
+func persistentAlloc(size uintptr) uintptr {
+	if persistentNext+size > persistentEnd {
+		var n uintptr = 262144
+		persistentNext = sysAlloc(n)
+		persistentEnd = persistentNext + n
+	}
+	var p uintptr = persistentNext
+	persistentNext = persistentNext + size
+	return p
+}
+
+// a span struct with its bitmaps
+func newSpanStruct() *mspan {
+	var s *mspan = spanCache
+	if s != nil {
+		spanCache = s.next
+	} else {
+		var p uintptr = persistentAlloc(spanStructSize + maxObjsPerSpan*2)
+		s = (*mspan)(unsafe.Pointer(p))
+		s.allocBits = p + spanStructSize
+		s.markBits = s.allocBits + maxObjsPerSpan
+	}
+	s.elemSize = 0
+	s.nelems = 0
+	s.noscan = false
+	s.large = false
+	s.needzero = false
+	s.freeindex = 0
+	s.nfree = 0
+	s.next = nil
+	s.prev = nil
+	s.allnext = nil
+	return s
+}
+
+func releaseSpanStruct(s *mspan) {
+	s.next = spanCache
+	spanCache = s
+}
+
+// the page table of the arena containing addr
+func pageTableOf(addr uintptr) uintptr {
+	return slotEntry((addr - arenaBase) >> arenaShift)
+}
+
+// make the n pages from addr belong to s
+func setPages(addr uintptr, n uintptr, s *mspan) {
+	var p uintptr = pageTableOf(addr) + (addr&(arenaSize-1))>>pageShift*8
+	var i uintptr
+	for i = 0; i < n; i++ {
+		*(**mspan)(unsafe.Pointer(p + i*8)) = s
+	}
+}
+
+// the span of the page at addr, which must be in an arena
+func spanOf(addr uintptr) *mspan {
+	return *(**mspan)(unsafe.Pointer(pageTableOf(addr) + (addr&(arenaSize-1))>>pageShift*8))
+}
+
+func insertFree(s *mspan) {
+	s.prev = nil
+	s.next = freeSpans
+	if freeSpans != nil {
+		freeSpans.prev = s
+	}
+	freeSpans = s
+}
+
+func removeFree(s *mspan) {
+	if s.prev != nil {
+		s.prev.next = s.next
+	} else {
+		freeSpans = s.next
+	}
+	if s.next != nil {
+		s.next.prev = s.prev
+	}
+	s.next = nil
+	s.prev = nil
+}
+
+// map a new arena, whose pages are free
+func growHeap() bool {
+	var addr uintptr = mapSlots(arenaSize)
+	if addr == 0 {
+		return false
+	}
+	setSlotEntry((addr-arenaBase)>>arenaShift, persistentAlloc(pagesPerArena*8))
+	var s *mspan = newSpanStruct()
+	s.start = addr
+	s.npages = pagesPerArena
+	setPages(addr, pagesPerArena, s)
+	insertFree(s)
+	return true
+}
+
+// take npages pages from the free spans, growing the heap if needed. It returns nil if the heap cannot grow.
+func allocPages(npages uintptr) *mspan {
+	var s *mspan
+	for s = freeSpans; s != nil; s = s.next {
+		if s.npages >= npages {
+			break
+		}
+	}
+	if s == nil {
+		if !growHeap() {
+			return nil
+		}
+		s = freeSpans
+	}
+	if s.npages == npages {
+		removeFree(s)
+		return s
+	}
+	// cut the pages off the end, so that only their page table entries change
+	var t *mspan = newSpanStruct()
+	s.npages = s.npages - npages
+	t.start = s.start + s.npages*pageSize
+	t.npages = npages
+	t.needzero = s.needzero
+	setPages(t.start, npages, t)
+	return t
+}
+
+// return the pages of s to the free spans, joining the free spans next to them
+func freePages(s *mspan) {
+	s.elemSize = 0
+	s.needzero = true
+	var page uintptr = (s.start & (arenaSize - 1)) >> pageShift
+	if page > 0 {
+		var p *mspan = spanOf(s.start - pageSize)
+		if p.elemSize == 0 {
+			setPages(s.start, s.npages, p)
+			p.npages = p.npages + s.npages
+			p.needzero = true
+			releaseSpanStruct(s)
+			s = p
+			removeFree(s)
+		}
+	}
+	var end uintptr = s.start + s.npages*pageSize
+	if end&(arenaSize-1) != 0 {
+		var n *mspan = spanOf(end)
+		if n.elemSize == 0 {
+			removeFree(n)
+			setPages(n.start, n.npages, s)
+			s.npages = s.npages + n.npages
+			releaseSpanStruct(n)
+		}
+	}
+	insertFree(s)
+}
+
+// a span of objects of the size class c
+func newClassSpan(c int, noscan bool) *mspan {
+	var s *mspan = allocPages(classPages[c])
+	if s == nil {
+		return nil
+	}
+	s.elemSize = classSize[c]
+	s.nelems = s.npages * pageSize / s.elemSize
+	s.noscan = noscan
+	s.freeindex = 0
+	s.nfree = s.nelems
+	memzeropad(s.allocBits, s.nelems)
+	memzeropad(s.markBits, s.nelems)
+	s.allnext = usedSpans
+	usedSpans = s
+	return s
+}
+
+func allocSmall(c int, noscan bool) uintptr {
+	var list *uintptr = &classSpans[c*2]
+	if noscan {
+		list = &classSpans[c*2+1]
+	}
+	var s *mspan = (*mspan)(unsafe.Pointer(*list))
+	if s == nil {
+		s = newClassSpan(c, noscan)
+		if s == nil {
+			return 0
+		}
+		*list = uintptr(unsafe.Pointer(s))
+	}
+	var bits uintptr = s.allocBits + s.freeindex
+	for *(*uint8)(unsafe.Pointer(bits)) != 0 {
+		bits++
+	}
+	*(*uint8)(unsafe.Pointer(bits)) = 1
+	s.freeindex = bits - s.allocBits + 1
+	s.nfree--
+	if s.nfree == 0 {
+		*list = uintptr(unsafe.Pointer(s.next))
+		s.next = nil
+	}
+	var p uintptr = s.start + (bits-s.allocBits)*s.elemSize
+	if s.needzero {
+		memzeropad(p, s.elemSize)
+	}
+	return p
+}
+
+// an object with a span of its own, in an arena or mapped by itself
+func allocLarge(size uintptr, noscan bool) uintptr {
+	var s *mspan
+	var n uintptr = (size + pageSize - 1) &^ (pageSize - 1)
+	if n <= maxSpanSize {
+		s = allocPages(n >> pageShift)
+		if s == nil {
+			return 0
+		}
+		if s.needzero {
+			memzeropad(s.start, n)
+		}
+	} else {
+		var addr uintptr = mapSlots(n)
+		if addr == 0 {
+			return 0
+		}
+		s = newSpanStruct()
+		s.start = addr
+		s.npages = n >> pageShift
+		s.large = true
+		var i uintptr
+		for i = 0; i < (n+arenaSize-1)>>arenaShift; i++ {
+			setSlotEntry((addr-arenaBase)>>arenaShift+i, uintptr(unsafe.Pointer(s))|1)
+		}
+	}
+	s.elemSize = n
+	s.nelems = 1
+	s.noscan = noscan
+	*(*uint8)(unsafe.Pointer(s.allocBits)) = 1
+	*(*uint8)(unsafe.Pointer(s.markBits)) = 0
+	s.allnext = usedSpans
+	usedSpans = s
+	return s.start
+}
+
+// unmap a large object
+func freeLarge(s *mspan) {
+	Syscall(uintptr(SYS_MUNMAP), s.start, s.elemSize, uintptr(0))
+	heapSys = heapSys - s.elemSize
+	var i uintptr
+	for i = 0; i < (s.elemSize+arenaSize-1)>>arenaShift; i++ {
+		setSlotEntry((s.start-arenaBase)>>arenaShift+i, 0)
+	}
+	releaseSpanStruct(s)
+}
+
+// an object of n bytes, which is of the size class c if it is small
+func allocObject(c int, n uintptr, noscan bool) uintptr {
+	if n <= maxSmallSize {
+		return allocSmall(c, noscan)
+	}
+	return allocLarge(n, noscan)
+}
+
+// allocate zeroed memory. The collector does not look for pointers in noscan memory.
+func mallocgc(size uintptr, noscan bool) uintptr {
+	var c int
+	var n uintptr
+	if size <= maxSmallSize {
+		c = sizeToClass(size)
+		n = classSize[c]
+	} else {
+		n = (size + pageSize - 1) &^ (pageSize - 1)
+	}
+	if heapLive+n > heapGoal {
+		GC()
+	}
+	var p uintptr = allocObject(c, n, noscan)
+	if p == 0 {
+		GC()
+		p = allocObject(c, n, noscan)
+		if p == 0 {
+			Write(2, []uint8("fatal error: out of memory\n\n"))
+			Syscall(uintptr(SYS_EXIT), 2, uintptr(0), uintptr(0))
+		}
+	}
+	heapLive = heapLive + n
+	totalAlloc = totalAlloc + n
+	numMallocs++
+	return p
+}
+
+func malloc(size uintptr) uintptr {
+	return mallocgc(size, false)
+}
+
+// GC runs a garbage collection.
+func GC() {
+	if gcoff {
+		return
+	}
+	var before uintptr = heapLive
+	markRoots()
+	drainMarkStack()
+	for markOverflow {
+		markOverflow = false
+		rescanHeap()
+	}
+	var freed int = sweep()
+	heapGoal = heapLive * 2
+	if heapGoal < minHeapGoal {
+		heapGoal = minHeapGoal
+	}
+	numGC++
+	if gctrace {
+		traceLen = 0
+		traceString("gc ")
+		traceInt(numGC)
+		traceString(": ")
+		traceInt(int(before / 1024))
+		traceString(" -> ")
+		traceInt(int(heapLive / 1024))
+		traceString(" KB, ")
+		traceInt(int(heapGoal / 1024))
+		traceString(" KB goal, ")
+		traceInt(freed)
+		traceString(" objects freed\n")
+		Write(2, traceBuf[0:traceLen])
+	}
+}
+
+// return the address of the table of the global roots emitted by the compiler. (implemented in asm)
+func getGlobalRoots() uintptr
+
+// return the stack pointer of the caller. (implemented in asm)
+func getsp() uintptr
+
+func markRoots() {
+	var roots uintptr = getGlobalRoots()
+	var n uintptr = *(*uintptr)(unsafe.Pointer(roots))
+	var i uintptr
+	for i = 0; i < n; i++ {
+		var entry uintptr = roots + 8 + i*16
+		scanRange(*(*uintptr)(unsafe.Pointer(entry)), *(*uintptr)(unsafe.Pointer(entry + 8)), 1)
+	}
+
+	// forget the goroutines which have exited
+	var gp *g = allgs
+	allgs = nil
+	for gp != nil {
+		var next *g = gp.alllink
+		if !gp.dead {
+			gp.alllink = allgs
+			allgs = gp
+		}
+		gp = next
+	}
+	// Values on the stacks are not always aligned.
+	// The stacks themselves are kept alive through allgs.
+	for gp = allgs; gp != nil; gp = gp.alllink {
+		var sp uintptr = gp.sp
+		if gp == curg {
+			sp = getsp()
+		}
+		scanRange(sp, gp.stackHi-sp, 1)
+	}
+}
+
+// mark the objects pointed to by the words in [addr, addr+size) at every step bytes,
+// and push the ones to be scanned onto the mark stack. (implemented in asm)
+func scanRange(addr uintptr, size uintptr, step uintptr)
+
+func drainMarkStack() {
+	for markTop > 0 {
+		markTop--
+		var entry uintptr = markStack + markTop*16
+		scanRange(*(*uintptr)(unsafe.Pointer(entry)), *(*uintptr)(unsafe.Pointer(entry + 8)), 8)
+	}
+}
+
+// scan the marked objects again to find the objects which could not be pushed
+func rescanHeap() {
+	var s *mspan
+	for s = usedSpans; s != nil; s = s.allnext {
+		if s.noscan {
+			continue
+		}
+		var i uintptr
+		for i = 0; i < s.nelems; i++ {
+			if *(*uint8)(unsafe.Pointer(s.markBits + i)) != 0 {
+				scanRange(s.start+i*s.elemSize, s.elemSize, 8)
+				drainMarkStack()
+			}
+		}
+	}
+}
+
+// free the objects which are not marked, and clear the marks.
+// It returns the number of the objects freed.
+func sweep() int {
+	var i int
+	for i = 0; i < len(classSpans); i++ {
+		classSpans[i] = 0
+	}
+	heapLive = 0
+	var freed int
+	var s *mspan = usedSpans
+	usedSpans = nil
+	for s != nil {
+		var next *mspan = s.allnext
+		var inuse uintptr
+		var j uintptr
+		for j = 0; j < s.nelems; j++ {
+			var alloc *uint8 = (*uint8)(unsafe.Pointer(s.allocBits + j))
+			var mark *uint8 = (*uint8)(unsafe.Pointer(s.markBits + j))
+			if *mark != 0 {
+				*mark = 0
+				inuse++
+			} else if *alloc != 0 {
+				*alloc = 0
+				freed++
+				s.needzero = true
+			}
+		}
+		if inuse == 0 {
+			if s.large {
+				freeLarge(s)
+			} else {
+				freePages(s)
+			}
+		} else {
+			heapLive = heapLive + inuse*s.elemSize
+			s.allnext = usedSpans
+			usedSpans = s
+			if s.nelems > 1 {
+				s.freeindex = 0
+				s.nfree = s.nelems - inuse
+				if s.nfree > 0 {
+					var c int = sizeToClass(s.elemSize)
+					var list *uintptr = &classSpans[c*2]
+					if s.noscan {
+						list = &classSpans[c*2+1]
+					}
+					s.next = (*mspan)(unsafe.Pointer(*list))
+					*list = uintptr(unsafe.Pointer(s))
+				}
+			}
+		}
+		s = next
+	}
+	numFrees = numFrees + uintptr(freed)
+	return freed
+}
+
+// MemStats records statistics about the memory allocator.
+type MemStats struct {
+	Alloc       uint64 // bytes of the allocated heap objects
+	TotalAlloc  uint64 // cumulative bytes allocated for heap objects
+	Sys         uint64 // bytes of memory obtained from the OS
+	Mallocs     uint64 // cumulative count of heap objects allocated
+	Frees       uint64 // cumulative count of heap objects freed
+	HeapAlloc   uint64 // same as Alloc
+	HeapSys     uint64 // bytes of memory mapped for the heap
+	HeapObjects uint64 // number of allocated heap objects
+	NextGC      uint64 // HeapAlloc at which the next collection runs
+	NumGC       uint32 // number of completed collections
+}
+
+// ReadMemStats populates m with the statistics of the memory allocator.
+func ReadMemStats(m *MemStats) {
+	m.Alloc = uint64(heapLive)
+	m.TotalAlloc = uint64(totalAlloc)
+	m.Sys = uint64(heapSys + otherSys + stackSys)
+	m.Mallocs = uint64(numMallocs)
+	m.Frees = uint64(numFrees)
+	m.HeapAlloc = uint64(heapLive)
+	m.HeapSys = uint64(heapSys)
+	m.HeapObjects = uint64(numMallocs - numFrees)
+	m.NextGC = uint64(heapGoal)
+	m.NumGC = uint32(numGC)
+}
+
+// a trace line is built without allocation
+var traceBuf [128]uint8
+var traceLen int
+
+func traceString(s string) {
+	var i int
+	for i = 0; i < len(s); i++ {
+		traceBuf[traceLen] = s[i]
+		traceLen++
+	}
+}
+
+func traceInt(x int) {
+	if x >= 10 {
+		traceInt(x / 10)
+	}
+	traceBuf[traceLen] = uint8('0' + x%10)
+	traceLen++
+}
+
+// the largest size in bytes of an allocation, as in Go on linux/amd64.
+// Sizes are checked against it before they are multiplied, so that they cannot overflow.
+const maxAlloc int = 1 << 48
+
+func makeSlice(elmSize int, slen int, scap int, noscan bool) (uintptr, int, int) {
+	if slen < 0 || (elmSize > 0 && slen > maxAlloc/elmSize) {
+		panic(errorString("makeslice: len out of range"))
+	}
+	if scap < slen || (elmSize > 0 && scap > maxAlloc/elmSize) {
+		panic(errorString("makeslice: cap out of range"))
+	}
+	var size uintptr = uintptr(elmSize * scap)
+	var addr uintptr = mallocgc(size, noscan)
+	return addr, slen, scap
+}
+
+// layout of a slice value
+type sliceHeader struct {
+	ptr uintptr
+	len int
+	cap int
+}
+
+// layout of a string value
+type stringHeader struct {
+	ptr uintptr
+	len int
+}
+
+// append the elements of elms to old.
+// Lengths and capacities of both slices count elements of elmSize bytes.
+func appendslice(old []uint8, elms []uint8, elmSize int, noscan bool) (uintptr, int, int) {
+	var o *sliceHeader = (*sliceHeader)(unsafe.Pointer(&old))
+	var e *sliceHeader = (*sliceHeader)(unsafe.Pointer(&elms))
+	var newlen int = o.len + e.len
+	var ptr uintptr = o.ptr
+	var newcap int = o.cap
+	if newlen > o.cap {
+		newcap = o.cap * 2
+		if newcap < newlen {
+			newcap = newlen
+		}
+		ptr = mallocgc(uintptr(newcap*elmSize), noscan)
+		memmove(o.ptr, ptr, o.len*elmSize)
+	}
+	memmove(e.ptr, ptr+uintptr(o.len*elmSize), e.len*elmSize)
+	return ptr, newlen, newcap
+}
+
+// copy(dst, src)
+func slicecopy(dst []uint8, src []uint8, elmSize int) int {
+	var d *sliceHeader = (*sliceHeader)(unsafe.Pointer(&dst))
+	var s *sliceHeader = (*sliceHeader)(unsafe.Pointer(&src))
+	var n int = d.len
+	if s.len < n {
+		n = s.len
+	}
+	memmove(s.ptr, d.ptr, n*elmSize)
+	return n
+}
+
+// zero the elements of s
+func clearslice(s []uint8, elmSize int) {
+	var h *sliceHeader = (*sliceHeader)(unsafe.Pointer(&s))
+	memzeropad(h.ptr, uintptr(h.len*elmSize))
+}
+
+func catstrings(a string, b string) string {
+	var totallen = len(a) + len(b)
+	var r = make([]uint8, totallen, totallen+1) // +1 is a workaround for syscall.Open. see runtime.s
+	var i int
+	for i = 0; i < len(a); i = i + 1 {
+		r[i] = a[i]
+	}
+	var j int
+	for j = 0; j < len(b); j = j + 1 {
+		r[i+j] = b[j]
+	}
+	return string(r)
+}
+
+// compare strings byte by byte. The result is -1, 0 or +1.
+func cmpstring(a string, b string) int {
+	var l int = len(a)
+	if len(b) < l {
+		l = len(b)
+	}
+	var i int
+	for i = 0; i < l; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(a) < len(b) {
+		return -1
+	}
+	if len(a) > len(b) {
+		return 1
+	}
+	return 0
+}
+
+func minstring(a string, b string) string {
+	if cmpstring(a, b) < 0 {
+		return a
+	}
+	return b
+}
+
+func maxstring(a string, b string) string {
+	if cmpstring(a, b) > 0 {
+		return a
+	}
+	return b
+}
+
+func cmpstrings(a string, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var i int
+	for i = 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const runeError int = 65533 // U+FFFD
+
+// decoderune returns the code point which starts at s[k] and the index after it.
+// An invalid UTF-8 sequence is decoded as runeError of width 1.
+func decoderune(s string, k int) (int, int) {
+	var c0 = int(s[k])
+	var n int
+	var r int
+	var min int
+	if c0 < 128 {
+		return c0, k + 1
+	} else if c0 >= 194 && c0 < 224 {
+		n = 2
+		r = c0 & 31
+		min = 128
+	} else if c0 >= 224 && c0 < 240 {
+		n = 3
+		r = c0 & 15
+		min = 2048
+	} else if c0 >= 240 && c0 < 245 {
+		n = 4
+		r = c0 & 7
+		min = 65536
+	} else {
+		return runeError, k + 1
+	}
+	if k+n > len(s) {
+		return runeError, k + 1
+	}
+	var i int
+	for i = 1; i < n; i++ {
+		var c = int(s[k+i])
+		if c&192 != 128 {
+			return runeError, k + 1
+		}
+		r = r<<6 | c&63
+	}
+	if r < min || r > 1114111 || (r >= 55296 && r <= 57343) {
+		// overlong encoding, out of range or surrogate half
+		return runeError, k + 1
+	}
+	return r, k + n
+}
+
+// encoderune appends the UTF-8 encoding of r to buf.
+func encoderune(buf []uint8, r int) []uint8 {
+	if r < 0 || r > 1114111 || (r >= 55296 && r <= 57343) {
+		r = runeError
+	}
+	if r < 128 {
+		return append(buf, uint8(r))
+	}
+	if r < 2048 {
+		buf = append(buf, uint8(192|r>>6))
+		return append(buf, uint8(128|r&63))
+	}
+	if r < 65536 {
+		buf = append(buf, uint8(224|r>>12))
+		buf = append(buf, uint8(128|r>>6&63))
+		return append(buf, uint8(128|r&63))
+	}
+	buf = append(buf, uint8(240|r>>18))
+	buf = append(buf, uint8(128|r>>12&63))
+	buf = append(buf, uint8(128|r>>6&63))
+	return append(buf, uint8(128|r&63))
+}
+
+// string(r)
+func intstring(v int) string {
+	var buf []uint8
+	buf = encoderune(buf, v)
+	return string(buf)
+}
+
+// []rune(s)
+func stringtoslicerune(s string) []int32 {
+	var n int
+	var k int
+	for k < len(s) {
+		_, k = decoderune(s, k)
+		n++
+	}
+	var a = make([]int32, n, n)
+	var r int
+	var i int
+	k = 0
+	for k < len(s) {
+		r, k = decoderune(s, k)
+		a[i] = int32(r)
+		i++
+	}
+	return a
+}
+
+// string([]rune)
+func slicerunetostring(a []int32) string {
+	var buf []uint8
+	var i int
+	for i = 0; i < len(a); i++ {
+		buf = encoderune(buf, int(a[i]))
+	}
+	return string(buf)
+}
+
+// Two interface values are equal if they have identical dynamic types and equal dynamic values or if both have value nil.
+func cmpinterface(a uintptr, b uintptr, c uintptr, d uintptr) bool {
+	if a != c {
+		return false
+	}
+	if a == 0 {
+		return true
+	}
+	return efaceeq((*dtype)(unsafe.Pointer(a)), b, d)
+}
+
+// compare the dynamic values at x and y of the same dynamic type t
+func efaceeq(t *dtype, x uintptr, y uintptr) bool {
+	if t.equal == nil {
+		panic(errorString("comparing uncomparable type " + t.name))
+	}
+	return t.equal(x, y)
+}
+
+// Type descriptor emitted by the compiler (see emitDynamicTypes)
+type dtype struct {
+	id      int
+	name    string
+	kind    int // same numbering as reflect.Kind
+	size    int
+	methods []imethod
+	equal   func(uintptr, uintptr) bool    // nil if the type is not comparable
+	hash    func(uintptr, uintptr) uintptr // mixes a value into a hash; nil if the type is not comparable
+}
+
+// An entry of the method table of a dtype.
+// The table of an interface type lists the methods it requires and has no code.
+type imethod struct {
+	id   int     // assigned to each method signature by the compiler
+	name string
+	fn   uintptr // wrapper which takes an interface value as its receiver
+}
+
+const kindString int = 24
+
+type eface struct {
+	dtype *dtype
+	data  uintptr
+}
+
+// find the method of the dynamic type to call an interface method
+func findMethod(t *dtype, id int) uintptr {
+	if t == nil {
+		panic(errorString("invalid memory address or nil pointer dereference"))
+	}
+	var i int
+	for i = 0; i < len(t.methods); i++ {
+		if t.methods[i].id == id {
+			return t.methods[i].fn
+		}
+	}
+	panic("method not found")
+	return 0
+}
+
+// report whether the dynamic type t has all the methods of the interface type ifc
+func implements(t *dtype, ifc *dtype) bool {
+	if t == nil {
+		return false
+	}
+	return missingMethod(t, ifc) == ""
+}
+
+// the name of the first method of the interface type ifc which t does not have, or ""
+func missingMethod(t *dtype, ifc *dtype) string {
+	var i int
+	var j int
+	for i = 0; i < len(ifc.methods); i++ {
+		var found bool
+		for j = 0; j < len(t.methods); j++ {
+			if t.methods[j].id == ifc.methods[i].id {
+				found = true
+			}
+		}
+		if !found {
+			return ifc.methods[i].name
+		}
+	}
+	return ""
+}
+
+// A failed type assertion, which implements Go's runtime.Error.
+// iface is the static type of the operand, and nil if the asserted type is an interface.
+type typeAssertionError struct {
+	iface         *dtype
+	concrete      *dtype
+	asserted      *dtype
+	missingMethod string
+}
+
+func (e *typeAssertionError) RuntimeError() {
+}
+
+func (e *typeAssertionError) Error() string {
+	var inter = "interface"
+	if e.iface != nil {
+		inter = typeName(e.iface)
+	}
+	var as = typeName(e.asserted)
+	if e.concrete == nil {
+		return "interface conversion: " + inter + " is nil, not " + as
+	}
+	var cs = typeName(e.concrete)
+	if e.missingMethod == "" {
+		return "interface conversion: " + inter + " is " + cs + ", not " + as
+	}
+	return "interface conversion: " + cs + " is not " + as + ": missing method " + e.missingMethod
+}
+
+// the name of t as Go prints it
+func typeName(t *dtype) string {
+	if t.name == "interface" {
+		return "interface {}"
+	}
+	return t.name
+}
+
+// called when a single-value type assertion x.(want) fails.
+// have is the dynamic type of x, and iface is its static type.
+func panicdottype(have *dtype, want *dtype, iface *dtype) {
+	var missing string
+	if have != nil && iface == nil {
+		missing = missingMethod(have, want)
+	}
+	panic(&typeAssertionError{
+		iface:         iface,
+		concrete:      have,
+		asserted:      want,
+		missingMethod: missing,
+	})
+}
+
+// --- map ---
+// A map value is a pointer to hmap. A nil map is 0.
+// Keys are boxed into interface values by the compiler, so that one implementation serves all key types.
+type hmap struct {
+	count     int
+	buckets   []*mapEntry
+	valueSize uintptr
+}
+
+type mapEntry struct {
+	next    *mapEntry
+	hash    uintptr
+	key     interface{}
+	value   uintptr // address of the value
+	deleted bool
+}
+
+type mapIter struct {
+	entries []*mapEntry
+	index   int
+	current *mapEntry
+}
+
+const mapMinBuckets int = 8
+
+// zero value returned on a lookup miss. It must not be written.
+var zeroArea uintptr
+var zeroAreaSize uintptr
+
+func getZeroArea(size uintptr) uintptr {
+	if size > zeroAreaSize {
+		zeroArea = malloc(size)
+		zeroAreaSize = size
+	}
+	return zeroArea
+}
+
+func makeMap(size int, valueSize int) uintptr {
+	if size > maxAlloc/8 {
+		// a hint too large to allocate is ignored as in Go
+		size = 0
+	}
+	var nbuckets int = mapMinBuckets
+	for nbuckets < size {
+		nbuckets = nbuckets * 2
+	}
+	var mp *hmap = new(hmap)
+	mp.buckets = make([]*mapEntry, nbuckets, nbuckets)
+	mp.valueSize = uintptr(valueSize)
+	return uintptr(unsafe.Pointer(mp))
+}
+
+func hashBytes(h uintptr, addr uintptr, size int) uintptr {
+	var i int
+	var p *uint8
+	for i = 0; i < size; i++ {
+		p = (*uint8)(unsafe.Pointer(addr + uintptr(i)))
+		h = h*31 + uintptr(*p)
+	}
+	return h
+}
+
+// The hash funcs emitted by the compiler call the following functions for the parts of a value.
+// Each mixes the value at p into h.
+
+func strhash(h uintptr, p uintptr) uintptr {
+	var s *stringHeader = (*stringHeader)(unsafe.Pointer(p))
+	return hashBytes(h, s.ptr, s.len)
+}
+
+// +0 and -0 are equal and have the same hash
+func f32hash(h uintptr, p uintptr) uintptr {
+	var f *float32 = (*float32)(unsafe.Pointer(p))
+	if *f == 0 {
+		return h * 31
+	}
+	return hashBytes(h, p, 4)
+}
+
+func f64hash(h uintptr, p uintptr) uintptr {
+	var f *float64 = (*float64)(unsafe.Pointer(p))
+	if *f == 0 {
+		return h * 31
+	}
+	return hashBytes(h, p, 8)
+}
+
+// the dynamic type and the dynamic value of an interface value
+func interhash(h uintptr, p uintptr) uintptr {
+	var e *eface = (*eface)(unsafe.Pointer(p))
+	if e.dtype == nil {
+		return h * 31
+	}
+	if e.dtype.hash == nil {
+		panic(errorString("hash of unhashable type " + e.dtype.name))
+	}
+	return e.dtype.hash(h*31+uintptr(e.dtype.id), e.data)
+}
+
+func hashKey(key interface{}) uintptr {
+	return interhash(7, uintptr(unsafe.Pointer(&key)))
+}
+
+// compare keys by their dynamic types and values
+func keyEqual(a interface{}, b interface{}) bool {
+	var ea *eface = (*eface)(unsafe.Pointer(&a))
+	var eb *eface = (*eface)(unsafe.Pointer(&b))
+	if ea.dtype != eb.dtype {
+		return false
+	}
+	if ea.dtype == nil {
+		return true
+	}
+	return efaceeq(ea.dtype, ea.data, eb.data)
+}
+
+func mapLookup(mp *hmap, key interface{}, hash uintptr) *mapEntry {
+	var idx int = int(hash % uintptr(len(mp.buckets)))
+	var e *mapEntry
+	for e = mp.buckets[idx]; e != nil; e = e.next {
+		if e.hash == hash && keyEqual(e.key, key) {
+			return e
+		}
+	}
+	return nil
+}
+
+func mapGrow(mp *hmap) {
+	var oldBuckets []*mapEntry = mp.buckets
+	var nbuckets int = len(oldBuckets) * 2
+	mp.buckets = make([]*mapEntry, nbuckets, nbuckets)
+	var e *mapEntry
+	var next *mapEntry
+	var idx int
+	for _, e = range oldBuckets {
+		for e != nil {
+			next = e.next
+			idx = int(e.hash % uintptr(nbuckets))
+			e.next = mp.buckets[idx]
+			mp.buckets[idx] = e
+			e = next
+		}
+	}
+}
+
+// v, ok := m[k]
+func mapAccess2(mp *hmap, key interface{}, valueSize int) (uintptr, bool) {
+	if mp == nil {
+		return getZeroArea(uintptr(valueSize)), false
+	}
+	var e *mapEntry = mapLookup(mp, key, hashKey(key))
+	if e == nil {
+		return getZeroArea(uintptr(valueSize)), false
+	}
+	return e.value, true
+}
+
+// m[k] = v
+func mapAssign(mp *hmap, key interface{}) uintptr {
+	if mp == nil {
+		panic(plainError("assignment to entry in nil map"))
+	}
+	var hash uintptr = hashKey(key)
+	var e *mapEntry = mapLookup(mp, key, hash)
+	if e != nil {
+		return e.value
+	}
+	if mp.count >= len(mp.buckets)*2 {
+		mapGrow(mp)
+	}
+	e = new(mapEntry)
+	e.hash = hash
+	e.key = key
+	e.value = malloc(mp.valueSize)
+	var idx int = int(hash % uintptr(len(mp.buckets)))
+	e.next = mp.buckets[idx]
+	mp.buckets[idx] = e
+	mp.count++
+	return e.value
+}
+
+func mapDelete(mp *hmap, key interface{}) {
+	if mp == nil {
+		return
+	}
+	var hash uintptr = hashKey(key)
+	var idx int = int(hash % uintptr(len(mp.buckets)))
+	var prev *mapEntry
+	var e *mapEntry
+	for e = mp.buckets[idx]; e != nil; e = e.next {
+		if e.hash == hash && keyEqual(e.key, key) {
+			if prev == nil {
+				mp.buckets[idx] = e.next
+			} else {
+				prev.next = e.next
+			}
+			e.deleted = true
+			mp.count--
+			return
+		}
+		prev = e
+	}
+}
+
+// clear(m) deletes all the entries
+func mapClear(mp *hmap) {
+	if mp == nil {
+		return
+	}
+	var i int
+	for i = 0; i < len(mp.buckets); i++ {
+		var e *mapEntry
+		for e = mp.buckets[i]; e != nil; e = e.next {
+			e.deleted = true
+		}
+		mp.buckets[i] = nil
+	}
+	mp.count = 0
+}
+
+func mapLen(mp *hmap) int {
+	if mp == nil {
+		return 0
+	}
+	return mp.count
+}
+
+// Iteration works on a snapshot of the entries.
+// Entries deleted during the iteration are skipped.
+func mapIterInit(mp *hmap) uintptr {
+	var it *mapIter = new(mapIter)
+	it.index = -1
+	if mp == nil {
+		return uintptr(unsafe.Pointer(it))
+	}
+	var e *mapEntry
+	for _, e = range mp.buckets {
+		for e != nil {
+			it.entries = append(it.entries, e)
+			e = e.next
+		}
+	}
+	return uintptr(unsafe.Pointer(it))
+}
+
+func mapIterNext(it *mapIter) bool {
+	for {
+		it.index++
+		if it.index >= len(it.entries) {
+			return false
+		}
+		it.current = it.entries[it.index]
+		if !it.current.deleted {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the address of the boxed key
+func mapIterKey(it *mapIter) uintptr {
+	return uintptr(unsafe.Pointer(&it.current.key))
+}
+
+func mapIterValue(it *mapIter) uintptr {
+	return it.current.value
+}
+
+func Write(fd int, p []byte) int
+func Syscall(trap uintptr, a1 uintptr, a2 uintptr, a3 uintptr) uintptr
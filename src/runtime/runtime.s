@@ -27,10 +27,15 @@ runtime.rt0_go:
   addq $16,  %rax # + 16 (skip null and go to next) => envp
   movq %rax, runtime.envp+0(%rip) # envp
 
+  movq %rsp, runtime.mainStackTop(%rip) # the top of the stack scanned by the garbage collector
   callq runtime.heapInit
+  callq runtime.schedinit
+  callq runtime.initsig
 
   callq runtime.__initGlobals
   callq runtime.envInit
+  callq runtime.gcinit
+  callq runtime.minit
 
   callq main.__initGlobals
 
@@ -71,6 +76,215 @@ runtime.printstring:
   popq %rax # retval
   ret
 
+// func memzeropad(addr1 uintptr, size uintptr)
+runtime.memzeropad:
+  movq  8(%rsp), %rdi # addr1
+  movq 16(%rsp), %rcx # size
+  xorl %eax, %eax
+  rep stosb
+  ret
+
+// func getsp() uintptr
+runtime.getsp:
+  leaq 8(%rsp), %rax # sp of the caller
+  movq %rax, 8(%rsp) # r0 uintptr
+  ret
+
+// install runtime.sigtramp as the handler of SIGSEGV and SIGBUS
+runtime.initsig:
+  subq $32, %rsp # struct sigaction
+  leaq runtime.sigtramp(%rip), %rax
+  movq %rax, 0(%rsp) # sa_handler
+  movq $0x0C000004, 8(%rsp) # sa_flags = SA_RESTORER|SA_ONSTACK|SA_SIGINFO
+  leaq runtime.sigreturn(%rip), %rax
+  movq %rax, 16(%rsp) # sa_restorer
+  movq $0, 24(%rsp) # sa_mask
+  movq $11, %rdi # SIGSEGV
+  movq %rsp, %rsi # act
+  movq $0, %rdx # oldact
+  movq $8, %r10 # sizeof(sa_mask)
+  movq $13, %rax # sys_rt_sigaction
+  syscall
+  movq $7, %rdi # SIGBUS
+  movq %rsp, %rsi # act
+  movq $0, %rdx # oldact
+  movq $8, %r10 # sizeof(sa_mask)
+  movq $13, %rax # sys_rt_sigaction
+  syscall
+  addq $32, %rsp
+  ret
+
+// the signal handler, called on the signal stack with sig in %rdi, siginfo in %rsi and ucontext in %rdx.
+// It makes the faulting instruction look like a call of runtime.sigpanic, as Go does,
+// so that the panic runs on the stack of the faulting goroutine once the handler returns.
+runtime.sigtramp:
+  pushq %rdx # ctxt
+  pushq %rsi # info
+  pushq %rdi # sig
+  callq runtime.sighandler
+  movq 16(%rsp), %rdx # ctxt
+  addq $24, %rsp
+  movq 160(%rdx), %rax # uc_mcontext.rsp
+  subq $8, %rax
+  movq 168(%rdx), %rcx # uc_mcontext.rip
+  movq %rcx, 0(%rax) # the return address of runtime.sigpanic
+  movq %rax, 160(%rdx) # uc_mcontext.rsp
+  leaq runtime.sigpanic(%rip), %rcx
+  movq %rcx, 168(%rdx) # uc_mcontext.rip
+  ret
+
+// return from the signal handler to the context in the ucontext
+runtime.sigreturn:
+  movq $15, %rax # sys_rt_sigreturn
+  syscall
+
+// func getFuncTab() uintptr
+runtime.getFuncTab:
+  leaq runtime.functab(%rip), %rax
+  movq %rax, 8(%rsp) # r0 uintptr
+  ret
+
+// func getLineTab() uintptr
+runtime.getLineTab:
+  leaq runtime.linetab(%rip), %rax
+  movq %rax, 8(%rsp) # r0 uintptr
+  ret
+
+// func getfp() uintptr
+runtime.getfp:
+  movq %rbp, 8(%rsp) # r0 uintptr: the frame pointer of the caller
+  ret
+
+// func getGlobalRoots() uintptr
+runtime.getGlobalRoots:
+  leaq runtime.globalRoots(%rip), %rax
+  movq %rax, 8(%rsp) # r0 uintptr
+  ret
+
+// func bitLen(x uintptr) int
+runtime.bitLen:
+  movq 8(%rsp), %rcx # x
+  movq $0, %rax
+  testq %rcx, %rcx
+  jz .L.bitLen.end
+  bsrq %rcx, %rax # the index of the highest bit
+  addq $1, %rax
+.L.bitLen.end:
+  movq %rax, 16(%rsp) # r0 int
+  ret
+
+// func scanRange(addr uintptr, size uintptr, step uintptr)
+runtime.scanRange:
+  movq  8(%rsp), %rsi # p
+  movq 16(%rsp), %rdi
+  addq %rsi, %rdi
+  subq $8, %rdi       # the last address of a word
+  movq 24(%rsp), %r8  # step
+.L.scanRange.loop:
+  cmpq %rdi, %rsi
+  ja .L.scanRange.end
+  movq (%rsi), %rax   # v
+  addq %r8, %rsi
+  movq %rax, %rcx
+  movabsq $0xc000000000, %rdx # arenaBase
+  subq %rdx, %rcx
+  shrq $22, %rcx      # the slot of v
+  cmpq $16384, %rcx   # arenaSlots
+  jae .L.scanRange.loop
+  movq runtime.arenaIndex(%rip), %rdx
+  movq (%rdx,%rcx,8), %rdx
+  cmpq $2, %rdx       # empty or reserved
+  jbe .L.scanRange.loop
+  testq $1, %rdx
+  jz .L.scanRange.arena
+  subq $1, %rdx       # the span of a large object
+  jmp .L.scanRange.span
+.L.scanRange.arena:
+  movq %rax, %rcx
+  shrq $13, %rcx      # pageShift
+  andq $511, %rcx     # the page in the arena
+  movq (%rdx,%rcx,8), %rdx # the span of the page
+.L.scanRange.span:
+  movq %rdx, %r9
+  movq 8(%r9), %rcx   # elemSize
+  testq %rcx, %rcx
+  jz .L.scanRange.loop # free pages
+  subq 0(%r9), %rax   # offset from start
+  xorl %edx, %edx
+  divq %rcx           # the index of the object
+  cmpq 16(%r9), %rax  # nelems
+  jae .L.scanRange.loop
+  movq 24(%r9), %rdx  # allocBits
+  cmpb $0, (%rdx,%rax)
+  je .L.scanRange.loop
+  movq 32(%r9), %rdx  # markBits
+  cmpb $0, (%rdx,%rax)
+  jne .L.scanRange.loop
+  movb $1, (%rdx,%rax)
+  cmpb $0, 40(%r9)    # noscan
+  jne .L.scanRange.loop
+  imulq %rcx, %rax
+  addq 0(%r9), %rax   # the address of the object
+  movq runtime.markTop(%rip), %rdx
+  cmpq $262144, %rdx  # markStackLen
+  je .L.scanRange.overflow
+  movq %rdx, %r10
+  shlq $4, %r10
+  addq runtime.markStack(%rip), %r10
+  movq %rax, 0(%r10)  # address
+  movq %rcx, 8(%r10)  # size
+  addq $1, %rdx
+  movq %rdx, runtime.markTop(%rip)
+  jmp .L.scanRange.loop
+.L.scanRange.overflow:
+  movq $1, runtime.markOverflow(%rip)
+  jmp .L.scanRange.loop
+.L.scanRange.end:
+  ret
+
+// func mmap(addr uintptr, length uintptr, prot int, flags int, fd int, offset int) uintptr
+runtime.mmap:
+  movq  8(%rsp), %rdi # addr
+  movq 16(%rsp), %rsi # length
+  movq 24(%rsp), %rdx # prot
+  movq 32(%rsp), %r10 # flags
+  movq 40(%rsp), %r8  # fd
+  movq 48(%rsp), %r9  # offset
+  movq $9, %rax       # sys_mmap
+  syscall
+  movq %rax, 56(%rsp) # r0 uintptr
+  ret
+
+// func returnFrom(rbp uintptr)
+runtime.returnFrom:
+  movq 8(%rsp), %rbp # frame to return from
+  leave
+  ret
+
+// func initStack(top uintptr) uintptr
+runtime.initStack:
+  movq 8(%rsp), %rax # stack top
+  leaq runtime.gostart(%rip), %rcx
+  movq %rcx, -8(%rax) # return address for gogo
+  movq $0, -16(%rax)  # saved rbp
+  subq $16, %rax
+  movq %rax, 16(%rsp) # r0 uintptr: initial sp
+  ret
+
+// func gogo(from *g, to *g)
+runtime.gogo:
+  movq  8(%rsp), %rax # from
+  movq 16(%rsp), %rcx # to
+  pushq %rbp
+  movq %rsp, 0(%rax) # from.sp
+  movq 0(%rcx), %rsp # to.sp
+  popq %rbp
+  ret
+
+runtime.gostart:
+  callq runtime.goentry
+  # not reached
+
 // func Syscall(trap, a1, a2, a3 uintptr) uintptr
 runtime.Syscall:
   movq   8(%rsp), %rax # syscall number
@@ -24,11 +24,19 @@ func Open(path string, mode int, perm int) (uintptr, int) {
 	return fd, 0
 }
 
+// the address passed for an empty buffer
+var _zero uintptr
+
 func Write(fd int, buf []byte) (uintptr, int) {
-	p := &buf[0]
+	var p unsafe.Pointer
+	if len(buf) > 0 {
+		p = unsafe.Pointer(&buf[0])
+	} else {
+		p = unsafe.Pointer(&_zero)
+	}
 	_len := len(buf)
 	var ret uintptr
-	ret = Syscall(SYS_WRITE, uintptr(fd), uintptr(unsafe.Pointer(p)), uintptr(_len))
+	ret = Syscall(SYS_WRITE, uintptr(fd), uintptr(p), uintptr(_len))
 	return ret, 0
 }
 
@@ -4,15 +4,2396 @@ import (
 	"github.com/DQNEO/babygo/lib/token"
 	"os"
 	"reflect"
+	"runtime"
 	"syscall"
 
 	"github.com/DQNEO/babygo/lib/fmt"
+	"github.com/DQNEO/babygo/lib/math"
 	"github.com/DQNEO/babygo/lib/mylib"
 	"github.com/DQNEO/babygo/lib/path"
 	"github.com/DQNEO/babygo/lib/strconv"
 	"github.com/DQNEO/babygo/lib/strings"
+	"github.com/DQNEO/babygo/lib/unicode/utf8"
 )
 
+type sizedRecord struct {
+	tag   int8
+	count uint32
+	delta int16
+	total int64
+}
+
+var gInt8 int8 = -5
+var gUint32 uint32 = 4000000000
+var gInt16 int16
+
+func addInt16(a int16, b int16) int16 {
+	return a + b
+}
+
+func splitUint16(x uint16) (uint8, int8) {
+	return uint8(x >> 8), int8(x)
+}
+
+func describeSized(x interface{}) string {
+	switch x.(type) {
+	case int8:
+		return "int8"
+	case int16:
+		return "int16"
+	case int32:
+		return "int32"
+	case int64:
+		return "int64"
+	case uint:
+		return "uint"
+	case uint32:
+		return "uint32"
+	case uint64:
+		return "uint64"
+	}
+	return "other"
+}
+
+type sample struct {
+	name   string
+	ratio  float32
+	weight float64
+}
+
+const halfPi = 3.14159265358979 / 2
+const kilo float64 = 1e3
+
+var gAverage float64 = 2.5
+var gScale float32 = 0.1
+var gCount float64 = 3
+
+func average(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func percentage(part int, total int) float64 {
+	return float64(part) * 100 / float64(total)
+}
+
+func scale32(x float32, by float32) float32 {
+	return x * by
+}
+
+func describeFloat(x interface{}) string {
+	switch v := x.(type) {
+	case float64:
+		return "float64 " + fmt.Sprintf("%g", v)
+	case float32:
+		return "float32 " + fmt.Sprintf("%g", v)
+	}
+	return "other"
+}
+
+func testFloats() {
+	var a = 1.5
+	var b float64 = 2
+	fmt.Printf("%f %f %f %f\n", a+b, a-b, a*b, a/b)
+	fmt.Printf("%g %g %g\n", .25, 1e3, 6.02e23)
+	fmt.Printf("%g %g %.3g\n", 0.1, 1.0/3, 2.0/3)
+	fmt.Printf("%.2f %.0f %e %.1e\n", 3.14159, 2.5, 123456.789, 0.000123)
+	fmt.Printf("%g %g\n", halfPi, kilo*2)
+
+	var c = -a
+	c *= 2
+	c -= 0.5
+	fmt.Printf("%g\n", c)
+	if a < b && b > a && a <= 1.5 && b >= 2 && a != b && !(a == b) {
+		writeln("float comparisons")
+	}
+
+	var n = 7
+	var f = float64(n) / 2
+	fmt.Printf("%g %d %d\n", f, int(f), int(-f))
+	var big uint64 = 18000000000000000000
+	var fbig = float64(big)
+	fmt.Printf("%g %d\n", fbig, int(uint64(fbig)/1000000000000))
+	var neg = -100.9
+	var i8 = int8(neg)
+	writeln(int(i8))
+
+	var s32 float32 = 0.1
+	var s64 float64 = float64(s32)
+	fmt.Printf("%g %g %g\n", s32, s64, float64(scale32(s32, 3)))
+	var total float32
+	var i int
+	for i = 0; i < 10; i++ {
+		total += s32
+	}
+	fmt.Printf("%g\n", total)
+
+	smp := &sample{name: "x", ratio: 0.75, weight: 60.5}
+	smp.ratio = smp.ratio * 2
+	fmt.Printf("%s %g %g\n", smp.name, smp.ratio, smp.weight)
+
+	fmt.Printf("%.2f %.1f%%\n", average([]float64{1, 2.5, 4}), percentage(1, 3))
+	fmt.Printf("%g %g %g\n", gAverage, gScale, gCount)
+	writeln(describeFloat(2.5))
+	writeln(describeFloat(float32(0.5)))
+
+	var zero float64
+	var inf = 1 / zero
+	var nan = zero / zero
+	fmt.Printf("%g %g %g %g\n", inf, -inf, nan, -zero)
+	if nan != nan && !(nan == nan) && !(nan < 1) && !(nan > 1) && math.IsNaN(nan) && math.IsInf(inf, 1) {
+		writeln("NaN is unordered")
+	}
+	fmt.Printf("%g %g\n", strconv.ParseFloat("12.5e-1"), strconv.ParseFloat("-0.125"))
+	writeln(strconv.FormatFloat(1234.5678, 'f', 2, 64))
+}
+
+type Animal struct {
+	name string
+	legs int
+}
+
+func (a Animal) Describe() string {
+	return a.name + " has " + strconv.Itoa(a.legs) + " legs"
+}
+
+func (a *Animal) Rename(name string) {
+	a.name = name
+}
+
+type Owner struct {
+	owner string
+}
+
+func (o *Owner) OwnerName() string {
+	return o.owner
+}
+
+type Dog struct {
+	Animal
+	*Owner
+	mylib.Type
+	breed string
+}
+
+func (d Dog) Sound() string {
+	return "woof"
+}
+
+type Puppy struct {
+	Dog
+	age int
+}
+
+// shadows Dog.Sound
+func (p Puppy) Sound() string {
+	return "yip"
+}
+
+type Describer interface {
+	Describe() string
+	Sound() string
+}
+
+type Renamer interface {
+	Rename(name string)
+	OwnerName() string
+}
+
+// embeds an interface
+type Wrap struct {
+	Namer
+	extra int
+}
+
+type Tagged struct {
+	*Wrap
+	tag string
+}
+
+func testStructEmbedding() {
+	var d Dog = Dog{
+		Animal: Animal{name: "rex", legs: 4},
+		Owner:  &Owner{owner: "alice"},
+		breed:  "collie",
+	}
+	writeln(d.name + " " + d.breed + " " + d.owner)
+	d.legs = 3
+	writeln(d.Describe())
+	d.Rename("max")
+	writeln(d.Animal.name)
+	writeln(d.OwnerName())
+	d.Field = 7
+	writeln(d.Method())
+
+	var p *Puppy = &Puppy{age: 1}
+	p.Dog = d
+	p.name = "bit"
+	p.legs++
+	writeln(p.Describe() + " " + p.Sound() + " " + p.Dog.Sound())
+	writeln(p.owner + " " + strconv.Itoa(p.age))
+
+	var ds Describer = d
+	writeln(ds.Describe() + " " + ds.Sound())
+	ds = p
+	writeln(ds.Describe() + " " + ds.Sound())
+	var r Renamer = p
+	r.Rename("bitsy")
+	writeln(p.name + " " + r.OwnerName())
+	r = &d
+	r.Rename("rover")
+	writeln(d.Describe())
+
+	var w = Wrap{Namer: Label("label"), extra: 1}
+	writeln(w.Name() + " " + w.Namer.Name())
+	var n Namer = w
+	writeln(n.Name())
+	var tg = &Tagged{Wrap: &w, tag: "tag"}
+	n = tg
+	writeln(tg.Name() + " " + n.Name() + " " + tg.tag)
+	w.Namer = Label("relabeled")
+	writeln(tg.Name() + " " + n.Name())
+}
+
+func classifyNumber(n int) string {
+	var s string
+	switch n {
+	case -3, -2, -1:
+		s = s + "negative "
+		fallthrough
+	case 0:
+		s = s + "small "
+	case 500:
+		s = s + "large "
+		fallthrough
+	default:
+		s = s + "number"
+	}
+	return s
+}
+
+func findPair(xs []int, sum int) string {
+	for i := 0; i < len(xs); i++ {
+		for j := i + 1; j < len(xs); j++ {
+			if xs[i]+xs[j] == sum {
+				goto found
+			}
+		}
+	}
+	return "none"
+found:
+	return "found"
+}
+
+const escapedGreeting = "tab\there \x41\102\u00e9" + "\\end"
+
+const rawTemplate = `<p class="x">
+	{{.Name}} \n
+</p>`
+
+type counter struct {
+	name  string
+	count int
+}
+
+func (c *counter) incr(n int) int {
+	c.count = c.count + n
+	return c.count
+}
+
+func (c counter) label(prefix string) string {
+	return prefix + c.name + ":" + strconv.Itoa(c.count)
+}
+
+func (c counter) split() (string, int) {
+	return c.name, c.count
+}
+
+func (c *counter) sum(nums ...int) int {
+	for _, n := range nums {
+		c.count = c.count + n
+	}
+	return c.count
+}
+
+func cmdHello(args []string) string {
+	return "hello " + args[0] + "," + strconv.Itoa(len(args))
+}
+
+func cmdCount(args []string) string {
+	return strconv.Itoa(len(args))
+}
+
+type command struct {
+	name    string
+	handler func(args []string) string
+}
+
+var commandTable = map[string]func(args []string) string{
+	"hello": cmdHello,
+	"count": cmdCount,
+}
+
+func applyInt(f func(int) int, x int) int {
+	return f(x)
+}
+
+func twice(x int) int {
+	return x * 2
+}
+
+func pair() (int, string) {
+	return 7, "seven"
+}
+
+func forwardPair() (int, string) {
+	return pair()
+}
+
+func testFuncValues() {
+	var f func(int) int = twice
+	writeln(f(21))
+	writeln(applyInt(twice, 5))
+	g := strconv.Itoa
+	writeln(g(123) + "!")
+	writeln(commandTable["hello"]([]string{"a", "b"}))
+	writeln(commandTable["count"]([]string{"a", "b", "c"}))
+	cmds := []command{command{name: "hi", handler: cmdHello}, command{name: "n", handler: cmdCount}}
+	for _, cmd := range cmds {
+		writeln(cmd.name + " => " + cmd.handler([]string{"x"}))
+	}
+	if f != nil {
+		writeln("func value is not nil")
+	}
+
+	c := &counter{name: "c", count: 1}
+	inc := c.incr
+	writeln(inc(2))
+	writeln(inc(3))
+	writeln(c.count)
+	lbl := c.label
+	c.count = 100
+	writeln(lbl("before "))
+	writeln(c.label("after "))
+	var v counter
+	v.name = "v"
+	vinc := v.incr
+	vinc(5)
+	writeln(v.count)
+	sumAll := c.sum
+	writeln(sumAll(1, 2, 3))
+	writeln(applyInt(c.incr, 10))
+
+	var fns []func(string) string
+	counters := []counter{counter{name: "a"}, counter{name: "b"}}
+	for _, cn := range counters {
+		fns = append(fns, cn.label)
+	}
+	for _, fn := range fns {
+		writeln(fn("> "))
+	}
+
+	incr := (*counter).incr
+	writeln(incr(c, 1))
+	label := counter.label
+	writeln(label(v, "expr "))
+	writeln(counter.label(v, "direct "))
+	split := counter.split
+	var name, count = split(v)
+	writeln(name + strconv.Itoa(count))
+
+	var d Describer = Dog{Animal: Animal{name: "rex", legs: 4}}
+	desc := d.Describe
+	writeln(desc())
+	describe := Describer.Describe
+	writeln(describe(d))
+
+	var n, s = forwardPair()
+	writeln(strconv.Itoa(n) + s)
+}
+
+type gcNode struct {
+	id   int
+	name string
+	next *gcNode
+}
+
+var gcList *gcNode
+
+func testGC() {
+	var i int
+	for i = 0; i < 1000; i++ {
+		gcList = &gcNode{id: i, name: "node" + strconv.Itoa(i), next: gcList}
+	}
+	// 800MB of garbage triggers collections, which must keep gcList and ptrs intact
+	var buf []uint8
+	for i = 0; i < 100; i++ {
+		buf = make([]uint8, 8*1024*1024)
+		buf[i] = uint8(i)
+	}
+	ptrs := make([]*int, 0)
+	for i = 0; i < 10000; i++ {
+		x := new(int)
+		*x = i
+		ptrs = append(ptrs, x)
+	}
+	runtime.GC()
+	total := 0
+	broken := 0
+	for n := gcList; n != nil; n = n.next {
+		total = total + n.id
+		if n.name != "node"+strconv.Itoa(n.id) {
+			broken++
+		}
+	}
+	for i = 0; i < len(ptrs); i++ {
+		total = total + *ptrs[i]
+	}
+	writeln("gc total " + strconv.Itoa(total) + " broken " + strconv.Itoa(broken) + " last " + strconv.Itoa(int(buf[99])))
+}
+
+func testMemStats() {
+	runtime.GC()
+	var before runtime.MemStats
+	var after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	buf := make([]uint8, 1<<20)
+	runtime.ReadMemStats(&after)
+	writeBool("HeapAlloc grows", after.HeapAlloc >= before.HeapAlloc+uint64(len(buf)))
+	writeBool("TotalAlloc grows", after.TotalAlloc-before.TotalAlloc >= uint64(len(buf)))
+	writeBool("Mallocs grows", after.Mallocs > before.Mallocs)
+	writeBool("HeapObjects", after.HeapObjects == after.Mallocs-after.Frees)
+	writeBool("HeapSys covers HeapAlloc", after.HeapSys >= after.HeapAlloc)
+	writeBool("Sys covers HeapSys", after.Sys >= after.HeapSys)
+
+	total := 0
+	for i := 0; i < 1000; i++ {
+		n := &gcNode{id: i}
+		total = total + n.id
+	}
+	runtime.GC()
+	var collected runtime.MemStats
+	runtime.ReadMemStats(&collected)
+	writeBool("NumGC grows", collected.NumGC > after.NumGC)
+	writeBool("Frees grows", collected.Frees > after.Frees)
+	writeln("garbage total " + strconv.Itoa(total) + " buf " + strconv.Itoa(len(buf)))
+}
+
+type record struct {
+	id    int8
+	name  string
+	score float64
+	_     int
+	tag   interface{}
+	pos   [2]int
+}
+
+type wrapper struct {
+	rec  record
+	tags [2]string
+}
+
+func writeBool(label string, b bool) {
+	if b {
+		writeln(label + ": true")
+	} else {
+		writeln(label + ": false")
+	}
+}
+
+func compareUncomparable(a interface{}, b interface{}) {
+	defer func() {
+		if recover() != nil {
+			writeln("comparing uncomparable values panics")
+		}
+	}()
+	writeBool("uncomparable", a == b)
+}
+
+func testEquality() {
+	r1 := record{id: 1, name: "ab", score: 1.5, tag: "t", pos: [2]int{1, 2}}
+	r2 := record{id: 1, name: "a" + "b", score: 1.5, tag: "t", pos: [2]int{1, 2}}
+	writeBool("r1 == r2", r1 == r2)
+	r2.pos[1] = 3
+	writeBool("pos differs", r1 == r2)
+	r2.pos[1] = 2
+	r2.tag = 1
+	writeBool("tag differs", r1 != r2)
+	r2.tag = "t"
+	var zero float64
+	r2.score = zero / zero
+	writeBool("NaN field", r1 == r2)
+	r1.score = r2.score
+	writeBool("NaN fields", r1 == r2)
+
+	w1 := wrapper{tags: [2]string{"x", "y"}}
+	w2 := wrapper{tags: [2]string{"x", "y"}}
+	writeBool("nested", w1 == w2)
+	w2.rec.name = "z"
+	writeBool("nested differs", w1 == w2)
+
+	a1 := [3]string{"a", "b", "c"}
+	var a2 [3]string
+	a2[0] = "a"
+	a2[1] = "b"
+	a2[2] = "c"
+	writeBool("arrays", a1 == a2)
+	a2[2] = "d"
+	writeBool("arrays differ", a1 == a2)
+
+	var i1 interface{} = "hello"
+	var i2 interface{} = "hel" + strconv.Itoa(0)[1:] + "lo"
+	writeBool("ifc strings", i1 == i2)
+	var i3 interface{} = w1
+	var i4 interface{} = wrapper{tags: [2]string{"x", "y"}}
+	writeBool("ifc structs", i3 == i4)
+	writeBool("ifc == struct", i3 == w1)
+	writeBool("struct == ifc", w2 == i3)
+	writeBool("ifc types differ", i1 == i3)
+	var i5 interface{} = 1.5
+	writeBool("ifc float", i5 == 1.5)
+	var nilIfc interface{}
+	writeBool("nil ifc", nilIfc == nil)
+	writeBool("nil == ifc", nil == i1)
+
+	var s []int
+	writeBool("nil slice", s == nil)
+	s = append(s, 0)
+	writeBool("slice == nil", s == nil)
+	writeBool("nil == slice", nil == s)
+
+	compareUncomparable(i1, []int{1})
+	compareUncomparable([]int{1}, []int{1})
+	compareUncomparable(r1, r1)
+}
+
+type minMaxPair struct {
+	lo int
+	hi int
+}
+
+type byteSlice []byte
+
+const smallest = min(3, 1, 2)
+const largest = max(1, 2.5)
+
+func makeLen() int {
+	writeln("makeLen called")
+	return 3
+}
+
+func makeBadSlice(l int, c int) {
+	defer func() {
+		if recover() != nil {
+			writeln("make panics with len " + strconv.Itoa(l) + " and cap " + strconv.Itoa(c))
+		}
+	}()
+	var s []int
+	if l < 0 {
+		s = make([]int, l)
+	} else {
+		s = make([]int, l, c)
+	}
+	writeln(len(s))
+}
+
+func makeBadChan(size int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("make panics with chan size " + strconv.Itoa(size) + ": " + r.(errorValue).Error())
+		}
+	}()
+	c := make(chan int, size)
+	writeln(cap(c))
+}
+
+func indexOutOfRange(s []int, i int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("index " + strconv.Itoa(i) + " panics: " + r.(errorValue).Error())
+		}
+	}()
+	writeln(s[i])
+}
+
+func sliceOutOfRange(s []int, low int, high int, max int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("slice [" + strconv.Itoa(low) + ":" + strconv.Itoa(high) + ":" + strconv.Itoa(max) + "] panics: " + r.(errorValue).Error())
+		}
+	}()
+	t := s[low:high:max]
+	writeln(len(t) + cap(t))
+}
+
+func stringOutOfRange(str string, low int, high int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("string [" + strconv.Itoa(low) + ":" + strconv.Itoa(high) + "] panics: " + r.(errorValue).Error())
+		}
+	}()
+	writeln(str[low:high])
+}
+
+func arrayOutOfRange(i int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("array index " + strconv.Itoa(i) + " panics: " + r.(errorValue).Error())
+		}
+	}()
+	var a [3]int
+	a[i] = 1
+	writeln(a[i])
+}
+
+var boundsCalls int
+
+func countedIndex(i int) int {
+	boundsCalls++
+	return i
+}
+
+type panicError struct {
+	code int
+}
+
+func (e *panicError) Error() string {
+	return "panicError " + strconv.Itoa(e.code)
+}
+
+type panicName string
+
+func (n panicName) String() string {
+	return "panicName " + string(n)
+}
+
+type errorValue interface {
+	Error() string
+}
+
+type stringValue interface {
+	String() string
+}
+
+func panicWith(v interface{}) {
+	defer func() {
+		switch x := recover().(type) {
+		case errorValue:
+			writeln("recovered error: " + x.Error())
+		case stringValue:
+			writeln("recovered stringer: " + x.String())
+		case int:
+			writeln("recovered int: " + strconv.Itoa(x))
+		case string:
+			writeln("recovered string: " + x)
+		default:
+			writeln("recovered something else")
+		}
+	}()
+	panic(v)
+}
+
+func testPanicValues() {
+	panicWith(&panicError{code: 7})
+	panicWith(panicName("x"))
+	panicWith(42)
+	panicWith("message")
+	panicWith([]int{1})
+}
+
+type nilPoint struct {
+	x int
+	y int
+}
+
+func (p *nilPoint) sum() int {
+	return p.x + p.y
+}
+
+func readNil(p *int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("reading through a nil pointer panics: " + r.(errorValue).Error())
+		}
+	}()
+	writeln(*p)
+}
+
+func writeNilField(p *nilPoint) {
+	defer func() {
+		if recover() != nil {
+			writeln("writing a field through a nil pointer panics")
+		}
+	}()
+	p.y = 1
+	writeln(p.y)
+}
+
+func callNilMethod(p *nilPoint) int {
+	defer func() {
+		if recover() != nil {
+			writeln("a method reading a nil receiver panics")
+		}
+	}()
+	return p.sum()
+}
+
+type bigNilStruct struct {
+	a int
+	b [100000]int
+	c int
+}
+
+func recoverFarNil() {
+	var r = recover()
+	if r != nil {
+		writeln("reading far from a nil pointer panics: " + r.(errorValue).Error())
+	}
+}
+
+// the offsets are beyond the page at address 0
+func readFarField(p *bigNilStruct) {
+	defer recoverFarNil()
+	writeln(p.c)
+}
+
+func readFarElement(p *bigNilStruct, i int) {
+	defer recoverFarNil()
+	writeln(p.b[i])
+}
+
+func testNilDereference() {
+	readNil(nil)
+	var p *nilPoint
+	writeNilField(p)
+	writeln(callNilMethod(p))
+	var bp *bigNilStruct
+	readFarField(bp)
+	readFarElement(bp, 99999)
+	bp = &bigNilStruct{c: 4}
+	bp.b[99999] = 5
+	readFarField(bp)
+	readFarElement(bp, 99999)
+	n := 3
+	readNil(&n)
+	p = &nilPoint{x: 1, y: 2}
+	writeln(callNilMethod(p))
+}
+
+func testBoundsCheck() {
+	s := make([]int, 3, 5)
+	indexOutOfRange(s, 2)
+	indexOutOfRange(s, 3)
+	indexOutOfRange(s, -1)
+	sliceOutOfRange(s, 1, 5, 5)
+	sliceOutOfRange(s, 0, 3, 6)
+	sliceOutOfRange(s, 2, 1, 5)
+	sliceOutOfRange(s, 1, 4, 3)
+	sliceOutOfRange(s, -1, 2, 3)
+	stringOutOfRange("abc", 1, 3)
+	stringOutOfRange("abc", 3, 3)
+	stringOutOfRange("abc", 2, 4)
+	stringOutOfRange("abc", 2, 1)
+	arrayOutOfRange(2)
+	arrayOutOfRange(3)
+
+	defer func() {
+		if recover() != nil {
+			writeln("slicing beyond the length panics")
+		}
+	}()
+	t := s[countedIndex(1):countedIndex(4)]
+	writeln(len(t) + cap(t))
+	writeln(boundsCalls)
+	str := "hello"
+	writeln(str[countedIndex(5):])
+	writeln(boundsCalls)
+	writeln(str[countedIndex(6):])
+}
+
+func testMakeNewMinMaxClear() {
+	s1 := make([]int, 4)
+	writeln(len(s1))
+	writeln(cap(s1))
+	s1[3] = 7
+	writeln(s1[3])
+	s2 := make([]string, makeLen())
+	writeln(len(s2) + cap(s2))
+	var n int = 2
+	s3 := make([]minMaxPair, n, n*3)
+	writeln(cap(s3))
+	s4 := make(byteSlice, 5)
+	writeln(len(s4))
+	m := make(map[string]int, 10)
+	m["a"] = 1
+	writeln(len(m))
+	c := make(chan int, 2)
+	writeln(cap(c))
+
+	makeBadSlice(-1, 0)
+	makeBadSlice(n+1, n)
+	// the sizes in bytes overflow
+	makeBadSlice(1<<62, 1<<62)
+	makeBadSlice(0, 1<<61)
+	makeBadChan(1 << 61)
+	var hint = 1 << 62
+	hm := make(map[int]int, hint)
+	hm[1] = 2
+	writeln(len(hm) + hm[1])
+
+	pi := new(int)
+	*pi = *pi + 5
+	writeln(*pi)
+	ps := new(string)
+	writeln("[" + *ps + "]")
+	pa := new([3]int)
+	arr := *pa
+	writeln(arr[0] + arr[1] + arr[2])
+	pp := new(minMaxPair)
+	writeln(pp.lo + pp.hi)
+	psl := new([]int)
+	*psl = append(*psl, 1)
+	writeln(len(*psl))
+	pm := new(map[string]int)
+	writeln(len(*pm))
+	pf := new(float64)
+	writeln(strconv.Itoa(int(*pf + 1.5)))
+	pe := new(interface{})
+	if *pe == nil {
+		writeln("new interface is nil")
+	}
+	pb := new(bool)
+	if !*pb {
+		writeln("new bool is false")
+	}
+
+	writeln(smallest)
+	writeln(int(largest * 2))
+	var a int = 10
+	var b int = -4
+	writeln(min(a, b))
+	writeln(max(a, b, 12))
+	writeln(min(a))
+	var u1 uint8 = 200
+	var u2 uint8 = 100
+	writeln(int(max(u1, u2)))
+	writeln(int(min(u1, u2, 150)))
+	var i8 int8 = -100
+	writeln(int(min(i8, 5)))
+	var f1 float64 = 1.5
+	var f2 float64 = -2.25
+	writeln(strconv.Itoa(int(max(f1, f2, 0) * 100)))
+	writeln(strconv.Itoa(int(min(f1, f2, 0) * 100)))
+	var zero float64 = 0
+	negZero := -zero
+	if 1/min(zero, negZero) < 0 {
+		writeln("min(0, -0) is -0")
+	}
+	if 1/max(negZero, zero) > 0 {
+		writeln("max(-0, 0) is 0")
+	}
+	nan := zero / zero
+	if min(f1, nan) != min(f1, nan) {
+		writeln("min with NaN is NaN")
+	}
+	writeln(min("banana", "apple", "cherry"))
+	writeln(max("banana", "apple", "cherry"))
+	var word string = "ab"
+	writeln(max(word, "a", "abc"))
+	writeln(min(word, "a", "abc"))
+
+	cm := map[string]int{"x": 1, "y": 2, "z": 3}
+	clear(cm)
+	writeln(len(cm))
+	cm["w"] = 4
+	writeln(len(cm))
+	writeln(cm["w"])
+	var nilMap map[int]int
+	clear(nilMap)
+	cs := []minMaxPair{minMaxPair{lo: 1, hi: 2}, minMaxPair{lo: 3, hi: 4}, minMaxPair{lo: 5, hi: 6}}
+	clear(cs[1:])
+	writeln(cs[0].lo + cs[0].hi + cs[1].lo + cs[2].hi)
+	writeln(len(cs))
+	strs := []string{"a", "b"}
+	clear(strs)
+	writeln("[" + strs[0] + strs[1] + "]")
+}
+
+type point3 struct {
+	x   int
+	y   int
+	z   int
+	tag string
+}
+
+func testAppendCopy() {
+	var pts []point3
+	for i := 0; i < 5; i++ {
+		pts = append(pts, point3{x: i, y: i * i, z: -i, tag: "p" + strconv.Itoa(i)})
+	}
+	pts = append(pts, point3{x: 10}, point3{x: 20, tag: "last"})
+	writeln(len(pts))
+	for _, p := range pts {
+		write(strconv.Itoa(p.x+p.y+p.z) + p.tag + " ")
+	}
+	writeln("")
+
+	ints := []int{1, 2}
+	ints = append(ints, 3, 4, 5)
+	more := []int{6, 7}
+	ints = append(ints, more...)
+	ints = append(ints)
+	ints = append(ints[:2], ints[3:]...)
+	for _, v := range ints {
+		write(strconv.Itoa(v) + " ")
+	}
+	writeln("")
+
+	var bs []byte
+	bs = append(bs, "hello"...)
+	bs = append(bs, ' ')
+	bs = append(bs, []byte("world")...)
+	writeln(string(bs))
+
+	var ifcs []interface{}
+	ifcs = append(ifcs, 1, "two", 3)
+	writeln(len(ifcs))
+	writeln(ifcs[1])
+
+	dst := make([]int, 3, 3)
+	n := copy(dst, ints)
+	writeln(n)
+	writeln(dst[0] + dst[1] + dst[2])
+	n = copy(ints, []int{9})
+	writeln(n)
+	writeln(ints[0])
+	n = copy(ints[1:], ints)
+	writeln(strconv.Itoa(ints[0]) + strconv.Itoa(ints[1]) + strconv.Itoa(ints[2]) + strconv.Itoa(ints[3]))
+	buf := make([]byte, 4, 4)
+	n = copy(buf, "golang")
+	writeln(n)
+	writeln(string(buf))
+
+	var big []point3
+	big = append(big, pts...)
+	copy(big[1:3], pts[4:])
+	writeln(big[1].tag + big[2].tag)
+}
+
+func 二倍(x int) int {
+	return x * 2
+}
+
+func testUnicode() {
+	var s = "aé世😀"
+	writeln(len(s))
+	for i, r := range s {
+		writeln(strconv.Itoa(i) + ":" + strconv.Itoa(int(r)) + ":" + string(r))
+	}
+	var n int
+	for _, r := range s {
+		if r > 0 {
+			n++
+		}
+	}
+	writeln(n)
+	for i := range "héllo" {
+		write(strconv.Itoa(i) + " ")
+	}
+	writeln("")
+	for _, r := range "a\xffb" {
+		write(strconv.Itoa(int(r)) + " ")
+	}
+	writeln("")
+	var last rune
+	for _, last = range "xyz" {
+	}
+	writeln(int(last))
+
+	runes := []rune(s)
+	writeln(len(runes))
+	writeln(int(runes[2]))
+	runes[0] = 'A'
+	writeln(string(runes))
+	writeln(string(rune(26085)) + string('本'))
+	var b byte = 65
+	writeln(string(b))
+	if string(rune(-1)) == "\uFFFD" {
+		writeln("invalid code point ok")
+	}
+	writeln(int('世'))
+
+	var 名前 = "gopher"
+	writeln(名前)
+	writeln(二倍(21))
+
+	var r, size = utf8.DecodeRuneInString("世界")
+	writeln(int(r))
+	writeln(size)
+	r, size = utf8.DecodeRuneInString("\xe4")
+	if r == utf8.RuneError {
+		writeln("RuneError ok")
+	}
+	writeln(size)
+	writeln(utf8.RuneLen('a'))
+	writeln(utf8.RuneLen('é'))
+	writeln(utf8.RuneLen(utf8.MaxRune))
+	writeln(utf8.RuneLen(-1))
+	buf := make([]byte, utf8.UTFMax, utf8.UTFMax)
+	n = utf8.EncodeRune(buf, '😀')
+	writeln(string(buf[:n]))
+	if utf8.ValidString(s) && !utf8.ValidString("a\xc0\x80") {
+		writeln("ValidString ok")
+	}
+}
+
+// The non-canonical forms, which gofmt rewrites, are tested in t/testdata/run/literals.go.
+func testLiterals() {
+	writeln(0x1F + 0xFF)
+	writeln(0o17 + 0o1 + 017)
+	writeln(0b1011 | 0b1)
+	writeln(1_000_000 + 0x_FF_FF)
+	writeln(0)
+	var mask uint8 = 0b1111_0000
+	writeln(int(mask >> 4))
+	var f = 1_000.5
+	writeln(int(f * 2))
+
+	writeln(int('\x41'))
+	writeln(int('\101'))
+	writeln(int('\u00e9'))
+	writeln(int('\U0001F600'))
+	writeln(int('\a' + '\b' + '\f' + '\v'))
+	writeln(int('\\'))
+	writeln(int('"'))
+
+	var s = "\x41\102C\u00e9\"q\""
+	writeln(len(s))
+	writeln(int(s[3]))
+	writeln(int(s[4]))
+	writeln(s[0:3])
+	writeln(escapedGreeting)
+	writeln(len(escapedGreeting))
+	writeln(strconv.Quote("a\"b\\c\n\x01"))
+	writeln(len("\\\\"))
+
+	writeln(rawTemplate)
+	writeln(len(`\n`))
+	writeln(`back\slash` + ` "quoted"`)
+
+	var x = 1 /* inline */ + 2
+	var y = x /* a comment
+	spanning lines ends the statement */
+	writeln(y)
+	writeln(x /* before a paren */)
+}
+
+func testLabels() {
+	var s string
+outer:
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if j == 2 {
+				continue outer
+			}
+			if i == 3 {
+				break outer
+			}
+			s = s + strconv.Itoa(i) + strconv.Itoa(j) + " "
+		}
+	}
+	writeln(s)
+
+	s = ""
+	for i := 0; i < 3; i++ {
+		switch i {
+		case 1:
+			s = s + "one "
+			break
+		default:
+			s = s + "other "
+		}
+		s = s + "| "
+	}
+	writeln(s)
+
+	s = ""
+	words := []string{"a", "b", "stop", "c"}
+loop:
+	for _, word := range words {
+		switch word {
+		case "stop":
+			break loop
+		}
+		s = s + word
+	}
+	writeln(s)
+
+	var ch = make(chan int, 1)
+	ch <- 5
+sel:
+	select {
+	case v := <-ch:
+		if v == 5 {
+			break sel
+		}
+		writeln("not reached")
+	}
+	writeln("after select")
+
+	var n int
+again:
+	n++
+	if n < 5 {
+		goto again
+	}
+	writeln(n)
+
+	writeln(classifyNumber(-3))
+	writeln(classifyNumber(0))
+	writeln(classifyNumber(500))
+	writeln(classifyNumber(50))
+	writeln(findPair([]int{1, 4, 6}, 10))
+	writeln(findPair([]int{1, 4, 6}, 3))
+
+	var count int
+	f := func() int {
+		var k int
+	again:
+		k++
+		if k < 3 {
+			goto again
+		}
+		return k
+	}
+	count = f()
+	writeln(count)
+}
+
+func testSizedIntegers() {
+	var a int8 = 127
+	a++
+	writeln(int(a))
+	var b int8 = 100
+	writeln(int(b + b))
+	var c uint8 = 250
+	writeln(int(c + 10))
+	var d int16 = 32767
+	d += 2
+	writeln(int(d))
+	var e uint16 = 3
+	writeln(int(e - 4))
+	var f int32 = 2147483647
+	writeln(int(f + 1))
+	var g uint32 = 4294967295
+	writeln(int(g + 1))
+	writeln(int(g * 2))
+
+	var n = 200
+	writeln(int(int8(n)))
+	var m = -1
+	writeln(int(uint8(m)))
+	writeln(int(uint32(m)))
+	var big = 70000
+	writeln(int(int16(big)))
+	var i64 int64 = 9000000000
+	writeln(int(i64 * 2))
+
+	var r rune = 'a'
+	var r32 int32 = r + 1
+	writeln(int(r32))
+
+	var u uint = 0
+	u--
+	if u > 100 {
+		writeln("uint wraps around to the maximum")
+	}
+	var u64 uint64 = 0
+	u64 = u64 - 1
+	writeln(int(u64 / 2))
+	writeln(int(u64 % 10))
+	writeln(int(u64 >> 60))
+	var s8 int8 = -128
+	writeln(int(s8 >> 3))
+	writeln(int(s8 / -1))
+	var su uint8 = 1
+	writeln(int(su << 9))
+	writeln(int(^su))
+
+	writeln(int(addInt16(30000, 30000)))
+	var hi, lo = splitUint16(511)
+	writeln(int(hi))
+	writeln(int(lo))
+
+	rec := sizedRecord{tag: -3, count: 3000000000, delta: -300, total: -1}
+	rec.count += 1500000000
+	writeln(int(rec.tag))
+	writeln(int(rec.count))
+	writeln(int(rec.delta))
+	writeln(int(rec.total))
+
+	var runes = []int32{'x', -2, 70000}
+	var sum int32
+	for _, v := range runes {
+		sum += v
+	}
+	writeln(int(sum))
+
+	writeln(int(gInt8))
+	writeln(int(gUint32))
+	gInt16 = -1
+	writeln(int(gInt16))
+
+	writeln(describeSized(a))
+	writeln(describeSized(d))
+	writeln(describeSized(r))
+	writeln(describeSized(i64))
+	writeln(describeSized(u))
+	writeln(describeSized(g))
+	writeln(describeSized(u64))
+}
+
+func divideBy(x int, y int) int {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("recovered from a division by zero: " + r.(errorValue).Error())
+		}
+	}()
+	return x / y
+}
+
+func testSignedDivision() {
+	a := -7
+	b := 2
+	writeln(a / b)
+	writeln(a % b)
+	writeln(-a / -b)
+	writeln(-a % -b)
+	writeln(a / -b)
+	writeln(a % -b)
+	var u uint8 = 200
+	var v uint8 = 3
+	writeln(int(u / v))
+	writeln(int(u % v))
+	var minInt = -9223372036854775807 - 1
+	var minusOne = -1
+	writeln(minInt / minusOne)
+	writeln(minInt % minusOne)
+
+	x := -100
+	x /= 7
+	writeln(x)
+	x %= 4
+	writeln(x)
+
+	divideBy(1, 0)
+	var zero = 0
+	defer func() {
+		if recover() != nil {
+			writeln("recovered from a modulo by zero")
+		}
+	}()
+	writeln(a % zero)
+}
+
+func hashBytes(b []uint8) int {
+	var h int = 2166136261
+	for _, c := range b {
+		h ^= int(c)
+		h = (h * 16777619) & 4294967295
+	}
+	return h
+}
+
+func shiftByNegative(n int) {
+	defer func() {
+		var r = recover()
+		if r != nil {
+			writeln("recovered from a negative shift: " + r.(errorValue).Error())
+		}
+	}()
+	writeln(1 << n)
+}
+
+func testBitwiseOps() {
+	a := 12
+	b := 10
+	writeln(a & b)
+	writeln(a | b)
+	writeln(a ^ b)
+	writeln(a &^ b)
+	writeln(^a)
+	writeln(1 + 2<<3)
+	writeln(a | b&3)
+
+	var n = 4
+	writeln(a << n)
+	writeln(a >> 2)
+	writeln(-a >> 1)
+	writeln(-1 >> n)
+	var u uint8 = 200
+	writeln(int(u >> 3))
+	var big = 70
+	writeln(1 << big)
+	writeln(-8 >> big)
+	writeln(8 >> big)
+
+	x := 7
+	x &= 6
+	writeln(x)
+	x |= 9
+	writeln(x)
+	x ^= 5
+	writeln(x)
+	x <<= 3
+	writeln(x)
+	x >>= 1
+	writeln(x)
+	x &^= 8
+	writeln(x)
+	x *= 3
+	writeln(x)
+	x /= 4
+	writeln(x)
+	x %= 5
+	writeln(x)
+
+	writeln(hashBytes([]uint8("babygo")))
+	shiftByNegative(-1)
+}
+
+const bufUnit = 4
+const bufSize = bufUnit * 2
+
+const (
+	kib     = 1 << 10
+	mib     = kib << 10
+	halfMib = mib >> 1
+	negHalf = -7 >> 1
+)
+
+const greeting = "hello, " + "const"
+const greetingLen = len(greeting)
+const maxByte uint8 = 255
+const byteLimit = uint8(maxByte / 5)
+const isBig = greetingLen > 10 && kib == 1024
+const nextChar = 'a' + 1
+
+var gFolded int = bufSize*3 + 1
+var gFoldedStr string = greeting
+var gFoldedByte uint8 = maxByte - 1
+var gFoldedBool bool = !isBig
+
+func testConstFolding() {
+	var buf [bufSize * 2]byte
+	writeln(len(buf))
+	writeln(kib)
+	writeln(mib)
+	writeln(halfMib)
+	writeln(negHalf)
+	writeln(greeting)
+	writeln(greetingLen)
+	writeln(int(maxByte))
+	writeln(int(byteLimit))
+	if isBig {
+		writeln("greeting is big")
+	}
+	writeln(int(nextChar))
+	writeln(gFolded)
+	writeln(gFoldedStr)
+	writeln(int(gFoldedByte))
+	if !gFoldedBool {
+		writeln("gFoldedBool is false")
+	}
+	switch 8 {
+	case bufUnit:
+		writeln("bufUnit")
+	case bufUnit * 2:
+		writeln("bufUnit * 2")
+	}
+
+	const localSize = bufSize / 3
+	var arr [localSize + 1]int
+	writeln(len(arr))
+	const localGreeting = greeting + "!"
+	writeln(localGreeting)
+	writeln(17 % bufUnit)
+}
+
+const maxUint64 uint64 = 1<<64 - 1
+const hugeShift = 1 << 70
+
+var gMaxUint64 uint64 = 18446744073709551615
+
+func formatUint64(x uint64) string {
+	if x < 10 {
+		return string([]uint8{uint8('0' + x)})
+	}
+	return formatUint64(x/10) + string([]uint8{uint8('0' + x%10)})
+}
+
+// untyped constants are exact beyond the range of int and float64
+func testBigConsts() {
+	var a uint64 = ^uint64(0)
+	var b uint64 = 18446744073709551615
+	writeln(formatUint64(maxUint64))
+	writeln(formatUint64(a))
+	writeln(formatUint64(b))
+	writeln(formatUint64(gMaxUint64))
+	writeln(hugeShift >> 65)
+	writeln(hugeShift / (1 << 60))
+	writeln(hugeShift % 1000 * -1 >> 1)
+	fmt.Printf("%g %g\n", 0.1+0.2, 1e400/1e390)
+	if 0.1+0.2 == 0.3 {
+		writeln("0.1 + 0.2 == 0.3")
+	}
+	var third float32 = 1.0 / 3
+	fmt.Printf("%g\n", float64(third))
+	var two int = 2.0
+	writeln(two)
+}
+
+type Weekday int
+
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+
+const (
+	kiloUnit = 1000 * (iota + 1)
+	megaUnit
+	gigaUnit
+)
+
+const (
+	iotaFirst, iotaSecond = iota, iota + 10
+	iotaThird, iotaFourth
+)
+
+var (
+	groupedVar1 int = 7
+	groupedVar2     = "grouped"
+)
+
+var multiA, multiB int = 1, 2
+
+type (
+	groupedElem struct {
+		x int
+	}
+	groupedCount int
+)
+
+func divmod(a int, b int) (int, int) {
+	return a / b, a % b
+}
+
+func testGroupedDecls() {
+	writeln(int(Sunday))
+	writeln(int(Tuesday))
+	var day Weekday = Monday
+	writeln(int(day))
+	writeln(kiloUnit)
+	writeln(gigaUnit)
+	writeln(iotaFirst)
+	writeln(iotaSecond)
+	writeln(iotaFourth)
+	writeln(groupedVar1)
+	writeln(groupedVar2)
+	writeln(multiA + multiB)
+
+	var (
+		lx, ly = 3, "three"
+		lz     int
+	)
+	writeln(lx)
+	writeln(ly)
+	writeln(lz)
+	var q, r = divmod(17, 5)
+	writeln(q)
+	writeln(r)
+
+	const (
+		localA = iota * 2
+		localB
+	)
+	writeln(localB)
+
+	var elem groupedElem = groupedElem{x: 5}
+	var count groupedCount = 6
+	writeln(elem.x + int(count))
+}
+
+type Shape interface {
+	Area() int
+	Name() string
+}
+
+type Scaler interface {
+	Shape
+	Scale(n int)
+}
+
+type Namer interface {
+	Name() string
+}
+
+type Rect struct {
+	w int
+	h int
+}
+
+func (r Rect) Area() int {
+	return r.w * r.h
+}
+
+func (r Rect) Name() string {
+	return "rect"
+}
+
+func (r *Rect) Scale(n int) {
+	r.w = r.w * n
+	r.h = r.h * n
+}
+
+type Square int
+
+func (sq Square) Area() int {
+	return int(sq) * int(sq)
+}
+
+func (sq *Square) Name() string {
+	return "square"
+}
+
+type Label string
+
+func (l Label) Name() string {
+	return string(l)
+}
+
+func describeShape(sh Shape) {
+	writeln(sh.Name() + ":" + strconv.Itoa(sh.Area()))
+}
+
+func testInterfaceMethods() {
+	var sh Shape = Rect{w: 2, h: 3}
+	describeShape(sh)
+	var sq Square = 4
+	describeShape(&sq)
+
+	var shapes []Shape = []Shape{Rect{w: 1, h: 1}, &sq}
+	for _, x := range shapes {
+		describeShape(x)
+	}
+
+	r := &Rect{w: 1, h: 2}
+	var sc Scaler = r
+	sc.Scale(3)
+	describeShape(sc)
+	writeln(r.w)
+
+	var nm Namer = Label("label")
+	writeln(nm.Name())
+	nm = sc
+	writeln(nm.Name())
+	writeln(Namer(Label("converted")).Name())
+}
+
+func testNonEmptyInterfaceAssertion() {
+	var sq Square = 5
+	var e interface{} = &sq
+	sh, ok := e.(Shape)
+	if ok {
+		describeShape(sh)
+	}
+	_, ok = e.(Scaler)
+	if !ok {
+		writeln("*Square is not a Scaler")
+	}
+	var e2 interface{} = sq
+	_, ok = e2.(Shape)
+	if !ok {
+		writeln("Square is not a Shape")
+	}
+
+	var items []interface{} = []interface{}{&Rect{w: 2, h: 2}, &sq, Label("lbl"), 1}
+	for _, item := range items {
+		switch v := item.(type) {
+		case Scaler:
+			v.Scale(2)
+			describeShape(v)
+		case Shape:
+			describeShape(v)
+		case Namer:
+			writeln("namer " + v.Name())
+		default:
+			writeln("not a namer")
+		}
+	}
+}
+
+type chanPoint struct {
+	x int
+	y int
+}
+
+func chanProduce(out chan<- int, n int) {
+	for i := 1; i <= n; i++ {
+		out <- i * 10
+	}
+	close(out)
+}
+
+func chanSum(in <-chan int, result chan int) {
+	acc := 0
+	for v := range in {
+		acc = acc + v
+	}
+	result <- acc
+}
+
+func testChannels() {
+	ch := make(chan int)
+	result := make(chan int)
+	go chanProduce(ch, 4)
+	go chanSum(ch, result)
+	fmt.Printf("sum=%d\n", <-result)
+
+	v, ok := <-ch
+	if !ok {
+		fmt.Printf("closed channel gives %d\n", v)
+	}
+
+	bc := make(chan string, 3)
+	bc <- "one"
+	bc <- "two"
+	fmt.Printf("len=%d cap=%d\n", len(bc), cap(bc))
+	s1 := <-bc
+	bc <- "three"
+	s2 := <-bc
+	s3 := <-bc
+	fmt.Printf("%s %s %s len=%d\n", s1, s2, s3, len(bc))
+
+	pc := make(chan chanPoint, 1)
+	pc <- chanPoint{x: 3, y: 4}
+	p := <-pc
+	fmt.Printf("point=%d,%d\n", p.x, p.y)
+
+	var nilch chan int
+	if nilch == nil && ch != nil {
+		fmt.Printf("nil channel\n")
+	}
+
+	done := make(chan bool)
+	go func() {
+		fmt.Printf("goroutine signals\n")
+		done <- true
+	}()
+	<-done
+	fmt.Printf("signal received\n")
+}
+
+func selectLoop(data chan int, names chan string, quit chan bool) {
+	total := 0
+	for {
+		select {
+		case v := <-data:
+			total = total + v
+		case name := <-names:
+			fmt.Printf("name=%s\n", name)
+		case <-quit:
+			fmt.Printf("total=%d\n", total)
+			return
+		}
+	}
+}
+
+func sendOnClosed(ch chan int, msg *string) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			*msg = "recovered from " + r.(errorValue).Error()
+		}
+	}()
+	ch <- 1
+	*msg = "not reached"
+}
+
+func closeChan(ch chan int, msg *string) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			*msg = "recovered from " + r.(errorValue).Error()
+		}
+	}()
+	close(ch)
+	*msg = "closed"
+}
+
+func testSelect() {
+	data := make(chan int)
+	names := make(chan string)
+	quit := make(chan bool)
+	finished := make(chan bool)
+	go func() {
+		selectLoop(data, names, quit)
+		finished <- true
+	}()
+	data <- 1
+	names <- "gopher"
+	data <- 2
+	data <- 3
+	quit <- true
+	<-finished
+
+	empty := make(chan int)
+	select {
+	case v := <-empty:
+		fmt.Printf("unexpected %d\n", v)
+	default:
+		fmt.Printf("nothing ready\n")
+	}
+
+	buf := make(chan int, 1)
+	select {
+	case buf <- 7:
+		fmt.Printf("sent to buffer\n")
+	default:
+		fmt.Printf("buffer full\n")
+	}
+	select {
+	case buf <- 8:
+		fmt.Printf("sent to buffer\n")
+	default:
+		fmt.Printf("buffer full\n")
+	}
+	select {
+	case v, ok := <-buf:
+		fmt.Printf("received %d %s\n", v, strconv.Itoa(len(buf)))
+		if ok {
+			fmt.Printf("ok\n")
+		}
+	}
+
+	out := make(chan int)
+	go func() {
+		fmt.Printf("receiver got %d\n", <-out)
+		finished <- true
+	}()
+	select {
+	case out <- 42:
+	}
+	<-finished
+
+	closed := make(chan int)
+	close(closed)
+	var msg string
+	sendOnClosed(closed, &msg)
+	fmt.Printf("%s\n", msg)
+	closeChan(closed, &msg)
+	fmt.Printf("%s\n", msg)
+	var nilChan chan int
+	closeChan(nilChan, &msg)
+	fmt.Printf("%s\n", msg)
+}
+
+func goSquare(n int, results []int, done []bool) {
+	runtime.Gosched()
+	results[n] = n * n
+	done[n] = true
+}
+
+// wait until all the flags are set by other goroutines
+func waitAll(done []bool) {
+	for {
+		all := true
+		for _, d := range done {
+			if !d {
+				all = false
+			}
+		}
+		if all {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+type goWorker struct {
+	n    int
+	done bool
+}
+
+func (w *goWorker) run(d int) {
+	w.n = d * 2
+	w.done = true
+}
+
+func spinForever() {
+	for {
+		runtime.Gosched()
+	}
+}
+
+func testGoroutines() {
+	defer fmt.Printf("testGoroutines done\n")
+	go spinForever()
+
+	results := make([]int, 5, 5)
+	done := make([]bool, 5, 5)
+	for i := 0; i < 5; i++ {
+		go goSquare(i, results, done)
+	}
+	waitAll(done)
+	fmt.Printf("squares=%d,%d,%d,%d,%d\n", results[0], results[1], results[2], results[3], results[4])
+
+	msgs := make([]string, 3, 3)
+	flags := make([]bool, 3, 3)
+	for i := 0; i < 3; i++ {
+		go func(j int) {
+			runtime.Gosched()
+			msgs[j] = "goroutine " + strconv.Itoa(j)
+			flags[j] = true
+		}(i)
+	}
+	waitAll(flags)
+	fmt.Printf("%s,%s,%s\n", msgs[0], msgs[1], msgs[2])
+
+	ws := []*goWorker{&goWorker{}}
+	go ws[0].run(7)
+	for !ws[0].done {
+		runtime.Gosched()
+	}
+	fmt.Printf("worker=%d\n", ws[0].n)
+
+	// each goroutine gets the receiver of its own iteration
+	ws = []*goWorker{&goWorker{}, &goWorker{}, &goWorker{}}
+	for i, w := range ws {
+		go w.run(i + 1)
+	}
+	for _, w := range ws {
+		for !w.done {
+			runtime.Gosched()
+		}
+	}
+	fmt.Printf("workers=%d,%d,%d\n", ws[0].n, ws[1].n, ws[2].n)
+
+	c := &deferCounter{}
+	cdone := make([]bool, 2, 2)
+	go func() {
+		defer func() {
+			r := recover()
+			msgs[0] = "recovered in goroutine: " + r.(string)
+			cdone[0] = true
+		}()
+		mayPanic(0)
+	}()
+	go func() {
+		runtime.Gosched()
+		c.add(3)
+		cdone[1] = true
+	}()
+	waitAll(cdone)
+	fmt.Printf("%s\n", msgs[0])
+	fmt.Printf("counter=%d\n", c.n)
+}
+
+type deferCounter struct {
+	n int
+}
+
+func (c *deferCounter) add(d int) {
+	c.n = c.n + d
+}
+
+func deferOrder() {
+	for i := 0; i < 3; i++ {
+		defer fmt.Printf("deferred %d\n", i)
+	}
+	fmt.Printf("deferOrder body\n")
+}
+
+func deferAndReturn() int {
+	x := 1
+	defer func() {
+		x = 100
+	}()
+	return x
+}
+
+func testDeferOrder() {
+	deferOrder()
+	fmt.Printf("deferAndReturn=%d\n", deferAndReturn())
+
+	x := 1
+	defer fmt.Printf("x at defer=%d\n", x)
+	x = 2
+	fmt.Printf("x now=%d\n", x)
+}
+
+func deferAddTo(c *deferCounter) {
+	defer c.add(10)
+	cs := []*deferCounter{c}
+	defer cs[0].add(5)
+	c.add(1)
+	fmt.Printf("in deferAddTo n=%d\n", c.n)
+}
+
+type deferFile struct {
+	name string
+}
+
+var deferLog []string
+
+func (f *deferFile) close() {
+	deferLog = append(deferLog, f.name)
+}
+
+func (f deferFile) show() {
+	deferLog = append(deferLog, "show "+f.name)
+}
+
+type deferCloser interface {
+	close()
+}
+
+// receivers are evaluated at the defer statement
+func deferReceivers() {
+	files := []*deferFile{&deferFile{name: "a"}, &deferFile{name: "b"}, &deferFile{name: "c"}}
+	for _, f := range files {
+		defer f.close()
+	}
+	t := deferFile{name: "t1"}
+	defer t.show()
+	t.name = "t2"
+	var cl deferCloser = files[0]
+	defer cl.close()
+	cl = files[1]
+	p := files[0]
+	defer p.close()
+	p = files[2]
+	v := deferFile{name: "v1"}
+	defer v.close()
+	v.name = "v2"
+}
+
+func testDeferMethod() {
+	c := &deferCounter{}
+	deferAddTo(c)
+	fmt.Printf("after deferAddTo n=%d\n", c.n)
+
+	deferReceivers()
+	var s string
+	for _, name := range deferLog {
+		s = s + " " + name
+	}
+	fmt.Printf("deferReceivers:%s\n", s)
+}
+
+func mayPanic(n int) int {
+	if n == 0 {
+		panic("n is zero")
+	}
+	return 10 / n
+}
+
+func catchPanic(n int, result *string) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			*result = "recovered: " + r.(string)
+		}
+	}()
+	*result = "ok " + strconv.Itoa(mayPanic(n))
+}
+
+func recoverReturnsZero() int {
+	defer func() {
+		recover()
+	}()
+	panic("boom")
+}
+
+func assertRecovered(result *string) {
+	defer func() {
+		*result = "asserted: " + recover().(string)
+	}()
+	panic("boom")
+}
+
+func recoverInHelper() interface{} {
+	return recover()
+}
+
+func deferredRecover(result *string) {
+	r := recover()
+	if r != nil {
+		*result = "recovered by a deferred func: " + r.(string)
+	}
+}
+
+type recoverer interface {
+	recoverInto(result *string)
+}
+
+type panicCatcher struct{}
+
+func (c *panicCatcher) recoverInto(result *string) {
+	deferredRecover(result) // not called by the deferred function
+	if *result == "" {
+		*result = "recovered by a deferred method: " + recover().(string)
+	}
+}
+
+func recoverThroughHelper(result *string) {
+	defer func() {
+		*result = "recovered after the helper: " + recover().(string)
+	}()
+	func() {
+		defer func() {
+			if recoverInHelper() == nil {
+				fmt.Printf("recover in a helper returns nil\n")
+			}
+		}()
+		panic("still panicking")
+	}()
+	*result = "not reached"
+}
+
+func recoverDeferredCalls() {
+	var result string
+	func() {
+		defer deferredRecover(&result)
+		panic("named")
+	}()
+	fmt.Printf("%s\n", result)
+	result = ""
+	func() {
+		var rc recoverer = &panicCatcher{}
+		defer rc.recoverInto(&result)
+		panic("method")
+	}()
+	fmt.Printf("%s\n", result)
+}
+
+func panicThrough() {
+	defer fmt.Printf("unwinding panicThrough\n")
+	mayPanic(0)
+	fmt.Printf("not reached in panicThrough\n")
+}
+
+func testRecover() {
+	var result string
+	catchPanic(2, &result)
+	fmt.Printf("%s\n", result)
+	catchPanic(0, &result)
+	fmt.Printf("%s\n", result)
+	fmt.Printf("recoverReturnsZero=%d\n", recoverReturnsZero())
+	assertRecovered(&result)
+	fmt.Printf("%s\n", result)
+	recoverThroughHelper(&result)
+	fmt.Printf("%s\n", result)
+	recoverDeferredCalls()
+
+	defer func() {
+		if recover() == nil {
+			fmt.Printf("recover returns nil without panic\n")
+		}
+	}()
+	defer func() {
+		r := recover()
+		fmt.Printf("recovered in testRecover: %s\n", r.(string))
+	}()
+	panicThrough()
+	fmt.Printf("not reached in testRecover\n")
+}
+
+type closureHandler struct {
+	name string
+	fn   func(int) int
+}
+
+var globalAdder func(int, int) int = func(a int, b int) int {
+	return a + b
+}
+
+func makeCounter() func() int {
+	var count int
+	return func() int {
+		count++
+		return count
+	}
+}
+
+func makeAdder(base int) func(int) int {
+	return func(x int) int {
+		return base + x
+	}
+}
+
+func applyTwice(f func(int) int, x int) int {
+	return f(f(x))
+}
+
+func testClosureCounter() {
+	c1 := makeCounter()
+	c2 := makeCounter()
+	c1()
+	c1()
+	fmt.Printf("c1=%d c2=%d\n", c1(), c2())
+	addTen := makeAdder(10)
+	fmt.Printf("addTen(5)=%d twice=%d\n", addTen(5), applyTwice(addTen, 1))
+}
+
+func testClosureCapture() {
+	x := 1
+	inc := func() {
+		x++
+	}
+	inc()
+	inc()
+	fmt.Printf("x=%d\n", x)
+
+	var s string = "hello"
+	greet := func(name string) string {
+		return s + " " + name
+	}
+	s = "bye"
+	fmt.Printf("%s\n", greet("babygo"))
+
+	var funcs []func() int
+	for i := 0; i < 3; i++ {
+		j := i * 10
+		funcs = append(funcs, func() int {
+			return j
+		})
+	}
+	for _, f := range funcs {
+		fmt.Printf("%d ", f())
+	}
+	fmt.Printf("\n")
+}
+
+func testClosureNested() {
+	total := 0
+	add := func(n int) func() {
+		return func() {
+			total = total + n
+		}
+	}
+	add(3)()
+	add(4)()
+	fmt.Printf("total=%d\n", total)
+
+	h := &closureHandler{
+		name: "double",
+		fn: func(x int) int {
+			return x * 2
+		},
+	}
+	fmt.Printf("%s=%d\n", h.name, h.fn(21))
+
+	var nilFunc func()
+	if nilFunc == nil {
+		fmt.Printf("nilFunc is nil\n")
+	}
+	fmt.Printf("%d\n", func(a int, b int) int {
+		return a * b
+	}(6, 7))
+	fmt.Printf("globalAdder=%d\n", globalAdder(2, 3))
+
+	func() {
+		total = total * 10
+	}()
+	func(n int) {
+		total = total + n
+	}(5)
+	fmt.Printf("total=%d\n", total)
+	(h.fn)(0)
+	(*h).name = "triple"
+	fmt.Printf("%s\n", h.name)
+}
+
+type mapPoint struct {
+	x int
+	y int
+}
+
+type mapLabel struct {
+	id   int
+	name string
+	tag  interface{}
+}
+
+var globalMap map[string]int = map[string]int{
+	"a": 10,
+	"b": 20,
+}
+
+func testMapBasic() {
+	var m map[string]int = make(map[string]int)
+	m["one"] = 1
+	m["two"] = 2
+	m["three"] = 3
+	m["two"] = 22
+	fmt.Printf("len=%d one=%d two=%d three=%d four=%d\n", len(m), m["one"], m["two"], m["three"], m["four"])
+	v, ok := m["three"]
+	if ok {
+		fmt.Printf("three is found: %d\n", v)
+	}
+	_, ok = m["four"]
+	if !ok {
+		fmt.Printf("four is not found\n")
+	}
+	delete(m, "one")
+	delete(m, "none")
+	fmt.Printf("len=%d one=%d\n", len(m), m["one"])
+	m["two"]++
+	m["two"] += 10
+	m["three"]--
+	fmt.Printf("two=%d three=%d\n", m["two"], m["three"])
+	m["one"] = len(m)
+	fmt.Printf("one=%d\n", m["one"])
+
+	var nilMap map[string]int
+	fmt.Printf("nil map: len=%d x=%d\n", len(nilMap), nilMap["x"])
+	delete(nilMap, "x")
+	assignToNilMap(nilMap)
+
+	globalMap["c"] = 30
+	fmt.Printf("globalMap: len=%d a=%d b=%d c=%d\n", len(globalMap), globalMap["a"], globalMap["b"], globalMap["c"])
+}
+
+func assignToNilMap(m map[string]int) {
+	defer func() {
+		r := recover()
+		fmt.Printf("recovered: %s\n", r.(errorValue).Error())
+	}()
+	m["x"] = 1
+}
+
+func testMapLiteralAndRange() {
+	m := map[int]string{
+		1: "a",
+		2: "bb",
+		3: "ccc",
+	}
+	var sumKeys int
+	var sumLens int
+	for k, v := range m {
+		sumKeys += k
+		sumLens += len(v)
+	}
+	fmt.Printf("sumKeys=%d sumLens=%d\n", sumKeys, sumLens)
+
+	// deleting entries during iteration
+	for k := range m {
+		if k == 2 {
+			delete(m, 3)
+			delete(m, 1)
+		}
+	}
+	fmt.Printf("len=%d\n", len(m))
+
+	var keys []string
+	var names map[string]bool = map[string]bool{
+		"x": true,
+	}
+	for key, _ := range names {
+		keys = append(keys, key)
+	}
+	fmt.Printf("keys=%s len=%d\n", keys[0], len(keys))
+}
+
+func testMapGrow() {
+	var m map[int]int = make(map[int]int, 4)
+	var i int
+	for i = 0; i < 1000; i++ {
+		m[i] = i * 2
+	}
+	var sum int
+	for _, v := range m {
+		sum += v
+	}
+	fmt.Printf("len=%d sum=%d m[999]=%d\n", len(m), sum, m[999])
+	for i = 0; i < 1000; i++ {
+		if i%2 == 0 {
+			delete(m, i)
+		}
+	}
+	fmt.Printf("len=%d m[998]=%d m[997]=%d\n", len(m), m[998], m[997])
+}
+
+func testMapKeysAndValues() {
+	// struct key and struct value
+	var points map[mapPoint]mapPoint = make(map[mapPoint]mapPoint)
+	points[mapPoint{x: 1, y: 2}] = mapPoint{x: 10, y: 20}
+	var key mapPoint = mapPoint{x: 1, y: 2}
+	p, ok := points[key]
+	if ok {
+		fmt.Printf("point=%d,%d y=%d\n", p.x, p.y, points[key].y)
+	}
+
+	// pointer key
+	var p1 *mapPoint = &mapPoint{x: 1}
+	var p2 *mapPoint = &mapPoint{x: 1}
+	var ptrs map[*mapPoint]string = map[*mapPoint]string{
+		p1: "p1",
+		p2: "p2",
+	}
+	ptrs[nil] = "nil"
+	fmt.Printf("ptrs: %s %s %s len=%d\n", ptrs[p1], ptrs[p2], ptrs[nil], len(ptrs))
+
+	// interface key
+	var ifcs map[interface{}]int = make(map[interface{}]int)
+	ifcs[1] = 100
+	ifcs["1"] = 200
+	ifcs[nil] = 300
+	var one interface{} = 1
+	fmt.Printf("ifcs: %d %d %d %d len=%d\n", ifcs[one], ifcs["1"], ifcs[nil], ifcs[2], len(ifcs))
+
+	// keys which are equal have the same hash, even if their strings are different allocations
+	var prefix = "ab"
+	var labels map[mapLabel]int = make(map[mapLabel]int)
+	labels[mapLabel{id: 1, name: prefix + "c", tag: prefix + "d"}] = 1
+	labels[mapLabel{id: 1, name: "abc", tag: "abd"}]++
+	fmt.Printf("labels: %d len=%d\n", labels[mapLabel{id: 1, name: "abc", tag: "abd"}], len(labels))
+	ifcs[mapLabel{id: 2, name: prefix + "c"}] = 400
+	var names map[[2]string]int = make(map[[2]string]int)
+	names[[2]string{prefix, prefix + "c"}] = 500
+	fmt.Printf("ifcs: %d names: %d\n", ifcs[mapLabel{id: 2, name: "abc"}], names[[2]string{"ab", "abc"}])
+	var zero float64
+	var floats map[float64]int = make(map[float64]int)
+	floats[zero] = 1
+	floats[-zero] = 2
+	fmt.Printf("floats: %d len=%d\n", floats[0], len(floats))
+
+	// slice value
+	var lists map[string][]string = make(map[string][]string)
+	lists["a"] = append(lists["a"], "x")
+	lists["a"] = append(lists["a"], "y")
+	fmt.Printf("lists: %s,%s\n", lists["a"][0], lists["a"][1])
+
+	// map value
+	var nested map[string]map[string]int = make(map[string]map[string]int)
+	nested["a"] = make(map[string]int)
+	nested["a"]["b"] = 1
+	fmt.Printf("nested: %d %d\n", nested["a"]["b"], nested["x"]["y"])
+}
+
 func testTokenString() {
 	tok := token.Token("hello")
 	fmt.Printf("%s\n", tok.String())
@@ -1983,6 +4364,41 @@ func testMisc() {
 }
 
 func main() {
+	testPanicValues()
+	testNilDereference()
+	testBoundsCheck()
+	testGC()
+	testMemStats()
+	testEquality()
+	testFuncValues()
+	testMakeNewMinMaxClear()
+	testAppendCopy()
+	testUnicode()
+	testLiterals()
+	testLabels()
+	testStructEmbedding()
+	testFloats()
+	testSizedIntegers()
+	testSignedDivision()
+	testBitwiseOps()
+	testConstFolding()
+	testBigConsts()
+	testGroupedDecls()
+	testInterfaceMethods()
+	testNonEmptyInterfaceAssertion()
+	testChannels()
+	testSelect()
+	testGoroutines()
+	testDeferOrder()
+	testDeferMethod()
+	testRecover()
+	testClosureCounter()
+	testClosureCapture()
+	testClosureNested()
+	testMapBasic()
+	testMapLiteralAndRange()
+	testMapGrow()
+	testMapKeysAndValues()
 	testTokenString()
 	testAssignIncDec()
 	testTypeAlias()
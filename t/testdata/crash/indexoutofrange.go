@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+func get(s []int, i int) int {
+	return s[i]
+}
+
+// The panic value of a failed bounds check has no position,
+// which is shown by the traceback.
+func main() {
+	var s = make([]int, 3)
+	get(s, 5)
+	os.Exit(0)
+}
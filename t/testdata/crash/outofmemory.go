@@ -0,0 +1,22 @@
+package main
+
+import "os"
+
+// env: BABYGO_MAXHEAP=16M
+
+type block struct {
+	data []uint8
+	next *block
+}
+
+var live *block
+
+// Memory which is still reachable cannot be collected,
+// so the heap limit is exceeded.
+func main() {
+	var i int
+	for i = 0; i < 64; i++ {
+		live = &block{data: make([]uint8, 1024*1024), next: live}
+	}
+	os.Exit(0)
+}
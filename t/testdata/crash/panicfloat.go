@@ -0,0 +1,16 @@
+package main
+
+import "os"
+
+func fail(f float64) {
+	panic(f)
+}
+
+// A panic which is not recovered prints its value and the stack,
+// and exits with status 2.
+func main() {
+	if len(os.Args) > 5 {
+		os.Exit(0)
+	}
+	fail(1.5)
+}
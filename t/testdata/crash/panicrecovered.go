@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+// A panic raised by a deferred call is printed after the panic which was running the call.
+func main() {
+	defer func() {
+		recover()
+		panic(float32(0.1))
+	}()
+	if len(os.Args) > 5 {
+		os.Exit(0)
+	}
+	panic("first")
+}
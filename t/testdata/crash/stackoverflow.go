@@ -0,0 +1,21 @@
+package main
+
+import "os"
+
+func recurse(n int) int {
+	var a [16]int
+	a[n%16] = n
+	return recurse(n+1) + a[0]
+}
+
+// A goroutine which overflows its stack faults on the guard below it,
+// instead of overwriting the memory of others.
+func main() {
+	var done = make(chan bool)
+	go func() {
+		recurse(0)
+		done <- true
+	}()
+	<-done
+	os.Exit(0)
+}
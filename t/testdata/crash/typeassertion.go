@@ -0,0 +1,14 @@
+package main
+
+import "os"
+
+type E interface {
+	Error() string
+}
+
+// A failed single-value assertion panics rather than giving the zero value.
+func main() {
+	var x interface{} = "s"
+	var e E = x.(E)
+	os.Exit(len(e.Error()))
+}
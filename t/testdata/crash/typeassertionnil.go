@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// Asserting a concrete type on a nil interface value panics too.
+func main() {
+	var x interface{}
+	var n int = x.(int)
+	os.Exit(n)
+}
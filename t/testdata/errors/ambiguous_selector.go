@@ -0,0 +1,23 @@
+package main
+
+import "os"
+
+type A struct {
+	x int
+}
+
+type B struct {
+	x int
+}
+
+type C struct {
+	A
+	B
+}
+
+func main() {
+	var c C
+	// error: ambiguous selector c.x
+	c.x = 1
+	os.Exit(c.A.x)
+}
@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+func main() {
+	// error: constant 9223372036854775808 overflows int
+	os.Exit(1 << 63)
+}
@@ -0,0 +1,12 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	if n == 0 {
+		// error: break is not in a loop, switch, or select
+		break
+	}
+	os.Exit(n)
+}
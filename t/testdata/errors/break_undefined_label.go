@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	for n < 3 {
+		n++
+		// error: break label not defined: L
+		break L
+	}
+	os.Exit(n)
+}
@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var a [2]map[int]int
+	var b [2]map[int]int
+	// error: invalid operation: [2]map[int]int cannot be compared
+	if a == b {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+func f() {
+}
+
+func main() {
+	var a func() = f
+	// error: invalid operation: func() can only be compared to nil
+	if a == f {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
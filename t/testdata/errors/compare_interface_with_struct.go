@@ -0,0 +1,17 @@
+package main
+
+import "os"
+
+type T struct {
+	f func()
+}
+
+func main() {
+	var x interface{}
+	var t T
+	// error: invalid operation: main.T cannot be compared
+	if x == t {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
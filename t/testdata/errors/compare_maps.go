@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var a map[string]int
+	var b map[string]int
+	// error: invalid operation: map[string]int can only be compared to nil
+	if a != b {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
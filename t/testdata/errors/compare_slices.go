@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var a []int
+	var b []int
+	// error: invalid operation: []int can only be compared to nil
+	if a == b {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
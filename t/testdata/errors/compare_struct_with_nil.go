@@ -0,0 +1,16 @@
+package main
+
+import "os"
+
+type T struct {
+	n int
+}
+
+func main() {
+	var t T
+	// error: invalid operation: mismatched types main.T and untyped nil
+	if t == nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "os"
+
+type T struct {
+	n  int
+	xs []int
+}
+
+func main() {
+	var a T
+	var b T
+	// error: invalid operation: main.T cannot be compared
+	if a == b {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// error: constant -256 overflows int8
+const c int8 = ^int8(127) * 2
+
+func main() {
+	os.Exit(int(c))
+}
@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// error: constant 18446744073709551616 overflows uint
+const U uint = 1 << 64
+
+func main() {
+	os.Exit(0)
+}
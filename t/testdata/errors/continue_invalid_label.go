@@ -0,0 +1,14 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+L:
+	switch n {
+	case 0:
+		// error: invalid continue label L
+		continue L
+	}
+	os.Exit(n)
+}
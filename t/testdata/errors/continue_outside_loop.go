@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	switch n {
+	case 0:
+		// error: continue is not in a loop
+		continue
+	}
+	os.Exit(n)
+}
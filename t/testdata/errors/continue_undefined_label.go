@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	for n < 3 {
+		n++
+		// error: continue label not defined: L
+		continue L
+	}
+	os.Exit(n)
+}
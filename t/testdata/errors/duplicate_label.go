@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+L:
+	n++
+	if n < 3 {
+		goto L
+	}
+	// error: label L already defined
+L:
+	os.Exit(n)
+}
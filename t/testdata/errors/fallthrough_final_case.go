@@ -0,0 +1,16 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	switch n {
+	case 0:
+		n++
+	case 1:
+		n--
+		// error: cannot fallthrough final case in switch
+		fallthrough
+	}
+	os.Exit(n)
+}
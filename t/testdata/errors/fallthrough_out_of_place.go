@@ -0,0 +1,12 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	if n == 0 {
+		// error: fallthrough statement out of place
+		fallthrough
+	}
+	os.Exit(n)
+}
@@ -0,0 +1,11 @@
+package main
+
+import "os"
+
+func main() {
+	// error: constant 1e+39 overflows float32
+	var f float32 = 1e39
+	if f > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: constant 2.5 truncated to integer
+	var x int = 2.5
+	os.Exit(x)
+}
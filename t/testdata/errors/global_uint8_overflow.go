@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// error: constant -1 overflows uint8
+var b uint8 = -1
+
+func main() {
+	os.Exit(int(b))
+}
@@ -0,0 +1,14 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	// error: goto L jumps into block
+	goto L
+	if n == 0 {
+	L:
+		n++
+	}
+	os.Exit(n)
+}
@@ -0,0 +1,11 @@
+package main
+
+import "os"
+
+func main() {
+	// error: goto L jumps over variable declaration
+	goto L
+	var n int = 1
+L:
+	os.Exit(n)
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: label L not defined
+	goto L
+	os.Exit(0)
+}
@@ -0,0 +1,12 @@
+package main
+
+import "os"
+
+func main() {
+	var x interface{}
+	// error: constant 1180591620717411303424 overflows int
+	x = 1 << 70
+	if x == nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: invalid argument: len larger than cap in make([]int)
+	var s = make([]int, 10, 5)
+	os.Exit(len(s))
+}
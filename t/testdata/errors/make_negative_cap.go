@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: invalid argument: negative cap argument in make([]int)
+	var s = make([]int, 0, -1)
+	os.Exit(len(s))
+}
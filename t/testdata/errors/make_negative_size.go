@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: invalid argument: negative size argument in make(chan int)
+	var c = make(chan int, -1)
+	os.Exit(cap(c))
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: invalid operation: make([]int) expects 2 or 3 arguments
+	var s = make([]int)
+	os.Exit(len(s))
+}
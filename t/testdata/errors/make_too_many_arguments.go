@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: invalid operation: too many arguments to make(map[string]int)
+	var m = make(map[string]int, 1, 2)
+	os.Exit(len(m))
+}
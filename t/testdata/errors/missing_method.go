@@ -0,0 +1,22 @@
+package main
+
+import "os"
+
+type Shape interface {
+	Area() int
+	Name() string
+}
+
+type Square struct {
+	side int
+}
+
+func (s Square) Area() int {
+	return s.side * s.side
+}
+
+func main() {
+	// error: main.Square does not implement main.Shape (missing method Name)
+	var s Shape = Square{side: 2}
+	os.Exit(s.Area())
+}
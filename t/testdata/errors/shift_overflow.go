@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// error: constant shift overflow
+const big = 1 << 1000
+
+func main() {
+	os.Exit(big >> 1000)
+}
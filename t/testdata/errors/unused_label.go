@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+func main() {
+	var n int
+	// error: label L defined and not used
+L:
+	for n < 3 {
+		n++
+	}
+	os.Exit(n)
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+func main() {
+	// error: constant 300 overflows int8
+	var y int8 = 300
+	os.Exit(int(y))
+}
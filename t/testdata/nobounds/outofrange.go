@@ -0,0 +1,16 @@
+package main
+
+import "os"
+
+func get(s []int, i int) int {
+	return s[i]
+}
+
+// Built with -B, s[3] reads the backing array beyond the length of s
+// instead of panicking, and the program exits with 7.
+func main() {
+	var b = make([]int, 5)
+	b[3] = 7
+	var s = b[:3]
+	os.Exit(get(s, 3))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/DQNEO/babygo/lib/strconv"
+)
+
+func writeln(n int) {
+	syscall.Write(1, []uint8(strconv.Itoa(n)+"\n"))
+}
+
+/* a block comment
+   before a declaration */
+func main() {
+	// prefixes in upper case
+	writeln(0XFF + 0Xa_B)
+	writeln(0O17 + 0o1)
+	writeln(0B1011 | 0b1)
+	var mask uint8 = 0B1111_0000 /* an inline
+	block comment */
+	writeln(int(mask >> 4))
+	os.Exit(0)
+}
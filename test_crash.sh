@@ -0,0 +1,36 @@
+#!/bin/bash
+# Run the programs in t/testdata/crash, which crash on purpose,
+# and check that each exits with status 2 and that its stderr starts with the lines in the .out file.
+# Hexadecimal numbers, which depend on the addresses, are compared as 0x?.
+set -u
+compiler=$1
+tmp=/tmp/babygo/crash
+mkdir -p $tmp
+failed=0
+for src in t/testdata/crash/*.go; do
+  name=$(basename $src .go)
+  expected=t/testdata/crash/${name}.out
+  $compiler $src > $tmp/${name}.s && as -o $tmp/${name}.o $tmp/${name}.s src/runtime/runtime.s && ld -e _rt0_amd64_linux -o $tmp/${name} $tmp/${name}.o
+  if [[ $? -ne 0 ]]; then
+    echo "FAILED to build $src"
+    failed=1
+    continue
+  fi
+  env $(sed -n 's|^// env: ||p' $src) $tmp/${name} 1>/dev/null 2>$tmp/${name}.stderr
+  status=$?
+  if [[ $status -ne 2 ]]; then
+    echo "FAILED: $src exited with status $status"
+    failed=1
+    continue
+  fi
+  sed -E 's/0x[0-9a-f]+/0x?/g' $tmp/${name}.stderr | head -n $(wc -l < $expected) | diff -u $expected -
+  if [[ $? -ne 0 ]]; then
+    echo "FAILED: $src"
+    failed=1
+  fi
+done
+
+if [[ $failed -ne 0 ]]; then
+  exit 1
+fi
+echo "ok"
@@ -0,0 +1,26 @@
+#!/bin/bash
+# Compile the programs in t/testdata/errors, which are invalid,
+# and check that the compiler fails with the message in the "// error: " comment of each.
+set -u
+compiler=$1
+failed=0
+for src in t/testdata/errors/*.go; do
+  expected=$(sed -n 's|^[[:space:]]*// error: ||p' $src)
+  $compiler $src 1>/dev/null 2>/tmp/babygo-error.txt
+  if [[ $? -eq 0 ]]; then
+    echo "FAILED: $src compiled without error"
+    failed=1
+    continue
+  fi
+  if ! grep -qF -- "$expected" /tmp/babygo-error.txt; then
+    echo "FAILED: $src"
+    echo "  expected: $expected"
+    echo "  got: $(head -1 /tmp/babygo-error.txt)"
+    failed=1
+  fi
+done
+
+if [[ $failed -ne 0 ]]; then
+  exit 1
+fi
+echo "ok"
@@ -0,0 +1,35 @@
+#!/bin/bash
+# Run the programs in t/testdata/run, which are not formatted by gofmt,
+# and check that each prints the same as with go run.
+set -u
+compiler=$1
+tmp=/tmp/babygo/run
+mkdir -p $tmp
+failed=0
+for src in t/testdata/run/*.go; do
+  name=$(basename $src .go)
+  $compiler $src > $tmp/${name}.s && as -o $tmp/${name}.o $tmp/${name}.s src/runtime/runtime.s && ld -e _rt0_amd64_linux -o $tmp/${name} $tmp/${name}.o
+  if [[ $? -ne 0 ]]; then
+    echo "FAILED to build $src"
+    failed=1
+    continue
+  fi
+  go run $src > $tmp/${name}.expected
+  $tmp/${name} > $tmp/${name}.actual
+  status=$?
+  if [[ $status -ne 0 ]]; then
+    echo "FAILED: $src exited with status $status"
+    failed=1
+    continue
+  fi
+  diff -u $tmp/${name}.expected $tmp/${name}.actual
+  if [[ $? -ne 0 ]]; then
+    echo "FAILED: $src"
+    failed=1
+  fi
+done
+
+if [[ $failed -ne 0 ]]; then
+  exit 1
+fi
+echo "ok"